@@ -103,7 +103,7 @@ fi
 #
 # Determine which build stages we want to tag as an image.
 #
-build_targets=(netwatcher svcwatcher webhook danm-cni-plugins)
+build_targets=(netwatcher svcwatcher webhook danm-agent danm-cni-plugins)
 
 if [ -n "${KEEP_BUILDER}" ]
 then
@@ -19,6 +19,7 @@ import (
 
 const (
   cniTestConfigFile = "/etc/cni/net.d/cnitest.conf"
+  cniTestTraceFile = "/etc/cni/net.d/cnitest.trace"
 )
 
 type TestConfig struct {
@@ -31,14 +32,41 @@ type CniExpectations struct {
   Ip6        string            `json:"ip6,omitempty"`
   Env        map[string]string `json:"env,omitempty"`
   ReturnType string            `json:"return,omitempty"`
+  Chain      []string          `json:"chain,omitempty"`
+}
+
+type ChainedPluginConf struct {
+  types.NetConf
+  RawPrevResult map[string]interface{} `json:"prevResult,omitempty"`
 }
 
 type SriovCniTestConfig struct {
   CniConf  cnidel.SriovNet `json:"cniconf"`
 }
 
+//DANM creates MACVLAN interfaces by itself, but the structure is still used to validate the generic parts of delegated CNI configs
+type MacvlanNet struct {
+  types.NetConf
+  Master string `json:"master"`
+  Mode   string `json:"mode"`
+  MTU    int    `json:"mtu,omitempty"`
+  Ipam   datastructs.IpamConfig `json:"ipam,omitEmpty"`
+}
+
 type MacvlanCniTestConfig struct {
-  CniConf  cnidel.MacvlanNet `json:"cniconf"`
+  CniConf  MacvlanNet `json:"cniconf"`
+}
+
+type BridgeCniTestConfig struct {
+  CniConf  cnidel.BridgeNet `json:"cniconf"`
+}
+
+type OvsCniTestConfig struct {
+  CniConf  cnidel.OvsNet `json:"cniconf"`
+}
+
+type HostDeviceCniTestConfig struct {
+  CniConf  cnidel.HostDeviceNet `json:"cniconf"`
 }
 
 type FlannelCniTestConfig struct {
@@ -74,8 +102,18 @@ func testSetup(args *skel.CmdArgs) error {
     err = validateSriovConfig(args.StdinData, expectedCniConf)
   } else if tcConf.CniExpectations.CniType == "macvlan" {
     err = validateMacvlanConfig(args.StdinData, expectedCniConf, tcConf)
+  } else if tcConf.CniExpectations.CniType == "bridge" {
+    err = validateBridgeConfig(args.StdinData, expectedCniConf)
+  } else if tcConf.CniExpectations.CniType == "host-device" {
+    err = validateHostDeviceConfig(args.StdinData, expectedCniConf)
+  } else if tcConf.CniExpectations.CniType == "ovs" {
+    err = validateOvsConfig(args.StdinData, expectedCniConf)
   } else if tcConf.CniExpectations.CniType == "flannel" {
     err = validateFlannelConfig(args.StdinData, expectedCniConf)
+  } else if tcConf.CniExpectations.CniType == "generic" {
+    err = validateGenericConfig(args.StdinData, expectedCniConf)
+  } else if tcConf.CniExpectations.CniType == "chain" {
+    return validateChainedPlugin(args.StdinData, tcConf)
   }
   if err != nil {
     return err
@@ -118,8 +156,30 @@ func validateSriovConfig(receivedCniConfig, expectedCniConfig []byte) error {
   return nil
 }
 
+//Configs of the pluggable backends have no Go representation, so they are compared as generic JSON objects
+func validateGenericConfig(receivedCniConfig, expectedCniConfig []byte) error {
+  var recConf map[string]interface{}
+  err := json.Unmarshal(receivedCniConfig, &recConf)
+  if err != nil {
+    return errors.New("Received CNI config could not be unmarshalled, because:" + err.Error())
+  }
+  log.Printf("Received CNI config:%v",recConf)
+  var expConf struct {
+    CniConf map[string]interface{} `json:"cniconf"`
+  }
+  err = json.Unmarshal(expectedCniConfig, &expConf)
+  if err != nil {
+    return errors.New("Expected CNI config could not be unmarshalled, because:" + err.Error())
+  }
+  log.Printf("Expected config:%v",expConf.CniConf)
+  if !reflect.DeepEqual(recConf, expConf.CniConf) {
+    return errors.New("Received delegate configuration does not match with expected!")
+  }
+  return nil
+}
+
 func validateMacvlanConfig(receivedCniConfig, expectedCniConfig []byte, tcConf TestConfig) error {
-  var recMacvlanConf cnidel.MacvlanNet
+  var recMacvlanConf MacvlanNet
   err := json.Unmarshal(receivedCniConfig, &recMacvlanConf)
   if err != nil {
     return errors.New("Received CNI config could not be unmarshalled, because:" + err.Error())
@@ -150,6 +210,63 @@ func validateMacvlanConfig(receivedCniConfig, expectedCniConfig []byte, tcConf T
   return nil
 }
 
+func validateBridgeConfig(receivedCniConfig, expectedCniConfig []byte) error {
+  var recBridgeConf cnidel.BridgeNet
+  err := json.Unmarshal(receivedCniConfig, &recBridgeConf)
+  if err != nil {
+    return errors.New("Received bridge config could not be unmarshalled, because:" + err.Error())
+  }
+  log.Printf("Received bridge config:%v",recBridgeConf)
+  var expBridgeConf BridgeCniTestConfig
+  err = json.Unmarshal(expectedCniConfig, &expBridgeConf)
+  if err != nil {
+    return errors.New("Expected bridge config could not be unmarshalled, because:" + err.Error())
+  }
+  log.Printf("Expected bridge config:%v",expBridgeConf.CniConf)
+  if !reflect.DeepEqual(recBridgeConf, expBridgeConf.CniConf) {
+    return errors.New("Received bridge delegate configuration does not match with expected!")
+  }
+  return nil
+}
+
+func validateOvsConfig(receivedCniConfig, expectedCniConfig []byte) error {
+  var recOvsConf cnidel.OvsNet
+  err := json.Unmarshal(receivedCniConfig, &recOvsConf)
+  if err != nil {
+    return errors.New("Received OVS config could not be unmarshalled, because:" + err.Error())
+  }
+  log.Printf("Received OVS config:%v",recOvsConf)
+  var expOvsConf OvsCniTestConfig
+  err = json.Unmarshal(expectedCniConfig, &expOvsConf)
+  if err != nil {
+    return errors.New("Expected OVS config could not be unmarshalled, because:" + err.Error())
+  }
+  log.Printf("Expected OVS config:%v",expOvsConf.CniConf)
+  if !reflect.DeepEqual(recOvsConf, expOvsConf.CniConf) {
+    return errors.New("Received OVS delegate configuration does not match with expected!")
+  }
+  return nil
+}
+
+func validateHostDeviceConfig(receivedCniConfig, expectedCniConfig []byte) error {
+  var recHostDeviceConf cnidel.HostDeviceNet
+  err := json.Unmarshal(receivedCniConfig, &recHostDeviceConf)
+  if err != nil {
+    return errors.New("Received host-device config could not be unmarshalled, because:" + err.Error())
+  }
+  log.Printf("Received host-device config:%v",recHostDeviceConf)
+  var expHostDeviceConf HostDeviceCniTestConfig
+  err = json.Unmarshal(expectedCniConfig, &expHostDeviceConf)
+  if err != nil {
+    return errors.New("Expected host-device config could not be unmarshalled, because:" + err.Error())
+  }
+  log.Printf("Expected host-device config:%v",expHostDeviceConf.CniConf)
+  if !reflect.DeepEqual(recHostDeviceConf, expHostDeviceConf.CniConf) {
+    return errors.New("Received host-device delegate configuration does not match with expected!")
+  }
+  return nil
+}
+
 func validateFlannelConfig(receivedCniConfig, expectedCniConfig []byte) error {
   var recFlannelConf FlannelConf
   err := json.Unmarshal(receivedCniConfig, &recFlannelConf)
@@ -169,6 +286,48 @@ func validateFlannelConfig(receivedCniConfig, expectedCniConfig []byte) error {
   return nil
 }
 
+//Plugins of a chain record their invocation, so tests can verify the order of execution
+//Every plugin after the first one must receive the result of the previous one, which it also returns, just like real meta plugins do
+func validateChainedPlugin(receivedCniConfig []byte, tcConf TestConfig) error {
+  recConf, err := traceChainedPlugin(receivedCniConfig)
+  if err != nil {
+    return err
+  }
+  if recConf.Name == "" || recConf.CNIVersion == "" {
+    return errors.New("name, or cniVersion of the CNI config list was not passed to chained plugin:" + recConf.Type)
+  }
+  if len(tcConf.CniExpectations.Chain) == 0 || recConf.Type == tcConf.CniExpectations.Chain[0] {
+    if recConf.RawPrevResult != nil {
+      return errors.New("first plugin of the chain:" + recConf.Type + " received a prevResult")
+    }
+    return createCurrentCniResult(tcConf).Print()
+  }
+  if recConf.RawPrevResult == nil {
+    return errors.New("chained plugin:" + recConf.Type + " did not receive the result of the previous plugin")
+  }
+  rawPrevResult, _ := json.Marshal(recConf.RawPrevResult)
+  prevResult, err := current.NewResult(rawPrevResult)
+  if err != nil {
+    return errors.New("prevResult of chained plugin:" + recConf.Type + " could not be parsed, because:" + err.Error())
+  }
+  return prevResult.Print()
+}
+
+func traceChainedPlugin(receivedCniConfig []byte) (*ChainedPluginConf, error) {
+  var recConf ChainedPluginConf
+  err := json.Unmarshal(receivedCniConfig, &recConf)
+  if err != nil {
+    return nil, errors.New("Received chained plugin config could not be unmarshalled, because:" + err.Error())
+  }
+  traceFile, err := os.OpenFile(cniTestTraceFile, os.O_RDWR | os.O_CREATE | os.O_APPEND, 0666)
+  if err != nil {
+    return nil, errors.New("invocation of chained plugin:" + recConf.Type + " could not be traced, because:" + err.Error())
+  }
+  defer traceFile.Close()
+  _, err = traceFile.WriteString(os.Getenv("CNI_COMMAND") + ":" + recConf.Type + "\n")
+  return &recConf, err
+}
+
 func createCurrentCniResult(tcConf TestConfig) *current.Result {
   cniRes := current.Result {CNIVersion: "0.3.1"}
   if tcConf.CniExpectations.Ip != "" ||  tcConf.CniExpectations.Ip6 != "" {
@@ -222,6 +381,8 @@ func testDelete(args *skel.CmdArgs) error {
     err = validateMacvlanConfig(args.StdinData, expectedCniConf, tcConf)
   } else if tcConf.CniExpectations.CniType == "flannel" {
     err = validateFlannelConfig(args.StdinData, expectedCniConf)
+  } else if tcConf.CniExpectations.CniType == "chain" {
+    _, err = traceChainedPlugin(args.StdinData)
   }
   return err
 }
@@ -1,10 +1,13 @@
 package main
 
 import (
+  "context"
   "flag"
   "log"
   "os"
   "github.com/containernetworking/cni/pkg/skel"
+  "github.com/containernetworking/cni/pkg/types/current"
+  "github.com/nokia/danm/pkg/agent"
   "github.com/nokia/danm/pkg/datastructs"
   "github.com/nokia/danm/pkg/metacni"
 )
@@ -29,5 +32,38 @@ func main() {
   defer f.Close()
   log.SetOutput(f)
   log.SetFlags(log.LstdFlags | log.Lmicroseconds)
-  skel.PluginMain(metacni.CreateInterfaces, metacni.GetInterfaces, metacni.DeleteInterfaces, datastructs.SupportedCniVersions, "")
+  skel.PluginMain(createInterfaces, getInterfaces, deleteInterfaces, datastructs.SupportedCniVersions, "")
+}
+
+//The DANM binary is only a thin shim when a node-local DANM agent is running: it forwards the operation, and prints the agent's answer
+//Operations are executed in the context of the CNI binary only when the agent cannot be reached
+func createInterfaces(args *skel.CmdArgs) error {
+  rawResult, err := agent.ForwardRequest(agent.CniAddOp, args)
+  if err == agent.ErrAgentUnavailable {
+    return metacni.CreateInterfaces(args)
+  }
+  if err != nil {
+    return err
+  }
+  cniResult, err := current.NewResult(rawResult)
+  if err != nil {
+    return err
+  }
+  return cniResult.Print()
+}
+
+func getInterfaces(args *skel.CmdArgs) error {
+  _, err := agent.ForwardRequest(agent.CniCheckOp, args)
+  if err == agent.ErrAgentUnavailable {
+    return metacni.GetInterfaces(context.Background(), args)
+  }
+  return err
+}
+
+func deleteInterfaces(args *skel.CmdArgs) error {
+  _, err := agent.ForwardRequest(agent.CniDelOp, args)
+  if err == agent.ErrAgentUnavailable {
+    return metacni.DeleteInterfaces(context.Background(), args)
+  }
+  return err
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+  "flag"
+  "os"
+  "log"
+  "k8s.io/client-go/rest"
+  "k8s.io/client-go/tools/clientcmd"
+  "github.com/nokia/danm/pkg/agent"
+)
+
+var(
+  version, commitHash string
+)
+
+func getClientConfig(kubeConfig string) (*rest.Config, error) {
+  if kubeConfig != "" {
+    return clientcmd.BuildConfigFromFlags("", kubeConfig)
+  }
+  return rest.InClusterConfig()
+}
+
+func main() {
+  printVersion := flag.Bool("version", false, "prints Git version information of the binary to standard out")
+  kubeConfig := flag.String("kubeconf", "", "Path to a kube config. Only required if out-of-cluster.")
+  socketPath := flag.String("socket", agent.DefaultSocketPath, "Path of the unix socket the DANM CNI binary forwards its requests to.")
+  statusInterval := flag.Duration("status-interval", agent.StatusInterval, "Interval of refreshing the status of the DanmEps of the node. Status refresh is disabled when not positive.")
+  statsInterval := flag.Duration("stats-interval", agent.StatsInterval, "Minimum interval between DanmEp status writes when only the interface counters changed.")
+  flag.Parse()
+  if *printVersion {
+    log.Println("DANM binary was built from release: " + version)
+    log.Println("DANM binary was built from commit: " + commitHash)
+    return
+  }
+  log.SetOutput(os.Stdout)
+  log.SetFlags(log.LstdFlags | log.Lmicroseconds)
+  log.Println("Starting DANM agent...")
+  config, err := getClientConfig(*kubeConfig)
+  if err != nil {
+    log.Println("ERROR: Parsing kubeconfig failed with error:" + err.Error() + " , exiting")
+    os.Exit(-1)
+  }
+  danmAgent, err := agent.NewAgent(config, *socketPath)
+  if err != nil {
+    log.Println("ERROR: Creation of DANM agent failed with error:" + err.Error() + " , exiting")
+    os.Exit(-1)
+  }
+  danmAgent.StatusInterval = *statusInterval
+  danmAgent.StatsInterval = *statsInterval
+  stopCh := make(chan struct{})
+  err = danmAgent.Run(stopCh)
+  if err != nil {
+    log.Println("ERROR: DANM agent stopped with error:" + err.Error() + " , exiting")
+    os.Exit(-1)
+  }
+}
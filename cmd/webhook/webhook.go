@@ -3,11 +3,13 @@ package main
 import (
   "flag"
   "log"
+  "os"
   "strconv"
   "time"
   "crypto/tls"
   "net/http"
   "github.com/nokia/danm/pkg/admit"
+  "github.com/nokia/danm/pkg/cnidel"
 )
 
 var(
@@ -19,6 +21,7 @@ func main() {
   key := flag.String("tls-private-key-file", "", "file containing the x509 private key matching --tls-cert-bundle.")
   port := flag.Int("bind-port", 8443, "the port on which to serve. Default is 8443.")
   address := flag.String("bind-address", "", "the IP address on which to listen. Default is all interfaces.")
+  backendDir := flag.String("backend-dir", cnidel.DefaultBackendDir, "directory of the pluggable DANM backend definitions, the same as the backendDir of the CNI config. Default is " + cnidel.DefaultBackendDir + ".")
   printVersion := flag.Bool("version", false, "prints Git version information of the binary to standard out")
   flag.Parse()
   if *printVersion {
@@ -35,6 +38,15 @@ func main() {
     log.Println("ERROR: TLS configuration could not be initialized, because:" + err.Error())
     return
   }
+  if _, err = os.Stat(*backendDir); err != nil {
+    log.Println("WARNING: backend directory:" + *backendDir + " cannot be accessed, TenantNetworks of pluggable backends won't be recognized, because:" + err.Error())
+  } else {
+    err = cnidel.LoadBackends(*backendDir)
+    if err != nil {
+      log.Println("ERROR: Pluggable backends cannot be loaded, because:" + err.Error())
+      return
+    }
+  }
   validator, err := admit.CreateNewValidator()
   if err != nil {
     log.Println("ERROR: Cannot create DANM REST client, because:" + err.Error())
@@ -43,6 +55,7 @@ func main() {
   http.HandleFunc("/netvalidation", validator.ValidateNetwork)
   http.HandleFunc("/confvalidation", validator.ValidateTenantConfig)
   http.HandleFunc("/netdeletion", validator.DeleteNetwork)
+  http.HandleFunc("/podvalidation", validator.ValidatePod)
   server := &http.Server{
     Addr:         *address + ":" + strconv.Itoa(*port),
     TLSConfig:    &tls.Config{Certificates: []tls.Certificate{tlsConf}},
@@ -2,6 +2,7 @@ package v1
 
 import (
   meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+  "k8s.io/apimachinery/pkg/runtime"
   "k8s.io/apimachinery/pkg/types"
 )
 
@@ -25,6 +26,8 @@ type DanmNetOption struct {
   Device string  `json:"host_device,omitempty"`
   // The resource_pool contains allocated device IDs
   DevicePool string  `json:"device_pool,omitempty"`
+  // The host devices a host-device network hands out whole to the connecting Pods
+  HostDevices []string `json:"host_devices,omitempty"`
   // the vxlan id on the host device (creation of vxlan interface)
   Vxlan  int  `json:"vxlan,omitempty"`
   // The name of the interface in the container
@@ -43,14 +46,52 @@ type DanmNetOption struct {
   Net6    string  `json:"net6,omitempty"`
   // IPv6 routes for this network
   Routes6 map[string]string  `json:"routes6,omitempty"`
+  // IPv4, and IPv6 routes for this network with multiple next-hops, and extended attributes
+  ExtendedRoutes []IpRoute `json:"extended_routes,omitempty"`
   // bit array tracking IPv6 allocations
   Alloc6  string  `json:"alloc6,omitempty"`
   // subset of the IPv6 subnet from which IPs can be allocated
   Pool6   IpPoolV6 `json:"allocation_pool_v6,omitEmpty"`
-  // Routing table number for policy routing
+  // Routing table number for policy routing, and the table of the VRF of the network
+  // When it is not set, the table holding the policy-based routes of a Pod interface is allocated automatically
   RTables int `json:"rt_tables,omitempty"`
-  // the VLAN id of the VLAN interface created on top of the host device
+  // Name of the VRF device the Pod interfaces of the network are enslaved to inside the Pod
+  Vrf string `json:"vrf,omitempty"`
+  // the VLAN id of the VLAN interface created on top of the host device, or the VLAN tag of the port for ovs networks
   Vlan  int  `json:"vlan,omitempty"`
+  // VLANs, and VLAN ranges carried by the trunk port of ovs networks
+  Trunk []VlanTrunk `json:"trunk,omitempty"`
+  // MTU of the interfaces, and bridges DANM creates for the network
+  MTU   int  `json:"mtu,omitempty"`
+  // Mode of the MACVLAN interfaces of macvlan networks
+  MacvlanMode string `json:"macvlan_mode,omitempty"`
+  // Source MAC addresses the MACVLAN interfaces of macvlan networks in source mode accept traffic from
+  MacvlanSourceMacs []string `json:"macvlan_source_macs,omitempty"`
+  // Mode of the IPVLAN interfaces of ipvlan networks: l2, l3, or l3s
+  IpvlanMode string `json:"ipvlan_mode,omitempty"`
+  // Flag of the IPVLAN interfaces of ipvlan networks: bridge, private, or vepa
+  IpvlanFlag string `json:"ipvlan_flag,omitempty"`
+  // Existing host VRF the host end of the veth pairs of veth networks is enslaved to
+  HostVrf string `json:"host_vrf,omitempty"`
+  // Host routing table the routes towards the Pods of veth networks are added to, when no host_vrf is set
+  HostRTable int `json:"host_rt_table,omitempty"`
+  // Properties of the VFs of sriov networks, overridable per Pod interface
+  Vf *VfOptions `json:"vf,omitempty"`
+  // Repetition of the gratuitous ARPs, and unsolicited Neighbor Advertisements announcing the addresses of new interfaces
+  NeighAnnounce *NeighAnnounce `json:"neigh_announce,omitempty"`
+  // Enables hairpin mode on the bridge ports of dynamic bridge networks
+  HairpinMode bool `json:"hairpin_mode,omitempty"`
+  // Enables promiscuous mode on the bridge of dynamic bridge networks
+  PromiscMode bool `json:"promisc_mode,omitempty"`
+  // CNI plugins invoked in a chain after the interface was created, e.g. tuning, bandwidth, or portmap
+  ChainedPlugins []ChainedPlugin `json:"chained_plugins,omitempty"`
+}
+
+type ChainedPlugin struct {
+  // Name of the CNI plugin binary
+  Type string `json:"type"`
+  // Plugin specific parameters, merged into the CNI config of the plugin as they are
+  Args runtime.RawExtension `json:"args,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -60,6 +101,71 @@ type DanmNetList struct {
   Items            []DanmNet `json:"items"`
 }
 
+type VlanTrunk struct {
+  // A single VLAN ID
+  ID    int `json:"id,omitempty"`
+  // First VLAN ID of a range
+  MinID int `json:"min_id,omitempty"`
+  // Last VLAN ID of a range
+  MaxID int `json:"max_id,omitempty"`
+}
+
+// IpRoute is an IP route with any number of weighted next-hops, more than one resulting in an ECMP route
+type IpRoute struct {
+  // Destination subnet of the route
+  Dst      string    `json:"dst"`
+  // Next-hops of the route, a route without next-hops is directly connected to the interface
+  Nexthops []Nexthop `json:"nexthops,omitempty"`
+  // Metric, i.e. priority of the route
+  Metric   int       `json:"metric,omitempty"`
+  // Preferred source address of the packets sent via the route
+  Src      string    `json:"src,omitempty"`
+  // Scope of the route: universe (default), site, link, or host
+  Scope    string    `json:"scope,omitempty"`
+  // MTU of the route
+  MTU      int       `json:"mtu,omitempty"`
+}
+
+// Nexthop is one gateway of an IpRoute
+type Nexthop struct {
+  // Address of the gateway
+  Gw     string `json:"gw"`
+  // Relative weight of the next-hop in ECMP routes between 1 (default), and 256
+  Weight int    `json:"weight,omitempty"`
+  // The gateway is reachable via the interface even if it is not in its subnet
+  Onlink bool   `json:"onlink,omitempty"`
+}
+
+// NeighAnnounce controls how many times, and how often the addresses of a new Pod interface are announced to its neighbours
+type NeighAnnounce struct {
+  // Number of gratuitous ARPs sent for the IPv4 address, 1 by default
+  GarpCount    int `json:"garp_count,omitempty"`
+  // Milliseconds between two gratuitous ARPs
+  GarpInterval int `json:"garp_interval,omitempty"`
+  // Number of unsolicited Neighbor Advertisements sent for the IPv6 address, 1 by default
+  NaCount      int `json:"na_count,omitempty"`
+  // Milliseconds between two unsolicited Neighbor Advertisements
+  NaInterval   int `json:"na_interval,omitempty"`
+}
+
+// VfOptions are the properties of the SR-IOV VF allocated to a Pod interface
+type VfOptions struct {
+  // Spoof checking of the VF, on or off
+  SpoofChk  string `json:"spoofchk,omitempty"`
+  // Trusted mode of the VF, on or off
+  Trust     string `json:"trust,omitempty"`
+  // Link state of the VF, auto, enable, or disable
+  LinkState string `json:"link_state,omitempty"`
+  // Minimum TX rate of the VF in Mbps
+  MinTxRate int    `json:"min_tx_rate,omitempty"`
+  // Maximum TX rate of the VF in Mbps
+  MaxTxRate int    `json:"max_tx_rate,omitempty"`
+  // 802.1p priority of the VLAN tagged frames of the VF
+  VlanQoS   int    `json:"vlan_qos,omitempty"`
+  // Protocol of the VLAN of the VF, 802.1q, or 802.1ad
+  VlanProto string `json:"vlan_proto,omitempty"`
+}
+
 type IpPool struct {
   Start string `json:"start,omitEmpty"`
   End   string `json:"end,omitEmpty"`
@@ -77,6 +183,7 @@ type DanmEp struct {
   meta_v1.TypeMeta   `json:",inline"`
   meta_v1.ObjectMeta `json:"metadata"`
   Spec               DanmEpSpec `json:"spec"`
+  Status             DanmEpStatus `json:"status,omitempty"`
 }
 
 type DanmEpSpec struct {
@@ -99,7 +206,40 @@ type DanmEpIface struct {
   MacAddress  string            `json:"MacAddress"`
   Proutes     map[string]string `json:"proutes"`
   Proutes6    map[string]string `json:"proutes6"`
+  ExtendedProutes []IpRoute     `json:"extended_proutes,omitempty"`
+  RTable      int               `json:"rt_table,omitempty"`
+  Vrf         string            `json:"vrf,omitempty"`
   DeviceID    string            `json:"DeviceID,omitempty"`
+  Bond        *DanmEpBond       `json:"Bond,omitempty"`
+  Vf          *VfOptions        `json:"Vf,omitempty"`
+}
+
+// DanmEpStatus is the live state of the Pod interface, periodically refreshed by the DANM agent of the node from the network namespace of the Pod
+type DanmEpStatus struct {
+  OperState   string       `json:"operState,omitempty"`
+  Carrier     bool         `json:"carrier"`
+  MTU         int          `json:"mtu,omitempty"`
+  Addresses   []string     `json:"addresses,omitempty"`
+  Routes      []string     `json:"routes,omitempty"`
+  Statistics  *DanmEpStats `json:"statistics,omitempty"`
+  LastUpdated meta_v1.Time `json:"lastUpdated,omitempty"`
+}
+
+// DanmEpStats contains the traffic counters of a Pod interface
+type DanmEpStats struct {
+  RxBytes   uint64 `json:"rxBytes"`
+  TxBytes   uint64 `json:"txBytes"`
+  RxPackets uint64 `json:"rxPackets"`
+  TxPackets uint64 `json:"txPackets"`
+  RxErrors  uint64 `json:"rxErrors"`
+  TxErrors  uint64 `json:"txErrors"`
+}
+
+// DanmEpBond describes the bond interface DANM created in the Pod from other interfaces of the same Pod
+type DanmEpBond struct {
+  Mode   string   `json:"Mode"`
+  Miimon int      `json:"Miimon"`
+  Slaves []string `json:"Slaves"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -120,6 +260,7 @@ type TenantConfig struct {
   meta_v1.ObjectMeta            `json:"metadata"`
   HostDevices []IfaceProfile    `json:"hostDevices,omitempty"`
   NetworkIds  map[string]string `json:"networkIds,omitempty"`
+  RoutingTables string          `json:"routingTables,omitempty"`
 }
 
 type IfaceProfile struct {
@@ -24,6 +24,23 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChainedPlugin) DeepCopyInto(out *ChainedPlugin) {
+	*out = *in
+	in.Args.DeepCopyInto(&out.Args)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChainedPlugin.
+func (in *ChainedPlugin) DeepCopy() *ChainedPlugin {
+	if in == nil {
+		return nil
+	}
+	out := new(ChainedPlugin)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterNetwork) DeepCopyInto(out *ClusterNetwork) {
 	*out = *in
@@ -90,6 +107,7 @@ func (in *DanmEp) DeepCopyInto(out *DanmEp) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -111,6 +129,27 @@ func (in *DanmEp) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DanmEpBond) DeepCopyInto(out *DanmEpBond) {
+	*out = *in
+	if in.Slaves != nil {
+		in, out := &in.Slaves, &out.Slaves
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DanmEpBond.
+func (in *DanmEpBond) DeepCopy() *DanmEpBond {
+	if in == nil {
+		return nil
+	}
+	out := new(DanmEpBond)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DanmEpIface) DeepCopyInto(out *DanmEpIface) {
 	*out = *in
@@ -128,6 +167,23 @@ func (in *DanmEpIface) DeepCopyInto(out *DanmEpIface) {
 			(*out)[key] = val
 		}
 	}
+	if in.ExtendedProutes != nil {
+		in, out := &in.ExtendedProutes, &out.ExtendedProutes
+		*out = make([]IpRoute, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Bond != nil {
+		in, out := &in.Bond, &out.Bond
+		*out = new(DanmEpBond)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Vf != nil {
+		in, out := &in.Vf, &out.Vf
+		*out = new(VfOptions)
+		**out = **in
+	}
 	return
 }
 
@@ -191,6 +247,54 @@ func (in *DanmEpSpec) DeepCopy() *DanmEpSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DanmEpStats) DeepCopyInto(out *DanmEpStats) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DanmEpStats.
+func (in *DanmEpStats) DeepCopy() *DanmEpStats {
+	if in == nil {
+		return nil
+	}
+	out := new(DanmEpStats)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DanmEpStatus) DeepCopyInto(out *DanmEpStatus) {
+	*out = *in
+	if in.Addresses != nil {
+		in, out := &in.Addresses, &out.Addresses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Routes != nil {
+		in, out := &in.Routes, &out.Routes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Statistics != nil {
+		in, out := &in.Statistics, &out.Statistics
+		*out = new(DanmEpStats)
+		**out = **in
+	}
+	in.LastUpdated.DeepCopyInto(&out.LastUpdated)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DanmEpStatus.
+func (in *DanmEpStatus) DeepCopy() *DanmEpStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DanmEpStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DanmNet) DeepCopyInto(out *DanmNet) {
 	*out = *in
@@ -254,6 +358,11 @@ func (in *DanmNetList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DanmNetOption) DeepCopyInto(out *DanmNetOption) {
 	*out = *in
+	if in.HostDevices != nil {
+		in, out := &in.HostDevices, &out.HostDevices
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.Routes != nil {
 		in, out := &in.Routes, &out.Routes
 		*out = make(map[string]string, len(*in))
@@ -269,7 +378,41 @@ func (in *DanmNetOption) DeepCopyInto(out *DanmNetOption) {
 			(*out)[key] = val
 		}
 	}
+	if in.ExtendedRoutes != nil {
+		in, out := &in.ExtendedRoutes, &out.ExtendedRoutes
+		*out = make([]IpRoute, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	out.Pool6 = in.Pool6
+	if in.Trunk != nil {
+		in, out := &in.Trunk, &out.Trunk
+		*out = make([]VlanTrunk, len(*in))
+		copy(*out, *in)
+	}
+	if in.MacvlanSourceMacs != nil {
+		in, out := &in.MacvlanSourceMacs, &out.MacvlanSourceMacs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Vf != nil {
+		in, out := &in.Vf, &out.Vf
+		*out = new(VfOptions)
+		**out = **in
+	}
+	if in.NeighAnnounce != nil {
+		in, out := &in.NeighAnnounce, &out.NeighAnnounce
+		*out = new(NeighAnnounce)
+		**out = **in
+	}
+	if in.ChainedPlugins != nil {
+		in, out := &in.ChainedPlugins, &out.ChainedPlugins
+		*out = make([]ChainedPlugin, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -354,6 +497,59 @@ func (in *IpPoolV6) DeepCopy() *IpPoolV6 {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IpRoute) DeepCopyInto(out *IpRoute) {
+	*out = *in
+	if in.Nexthops != nil {
+		in, out := &in.Nexthops, &out.Nexthops
+		*out = make([]Nexthop, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IpRoute.
+func (in *IpRoute) DeepCopy() *IpRoute {
+	if in == nil {
+		return nil
+	}
+	out := new(IpRoute)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NeighAnnounce) DeepCopyInto(out *NeighAnnounce) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NeighAnnounce.
+func (in *NeighAnnounce) DeepCopy() *NeighAnnounce {
+	if in == nil {
+		return nil
+	}
+	out := new(NeighAnnounce)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Nexthop) DeepCopyInto(out *Nexthop) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Nexthop.
+func (in *Nexthop) DeepCopy() *Nexthop {
+	if in == nil {
+		return nil
+	}
+	out := new(Nexthop)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TenantConfig) DeepCopyInto(out *TenantConfig) {
 	*out = *in
@@ -484,3 +680,35 @@ func (in *TenantNetworkList) DeepCopyObject() runtime.Object {
 	}
 	return nil
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VfOptions) DeepCopyInto(out *VfOptions) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VfOptions.
+func (in *VfOptions) DeepCopy() *VfOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(VfOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VlanTrunk) DeepCopyInto(out *VlanTrunk) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VlanTrunk.
+func (in *VlanTrunk) DeepCopy() *VlanTrunk {
+	if in == nil {
+		return nil
+	}
+	out := new(VlanTrunk)
+	in.DeepCopyInto(out)
+	return out
+}
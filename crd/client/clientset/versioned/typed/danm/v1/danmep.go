@@ -40,6 +40,7 @@ type DanmEpsGetter interface {
 type DanmEpInterface interface {
 	Create(ctx context.Context, danmEp *v1.DanmEp, opts metav1.CreateOptions) (*v1.DanmEp, error)
 	Update(ctx context.Context, danmEp *v1.DanmEp, opts metav1.UpdateOptions) (*v1.DanmEp, error)
+	UpdateStatus(ctx context.Context, danmEp *v1.DanmEp, opts metav1.UpdateOptions) (*v1.DanmEp, error)
 	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
 	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
 	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.DanmEp, error)
@@ -135,6 +136,22 @@ func (c *danmEps) Update(ctx context.Context, danmEp *v1.DanmEp, opts metav1.Upd
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *danmEps) UpdateStatus(ctx context.Context, danmEp *v1.DanmEp, opts metav1.UpdateOptions) (result *v1.DanmEp, err error) {
+	result = &v1.DanmEp{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("danmeps").
+		Name(danmEp.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(danmEp).
+		Do(ctx).
+		Into(result)
+	return
+}
+
 // Delete takes name of the danmEp and deletes it. Returns an error if one occurs.
 func (c *danmEps) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
 	return c.client.Delete().
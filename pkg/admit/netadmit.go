@@ -72,7 +72,7 @@ func (validator *Validator) ValidateNetwork(responseWriter http.ResponseWriter,
     SendErroneousAdmissionResponse(responseWriter, admissionReview.Request, err)
     return
   }
-  err = postValidateManifest(newManifest)
+  err = postValidateManifest(validator.Client, newManifest)
   if err != nil {
     SendErroneousAdmissionResponse(responseWriter, admissionReview.Request, err)
     return
@@ -129,8 +129,12 @@ func mutateNetManifest(danmClient danmclientset.Interface, dnet *danmtypes.DanmN
 //So we cannot validate those rules beforehand, but we also can't be sure they are satisfied by variable user configuration.
 //Example is NetworkID related validations for TenantNetworks
 //TODO: make this also fancy when more post validation needs surface
-func postValidateManifest(dnet *danmtypes.DanmNet) error {
-  return validateNetworkId(nil, dnet, "", nil)
+func postValidateManifest(client danmclientset.Interface, dnet *danmtypes.DanmNet) error {
+  err := validateNetworkId(nil, dnet, "", nil)
+  if err != nil {
+    return err
+  }
+  return validateHostBridge(client, dnet)
 }
 
 //TODO: we could easily add CIDR + allocation pool overwrites as well for TenantNetworks, if needed
@@ -140,7 +144,7 @@ func addTenantSpecificDetails(danmClient danmclientset.Interface, tnet *danmtype
   if err != nil {
     return err
   }
-  if IsTypeDynamic(tnet.Spec.NetworkType) {
+  if IsNetworkDynamic(tnet) {
     err = allocateDetailsForDynamicBackends(danmClient, tnet,tconf)
     if err != nil {
       return err
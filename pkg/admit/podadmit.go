@@ -0,0 +1,36 @@
+package admit
+
+import (
+  "errors"
+  "encoding/json"
+  "net/http"
+  "k8s.io/api/admission/v1beta1"
+  corev1 "k8s.io/api/core/v1"
+  "github.com/nokia/danm/pkg/metacni"
+)
+
+// ValidatePod rejects Pods with an invalid DANM annotation before they are scheduled
+// It does not modify the Pod, the annotation is processed by the CNI as it is
+func (validator *Validator) ValidatePod(responseWriter http.ResponseWriter, request *http.Request) {
+  admissionReview, err := DecodeAdmissionReview(request)
+  if err != nil {
+    SendErroneousAdmissionResponse(responseWriter, admissionReview.Request, err)
+    return
+  }
+  var pod corev1.Pod
+  err = json.Unmarshal(admissionReview.Request.Object.Raw, &pod)
+  if err != nil {
+    SendErroneousAdmissionResponse(responseWriter, admissionReview.Request, errors.New("Pod manifest could not be decoded, because:" + err.Error()))
+    return
+  }
+  _, err = metacni.ParseInterfaces(pod.ObjectMeta.Annotations)
+  if err != nil {
+    SendErroneousAdmissionResponse(responseWriter, admissionReview.Request, err)
+    return
+  }
+  responseAdmissionReview := v1beta1.AdmissionReview {
+    Response: CreateReviewResponseFromPatches(nil),
+  }
+  responseAdmissionReview.Response.UID = admissionReview.Request.UID
+  SendAdmissionResponse(responseWriter, responseAdmissionReview)
+}
@@ -11,7 +11,9 @@ import (
   metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
   "k8s.io/apimachinery/pkg/runtime"
   "k8s.io/apimachinery/pkg/runtime/serializer"
+  danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
   "github.com/nokia/danm/pkg/cnidel"
+  "github.com/nokia/danm/pkg/danmep"
 )
 
 type Patch struct {
@@ -94,10 +96,20 @@ func CreateGenericPatchFromChange(path string, value interface{}) Patch {
   return patch
 }
 
+//Backends which are only dynamic for networks with a host_device, like bridge, are not considered dynamic based on their type alone
+//VLAN, and veth type networks are not dynamic either, as they do not use any host interface created by DANM
 func IsTypeDynamic(cniType string) bool {
   neType := strings.ToLower(cniType)
-  if _, ok := cnidel.SupportedNativeCnis[neType]; ok || neType == "" || neType == "ipvlan" {
+  if cni, ok := cnidel.LookupBackend(neType); (ok && !cni.HostDeviceNeeded) || (cnidel.IsDanmNativeType(neType) && neType != danmep.VlanNetworkType && neType != danmep.VethNetworkType) {
     return true
   }
   return false
 }
+
+func IsNetworkDynamic(dnet *danmtypes.DanmNet) bool {
+  if IsTypeDynamic(dnet.Spec.NetworkType) {
+    return true
+  }
+  _, ok := cnidel.GetNativeBackend(dnet)
+  return ok
+}
@@ -1,26 +1,39 @@
 package admit
 
 import (
+  "context"
   "errors"
   "net"
   "strconv"
+  "strings"
+  "encoding/json"
   admissionv1 "k8s.io/api/admission/v1beta1"
+  meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
   danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
   danmclientset "github.com/nokia/danm/crd/client/clientset/versioned"
+  "github.com/nokia/danm/pkg/cnidel"
   "github.com/nokia/danm/pkg/datastructs"
   "github.com/nokia/danm/pkg/danmep"
   "github.com/nokia/danm/pkg/ipam"
+  "github.com/nokia/danm/pkg/netcontrol"
   "k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
 )
 
 const (
   MaxNidLength = 10
+  MaxIfaceNameLength = 15
+  MaxVlanId = 4094
+  MinMtu = 68
+  MaxMtu = 65535
+  MinReservedRoutingTable = 253
 )
 
 var (
-  DanmNetMapping = []ValidatorFunc{validateIpv4Fields,validateIpv6Fields,validateAllocationPools,validateVids,validateNetworkId,validateAbsenceOfAllowedTenants,validateNeType,validateVniChange}
-  ClusterNetMapping = []ValidatorFunc{validateIpv4Fields,validateIpv6Fields,validateAllocationPools,validateVids,validateNetworkId,validateNeType,validateVniChange}
-  TenantNetMapping = []ValidatorFunc{validateIpv4Fields,validateIpv6Fields,validateAllocationPools,validateAbsenceOfAllowedTenants,validateTenantNetRules,validateNeType}
+  DanmNetMapping = []ValidatorFunc{validateIpv4Fields,validateIpv6Fields,validateAllocationPools,validateVids,validateNetworkId,validateAbsenceOfAllowedTenants,validateNeType,validateVniChange,validateChainedPlugins,validateHostDevices,validateOvsOptions,validateMacvlanOptions,validateIpvlanOptions,validateVlanNetwork,validateVethOptions,validateVrf,validateMtu,validateVfOptions,validateNeighAnnounce}
+  ClusterNetMapping = []ValidatorFunc{validateIpv4Fields,validateIpv6Fields,validateAllocationPools,validateVids,validateNetworkId,validateNeType,validateVniChange,validateChainedPlugins,validateHostDevices,validateOvsOptions,validateMacvlanOptions,validateIpvlanOptions,validateVlanNetwork,validateVethOptions,validateVrf,validateMtu,validateVfOptions,validateNeighAnnounce}
+  TenantNetMapping = []ValidatorFunc{validateIpv4Fields,validateIpv6Fields,validateAllocationPools,validateAbsenceOfAllowedTenants,validateTenantNetRules,validateNeType,validateChainedPlugins,validateHostDevices,validateOvsOptions,validateMacvlanOptions,validateIpvlanOptions,validateVlanNetwork,validateVethOptions,validateVrf,validateMtu,validateVfOptions,validateNeighAnnounce}
+  reservedChainedPluginArgs = []string{"cniVersion","name","type","prevResult","runtimeConfig"}
+  supportedMacvlanModes = []string{"bridge","private","vepa","passthru","source"}
   danmValidationConfig = map[string]ValidatorMapping {
     "DanmNet": DanmNetMapping,
     "ClusterNetwork": ClusterNetMapping,
@@ -32,16 +45,27 @@ type ValidatorFunc func(oldManifest, newManifest *danmtypes.DanmNet, opType admi
 type ValidatorMapping []ValidatorFunc
 
 func validateIpv4Fields(oldManifest, newManifest *danmtypes.DanmNet, opType admissionv1.Operation, client danmclientset.Interface) error {
-  return validateIpFields(newManifest.Spec.Options.Cidr, newManifest.Spec.Options.Routes)
+  return validateIpFields(newManifest.Spec.Options.Cidr, newManifest.Spec.Options.Routes, newManifest.Spec.Options.ExtendedRoutes, false)
 }
 
 func validateIpv6Fields(oldManifest, newManifest *danmtypes.DanmNet, opType admissionv1.Operation, client danmclientset.Interface) error {
-  return validateIpFields(newManifest.Spec.Options.Net6, newManifest.Spec.Options.Routes6)
+  return validateIpFields(newManifest.Spec.Options.Net6, newManifest.Spec.Options.Routes6, newManifest.Spec.Options.ExtendedRoutes, true)
 }
 
-func validateIpFields(cidr string, routes map[string]string) error {
+//Extended routes are listed together for both IP families, so only the ones matching the family of the CIDR are validated against it
+func validateIpFields(cidr string, routes map[string]string, extRoutes []danmtypes.IpRoute, isV6 bool) error {
+  familyRoutes := make([]danmtypes.IpRoute, 0)
+  for _, route := range extRoutes {
+    _, dst, err := net.ParseCIDR(route.Dst)
+    if err != nil {
+      return errors.New("Invalid destination of extended IP route: " + route.Dst)
+    }
+    if (dst.IP.To4() == nil) == isV6 {
+      familyRoutes = append(familyRoutes, route)
+    }
+  }
   if cidr == "" {
-    if routes != nil  {
+    if routes != nil || len(familyRoutes) > 0 {
       return errors.New("IP routes cannot be defined for a L2 network")
     }
     return nil
@@ -55,6 +79,12 @@ func validateIpFields(cidr string, routes map[string]string) error {
       return errors.New("Specified GW address:" + gw + " is not part of CIDR:" + cidr)
     }
   }
+  for _, route := range familyRoutes {
+    err = danmep.ValidateIpRoute(route, ipnet)
+    if err != nil {
+      return errors.New("Invalid extended IP route, because:" + err.Error())
+    }
+  }
   return nil
 }
 
@@ -158,10 +188,17 @@ func validateNetworkId(oldManifest, newManifest *danmtypes.DanmNet, opType admis
   if newManifest.Spec.NetworkID == "" {
     return errors.New("Spec.NetworkID mandatory parameter is missing!")
   }
-  if len(newManifest.Spec.NetworkID) > MaxNidLength && IsTypeDynamic(newManifest.Spec.NetworkType) &&
+  //OVS networks connect to an existing bridge with VLAN port tags, no host interface is named after their NetworkID
+  if strings.EqualFold(newManifest.Spec.NetworkType, "ovs") {
+    return nil
+  }
+  if len(newManifest.Spec.NetworkID) > MaxNidLength && IsNetworkDynamic(newManifest) &&
     (newManifest.Spec.Options.Vxlan != 0 || newManifest.Spec.Options.Vlan != 0) {
     return errors.New("Spec.NetworkID cannot be longer than " + strconv.Itoa(MaxNidLength) + " characters (otherwise VLAN and VxLAN host interface creation might fail)!")
   }
+  if len(newManifest.Spec.NetworkID) > MaxNidLength && !IsTypeDynamic(newManifest.Spec.NetworkType) && IsNetworkDynamic(newManifest) {
+    return errors.New("Spec.NetworkID cannot be longer than " + strconv.Itoa(MaxNidLength) + " characters for networks with a host_device (otherwise host bridge creation might fail)!")
+  }
   return nil
 }
 
@@ -178,6 +215,12 @@ func validateTenantNetRules(oldManifest, newManifest *danmtypes.DanmNet, opType
      newManifest.Spec.Options.Vlan   != 0) {
     return errors.New("Manually configuring Spec.Options.vlan, or Spec.Options.vxlan attributes is not allowed for TenantNetworks!")
   }
+  if len(newManifest.Spec.Options.HostDevices) != 0 {
+    return errors.New("Manually configuring Spec.Options.host_devices attribute is not allowed for TenantNetworks!")
+  }
+  if len(newManifest.Spec.Options.Trunk) != 0 {
+    return errors.New("Manually configuring Spec.Options.trunk attribute is not allowed for TenantNetworks!")
+  }
   if opType == admissionv1.Update &&
     (newManifest.Spec.Options.Device  != oldManifest.Spec.Options.Device  ||
      newManifest.Spec.Options.DevicePool  != oldManifest.Spec.Options.DevicePool  ||
@@ -189,8 +232,14 @@ func validateTenantNetRules(oldManifest, newManifest *danmtypes.DanmNet, opType
 }
 
 func validateTenantconfig(oldManifest, newManifest *danmtypes.TenantConfig, opType admissionv1.Operation) error {
-  if len(newManifest.HostDevices) == 0 && len(newManifest.NetworkIds) == 0 {
-    return errors.New("Either hostDevices, or networkIds must be provided!")
+  if len(newManifest.HostDevices) == 0 && len(newManifest.NetworkIds) == 0 && newManifest.RoutingTables == "" {
+    return errors.New("Either hostDevices, networkIds, or routingTables must be provided!")
+  }
+  if newManifest.RoutingTables != "" {
+    err := danmep.ValidateRoutingTables(newManifest.RoutingTables)
+    if err != nil {
+      return errors.New("routingTables:" + newManifest.RoutingTables + " is invalid because it " + err.Error())
+    }
   }
   var err error
   for _, ifaceConf := range newManifest.HostDevices {
@@ -266,3 +315,298 @@ func validateVniChange(oldManifest, newManifest *danmtypes.DanmNet, opType admis
   }
   return nil
 }
+
+func validateHostDevices(oldManifest, newManifest *danmtypes.DanmNet, opType admissionv1.Operation, client danmclientset.Interface) error {
+  hostDevices := newManifest.Spec.Options.HostDevices
+  if len(hostDevices) == 0 {
+    return nil
+  }
+  if !strings.EqualFold(newManifest.Spec.NetworkType, "host-device") {
+    return errors.New("Spec.Options.host_devices can only be provided for host-device networks!")
+  }
+  if newManifest.Spec.Options.Device != "" || newManifest.Spec.Options.DevicePool != "" {
+    return errors.New("Spec.Options.host_devices cannot be provided together with host_device, or device_pool!")
+  }
+  seenDevices := map[string]bool{}
+  for _, device := range hostDevices {
+    if device == "" || len(device) > MaxIfaceNameLength || strings.ContainsAny(device, "/ ") {
+      return errors.New("Spec.Options.host_devices contains an invalid interface name:" + device)
+    }
+    if seenDevices[device] {
+      return errors.New("Spec.Options.host_devices contains host device:" + device + " more than once")
+    }
+    seenDevices[device] = true
+  }
+  return nil
+}
+
+func validateOvsOptions(oldManifest, newManifest *danmtypes.DanmNet, opType admissionv1.Operation, client danmclientset.Interface) error {
+  isOvs := strings.EqualFold(newManifest.Spec.NetworkType, "ovs")
+  trunks := newManifest.Spec.Options.Trunk
+  if !isOvs {
+    if len(trunks) != 0 {
+      return errors.New("Spec.Options.trunk can only be provided for ovs networks!")
+    }
+    return nil
+  }
+  if newManifest.Spec.Options.Vxlan != 0 {
+    return errors.New("Spec.Options.vxlan is not supported for ovs networks!")
+  }
+  if newManifest.Spec.Options.Vlan != 0 && len(trunks) != 0 {
+    return errors.New("Spec.Options.vlan and Spec.Options.trunk are mutually exclusive for ovs networks!")
+  }
+  for _, trunk := range trunks {
+    isRange := trunk.MinID != 0 || trunk.MaxID != 0
+    if trunk.ID != 0 && isRange {
+      return errors.New("an entry of Spec.Options.trunk can either define id, or min_id and max_id, but not both!")
+    }
+    if !isRange {
+      if !isValidVlanId(trunk.ID) {
+        return errors.New("Spec.Options.trunk contains invalid VLAN ID:" + strconv.Itoa(trunk.ID))
+      }
+      continue
+    }
+    if !isValidVlanId(trunk.MinID) || !isValidVlanId(trunk.MaxID) || trunk.MinID > trunk.MaxID {
+      return errors.New("Spec.Options.trunk contains invalid VLAN range:" + strconv.Itoa(trunk.MinID) + "-" + strconv.Itoa(trunk.MaxID))
+    }
+  }
+  return nil
+}
+
+func validateMacvlanOptions(oldManifest, newManifest *danmtypes.DanmNet, opType admissionv1.Operation, client danmclientset.Interface) error {
+  mode := newManifest.Spec.Options.MacvlanMode
+  sourceMacs := newManifest.Spec.Options.MacvlanSourceMacs
+  if mode == "" && len(sourceMacs) == 0 {
+    return nil
+  }
+  if !strings.EqualFold(newManifest.Spec.NetworkType, "macvlan") {
+    return errors.New("Spec.Options.macvlan_mode and Spec.Options.macvlan_source_macs can only be provided for macvlan networks!")
+  }
+  //source mode MACVLANs only accept traffic from the listed MACs, so the list is mandatory for them, and meaningless for the other modes
+  if mode != "source" {
+    if len(sourceMacs) != 0 {
+      return errors.New("Spec.Options.macvlan_source_macs can only be provided together with macvlan_mode source!")
+    }
+  } else if len(sourceMacs) == 0 {
+    return errors.New("Spec.Options.macvlan_source_macs is mandatory for macvlan_mode source!")
+  }
+  for _, mac := range sourceMacs {
+    _, err := net.ParseMAC(mac)
+    if err != nil {
+      return errors.New("Spec.Options.macvlan_source_macs contains an invalid MAC address:" + mac)
+    }
+  }
+  for _, supportedMode := range supportedMacvlanModes {
+    if mode == supportedMode {
+      return nil
+    }
+  }
+  return errors.New("Spec.Options.macvlan_mode:" + mode + " is invalid, supported modes are:" + strings.Join(supportedMacvlanModes, ","))
+}
+
+func validateIpvlanOptions(oldManifest, newManifest *danmtypes.DanmNet, opType admissionv1.Operation, client danmclientset.Interface) error {
+  mode := newManifest.Spec.Options.IpvlanMode
+  flag := newManifest.Spec.Options.IpvlanFlag
+  if mode == "" && flag == "" {
+    return nil
+  }
+  neType := strings.ToLower(newManifest.Spec.NetworkType)
+  if neType != "" && neType != "ipvlan" {
+    return errors.New("Spec.Options.ipvlan_mode and Spec.Options.ipvlan_flag can only be provided for ipvlan networks!")
+  }
+  if !danmep.IsIpvlanModeSupported(mode) {
+    return errors.New("Spec.Options.ipvlan_mode:" + mode + " is invalid, supported modes are:" + strings.Join([]string{danmep.IpvlanModeL2,danmep.IpvlanModeL3,danmep.IpvlanModeL3S}, ","))
+  }
+  if !danmep.IsIpvlanFlagSupported(flag) {
+    return errors.New("Spec.Options.ipvlan_flag:" + flag + " is invalid, supported flags are:" + strings.Join([]string{danmep.IpvlanFlagBridge,danmep.IpvlanFlagPrivate,danmep.IpvlanFlagVepa}, ","))
+  }
+  if opType != admissionv1.Update || (strings.EqualFold(oldManifest.Spec.Options.IpvlanMode, mode) && strings.EqualFold(oldManifest.Spec.Options.IpvlanFlag, flag)) {
+    return nil
+  }
+  //Existing IPVLAN interfaces keep their mode, while the host routes would follow the new one
+  isAnyPodConnectedToNetwork, connectedEp, err := danmep.ArePodsConnectedToNetwork(client, oldManifest)
+  if err != nil {
+    return errors.New("no way to tell if Pods are still using the network due to:" + err.Error())
+  }
+  if isAnyPodConnectedToNetwork {
+    return errors.New("cannot change ipvlan_mode/ipvlan_flag of a network which having any Pods connected to it e.g. Pod:" + connectedEp.Spec.Pod + " in namespace:" + connectedEp.ObjectMeta.Namespace)
+  }
+  return nil
+}
+
+//The sub-interface of a VLAN network is moved into the Pod, so it always needs an explicit host device and VLAN ID
+func validateVlanNetwork(oldManifest, newManifest *danmtypes.DanmNet, opType admissionv1.Operation, client danmclientset.Interface) error {
+  if !strings.EqualFold(newManifest.Spec.NetworkType, danmep.VlanNetworkType) {
+    return nil
+  }
+  if newManifest.Spec.Options.Device == "" || newManifest.Spec.Options.DevicePool != "" {
+    return errors.New("Spec.Options.host_device must be provided, and Spec.Options.device_pool must not be provided for vlan networks!")
+  }
+  if newManifest.Spec.Options.Vlan == 0 {
+    return errors.New("Spec.Options.vlan is mandatory for vlan networks!")
+  }
+  return nil
+}
+
+//Netwatcher deletes the host bridge of a dynamic bridge network together with the host interface enslaved to it
+//So neither of them can be shared with another bridge network, otherwise deleting one network would tear down the other
+//NetworkIDs, and host devices of TenantNetworks are only known after mutation, so this rule is validated after it
+func validateHostBridge(client danmclientset.Interface, dnet *danmtypes.DanmNet) error {
+  if !strings.EqualFold(dnet.Spec.NetworkType, "bridge") || !IsNetworkDynamic(dnet) {
+    return nil
+  }
+  bridgeNets, err := getDynamicBridgeNetworks(client)
+  if err != nil {
+    return errors.New("no way to tell if the host bridge of the network is used by other networks due to:" + err.Error())
+  }
+  brName := netcontrol.DetermineBridgeName(dnet)
+  brPort := netcontrol.DetermineHostDeviceName(dnet)
+  for _, bnet := range bridgeNets {
+    if bnet.TypeMeta.Kind == dnet.TypeMeta.Kind && bnet.ObjectMeta.Namespace == dnet.ObjectMeta.Namespace && bnet.ObjectMeta.Name == dnet.ObjectMeta.Name {
+      continue
+    }
+    if netcontrol.DetermineBridgeName(bnet) == brName {
+      return errors.New("host bridge:" + brName + " already belongs to " + bnet.TypeMeta.Kind + ":" + bnet.ObjectMeta.Name + ", Spec.NetworkID must be unique among bridge networks!")
+    }
+    if netcontrol.DetermineHostDeviceName(bnet) == brPort {
+      return errors.New("host interface:" + brPort + " is already enslaved to the host bridge of " + bnet.TypeMeta.Kind + ":" + bnet.ObjectMeta.Name + ", it cannot be enslaved to another bridge!")
+    }
+  }
+  return nil
+}
+
+func getDynamicBridgeNetworks(client danmclientset.Interface) ([]*danmtypes.DanmNet, error) {
+  nets := make([]*danmtypes.DanmNet, 0)
+  dnets, err := client.DanmV1().DanmNets("").List(context.TODO(), meta_v1.ListOptions{})
+  if err != nil {
+    return nil, errors.New("cannot list DanmNets because:" + err.Error())
+  }
+  if dnets != nil {
+    for i := range dnets.Items {
+      dnets.Items[i].TypeMeta.Kind = netcontrol.DanmNetKind
+      nets = append(nets, &dnets.Items[i])
+    }
+  }
+  tnets, err := client.DanmV1().TenantNetworks("").List(context.TODO(), meta_v1.ListOptions{})
+  if err != nil {
+    return nil, errors.New("cannot list TenantNetworks because:" + err.Error())
+  }
+  if tnets != nil {
+    for i := range tnets.Items {
+      nets = append(nets, netcontrol.ConvertTnetToDnet(&tnets.Items[i]))
+    }
+  }
+  cnets, err := client.DanmV1().ClusterNetworks().List(context.TODO(), meta_v1.ListOptions{})
+  if err != nil {
+    return nil, errors.New("cannot list ClusterNetworks because:" + err.Error())
+  }
+  if cnets != nil {
+    for i := range cnets.Items {
+      nets = append(nets, netcontrol.ConvertCnetToDnet(&cnets.Items[i]))
+    }
+  }
+  bridgeNets := make([]*danmtypes.DanmNet, 0)
+  for _, bnet := range nets {
+    if strings.EqualFold(bnet.Spec.NetworkType, "bridge") && IsNetworkDynamic(bnet) {
+      bridgeNets = append(bridgeNets, bnet)
+    }
+  }
+  return bridgeNets, nil
+}
+
+//The host end of veth pairs is routed, so such networks are not connected to any host device, VLAN, or VxLAN
+func validateVethOptions(oldManifest, newManifest *danmtypes.DanmNet, opType admissionv1.Operation, client danmclientset.Interface) error {
+  opts := newManifest.Spec.Options
+  if !strings.EqualFold(newManifest.Spec.NetworkType, danmep.VethNetworkType) {
+    if opts.HostVrf != "" || opts.HostRTable != 0 {
+      return errors.New("Spec.Options.host_vrf and Spec.Options.host_rt_table can only be provided for veth networks!")
+    }
+    return nil
+  }
+  if opts.Device != "" || opts.DevicePool != "" || opts.Vlan != 0 || opts.Vxlan != 0 {
+    return errors.New("Spec.Options.host_device, device_pool, vlan, and vxlan cannot be provided for veth networks!")
+  }
+  if opts.HostVrf != "" && opts.HostRTable != 0 {
+    return errors.New("Spec.Options.host_vrf and Spec.Options.host_rt_table are mutually exclusive!")
+  }
+  if opts.HostRTable < 0 {
+    return errors.New("Spec.Options.host_rt_table:" + strconv.Itoa(opts.HostRTable) + " is not a valid routing table!")
+  }
+  return nil
+}
+
+//The main, local, and default tables are used by the Pod itself, so they cannot belong to a VRF
+func validateVrf(oldManifest, newManifest *danmtypes.DanmNet, opType admissionv1.Operation, client danmclientset.Interface) error {
+  vrf := newManifest.Spec.Options.Vrf
+  if vrf == "" {
+    return nil
+  }
+  if len(vrf) > MaxIfaceNameLength || strings.ContainsAny(vrf, "/ ") {
+    return errors.New("Spec.Options.vrf:" + vrf + " is not a valid interface name!")
+  }
+  rtable := newManifest.Spec.Options.RTables
+  if rtable <= 0 || rtable >= MinReservedRoutingTable {
+    return errors.New("Spec.Options.rt_tables must be between 1 and " + strconv.Itoa(MinReservedRoutingTable-1) + " for networks with a vrf, but it is:" + strconv.Itoa(rtable))
+  }
+  return nil
+}
+
+func validateVfOptions(oldManifest, newManifest *danmtypes.DanmNet, opType admissionv1.Operation, client danmclientset.Interface) error {
+  vf := newManifest.Spec.Options.Vf
+  if vf == nil {
+    return nil
+  }
+  if !strings.EqualFold(newManifest.Spec.NetworkType, "sriov") {
+    return errors.New("Spec.Options.vf can only be provided for sriov networks!")
+  }
+  if (vf.VlanQoS != 0 || vf.VlanProto != "") && newManifest.Spec.Options.Vlan == 0 {
+    return errors.New("Spec.Options.vf.vlan_qos and Spec.Options.vf.vlan_proto can only be provided together with Spec.Options.vlan!")
+  }
+  err := cnidel.ValidateVfOptions(vf)
+  if err != nil {
+    return errors.New("Spec.Options.vf is invalid because:" + err.Error())
+  }
+  return nil
+}
+
+func validateNeighAnnounce(oldManifest, newManifest *danmtypes.DanmNet, opType admissionv1.Operation, client danmclientset.Interface) error {
+  err := danmep.ValidateNeighAnnounce(newManifest.Spec.Options.NeighAnnounce)
+  if err != nil {
+    return errors.New("Spec.Options.neigh_announce is invalid because:" + err.Error())
+  }
+  return nil
+}
+
+func validateMtu(oldManifest, newManifest *danmtypes.DanmNet, opType admissionv1.Operation, client danmclientset.Interface) error {
+  mtu := newManifest.Spec.Options.MTU
+  if mtu != 0 && (mtu < MinMtu || mtu > MaxMtu) {
+    return errors.New("Spec.Options.mtu:" + strconv.Itoa(mtu) + " is out of the valid range:" + strconv.Itoa(MinMtu) + "-" + strconv.Itoa(MaxMtu))
+  }
+  return nil
+}
+
+func isValidVlanId(vlanId int) bool {
+  return vlanId > 0 && vlanId <= MaxVlanId
+}
+
+func validateChainedPlugins(oldManifest, newManifest *danmtypes.DanmNet, opType admissionv1.Operation, client danmclientset.Interface) error {
+  for index, plugin := range newManifest.Spec.Options.ChainedPlugins {
+    if plugin.Type == "" || strings.ContainsAny(plugin.Type, "/\\") {
+      return errors.New("type of chained plugin no.:" + strconv.Itoa(index) + " must be the name of a CNI binary, but it is:" + plugin.Type)
+    }
+    if len(plugin.Args.Raw) == 0 {
+      continue
+    }
+    var args map[string]interface{}
+    err := json.Unmarshal(plugin.Args.Raw, &args)
+    if err != nil {
+      return errors.New("args of chained plugin:" + plugin.Type + " must be a JSON object, but its parsing fails with:" + err.Error())
+    }
+    for _, reservedArg := range reservedChainedPluginArgs {
+      if _, ok := args[reservedArg]; ok {
+        return errors.New("args of chained plugin:" + plugin.Type + " cannot contain:" + reservedArg + ", because it is set by DANM")
+      }
+    }
+  }
+  return nil
+}
@@ -0,0 +1,351 @@
+package agent
+
+import (
+  "errors"
+  "fmt"
+  "log"
+  "net"
+  "os"
+  "path/filepath"
+  "runtime/debug"
+  "sync"
+  "time"
+  "context"
+  "encoding/json"
+  "github.com/containernetworking/cni/pkg/skel"
+  "github.com/containernetworking/cni/pkg/types"
+  "k8s.io/apimachinery/pkg/labels"
+  "k8s.io/client-go/kubernetes"
+  "k8s.io/client-go/rest"
+  "k8s.io/client-go/tools/cache"
+  danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
+  danmclientset "github.com/nokia/danm/crd/client/clientset/versioned"
+  danminformers "github.com/nokia/danm/crd/client/informers/externalversions"
+  danmlisters "github.com/nokia/danm/crd/client/listers/danm/v1"
+  "github.com/nokia/danm/pkg/danmep"
+  "github.com/nokia/danm/pkg/metacni"
+)
+
+const (
+  DefaultSocketPath = "/var/run/danm/danm-agent.sock"
+  ReplayInterval = 1 * time.Minute
+  CleanupInterval = 10 * time.Minute
+  StatusInterval = 30 * time.Second
+  StatsInterval = 5 * time.Minute
+  CniAddOp = "ADD"
+  CniDelOp = "DEL"
+  CniCheckOp = "CHECK"
+)
+
+// CniRequest is the message the thin DANM CNI shim forwards to the node-local agent
+// It contains everything skel would otherwise read from the environment, and the standard input of the CNI invocation
+// The shim stops waiting for the response at Deadline, so the agent finishes the operation before it
+type CniRequest struct {
+  Env      map[string]string `json:"env"`
+  Config   []byte            `json:"config"`
+  Deadline time.Time         `json:"deadline,omitempty"`
+}
+
+// CniResponse is the answer of the node-local agent to a forwarded CNI request
+// Result is only set for a successful ADD, Error is set for every failed operation
+type CniResponse struct {
+  Result json.RawMessage `json:"result,omitempty"`
+  Error  *types.Error     `json:"error,omitempty"`
+}
+
+// Agent represents the node-local DANM daemon serving CNI requests over a unix socket
+// It keeps persistent API connections, and an informer backed DanmEp cache, which are shared by all CNI operations
+// CNI operations of different containers are executed in parallel, only the operations of the same container wait for each other
+type Agent struct {
+  SocketPath string
+  DanmClient danmclientset.Interface
+  K8sClient kubernetes.Interface
+  DanmFactory danminformers.SharedInformerFactory
+  StatusInterval time.Duration
+  StatsInterval time.Duration
+  StatusGetter func(*danmtypes.DanmEp) (*danmtypes.DanmEpStatus, error)
+  //CNI operations share the lock, while the periodic maintenance of the node-local store excludes all of them
+  mux sync.RWMutex
+  containerLocks map[string]*containerLock
+  containerLocksMux sync.Mutex
+}
+
+type containerLock struct {
+  sync.Mutex
+  waiters int
+}
+
+// NewAgent initializes the API clients, and the DanmEp informer of the node-local DANM agent
+func NewAgent(cfg *rest.Config, socketPath string) (*Agent,error) {
+  danmClient, err := danmclientset.NewForConfig(cfg)
+  if err != nil {
+    return nil, errors.New("cannot create DANM REST client because:" + err.Error())
+  }
+  k8sClient, err := kubernetes.NewForConfig(cfg)
+  if err != nil {
+    return nil, errors.New("cannot create K8s REST client because:" + err.Error())
+  }
+  if socketPath == "" {
+    socketPath = DefaultSocketPath
+  }
+  agent := &Agent{
+    SocketPath: socketPath,
+    DanmClient: danmClient,
+    K8sClient: k8sClient,
+    DanmFactory: danminformers.NewSharedInformerFactory(danmClient, time.Minute*10),
+    StatusInterval: StatusInterval,
+    StatsInterval: StatsInterval,
+    StatusGetter: danmep.GetInterfaceStatus,
+  }
+  return agent, nil
+}
+
+// Run starts the DanmEp informer, waits for its cache to be filled, then serves CNI requests until the stop channel is closed
+func (agent *Agent) Run(stopCh <-chan struct{}) error {
+  epInformer := agent.DanmFactory.Danm().V1().DanmEps()
+  epLister := epInformer.Lister()
+  agent.DanmFactory.Start(stopCh)
+  if !cache.WaitForCacheSync(stopCh, epInformer.Informer().HasSynced) {
+    return errors.New("DanmEp cache could not be synchronized")
+  }
+  metacni.SetAgentCaches(&metacni.AgentCaches{
+    DanmClient: agent.DanmClient,
+    K8sClient:  agent.K8sClient,
+    EpLister:   epLister,
+  })
+  listener, err := agent.listen()
+  if err != nil {
+    return err
+  }
+  go func() {
+    <-stopCh
+    listener.Close()
+  }()
+  go agent.replayPendingReleases(stopCh)
+  go agent.cleanupStaleReservations(stopCh)
+  go agent.refreshEpStatuses(stopCh, epLister)
+  log.Println("INFO: DANM agent is serving CNI requests on socket:" + agent.SocketPath)
+  for {
+    conn, err := listener.Accept()
+    if err != nil {
+      select {
+      case <-stopCh:
+        return nil
+      default:
+        log.Println("ERROR: DANM agent could not accept connection because:" + err.Error())
+        continue
+      }
+    }
+    go agent.serveConnection(conn)
+  }
+}
+
+//DanmEp releases queued by offline CNI DELs are periodically retried, so they do not need to wait for the next CNI DEL on the node
+func (agent *Agent) replayPendingReleases(stopCh <-chan struct{}) {
+  ticker := time.NewTicker(ReplayInterval)
+  defer ticker.Stop()
+  for {
+    select {
+    case <-stopCh:
+      return
+    case <-ticker.C:
+      agent.mux.Lock()
+      metacni.ReplayPendingReleases(agent.DanmClient, nil)
+      agent.mux.Unlock()
+    }
+  }
+}
+
+//Reservations leaked by delegated plugins using host-local IPAM, or by interfaces using host devices would eventually exhaust their pools
+func (agent *Agent) cleanupStaleReservations(stopCh <-chan struct{}) {
+  host, err := os.Hostname()
+  if err != nil {
+    log.Println("ERROR: stale reservations are not cleaned, because the name of the host cannot be determined:" + err.Error())
+    return
+  }
+  ticker := time.NewTicker(CleanupInterval)
+  defer ticker.Stop()
+  for {
+    select {
+    case <-stopCh:
+      return
+    case <-ticker.C:
+      agent.mux.Lock()
+      metacni.CleanupStaleReservations(host)
+      agent.mux.Unlock()
+    }
+  }
+}
+
+//The state of the interfaces is read from the Pods' network namespaces without the CNI lock, so CNI operations are never delayed by it
+func (agent *Agent) refreshEpStatuses(stopCh <-chan struct{}, epLister danmlisters.DanmEpLister) {
+  host, err := os.Hostname()
+  if err != nil {
+    log.Println("ERROR: DanmEp statuses are not refreshed, because the name of the host cannot be determined:" + err.Error())
+    return
+  }
+  if agent.StatusInterval <= 0 {
+    log.Println("INFO: DanmEp statuses are not refreshed, because the status refresh interval is not positive")
+    return
+  }
+  ticker := time.NewTicker(agent.StatusInterval)
+  defer ticker.Stop()
+  for {
+    select {
+    case <-stopCh:
+      return
+    case <-ticker.C:
+      agent.RefreshEpStatusesOfHost(host, epLister)
+    }
+  }
+}
+
+// RefreshEpStatusesOfHost reads the state of the Pod interfaces of the host, and writes it into their DanmEps when it changed
+// Every status write is an update event for every DanmEp watcher, so unchanged statuses are not written
+func (agent *Agent) RefreshEpStatusesOfHost(host string, epLister danmlisters.DanmEpLister) {
+  eps, err := epLister.List(labels.Everything())
+  if err != nil {
+    log.Println("WARNING: DanmEps could not be listed for status refresh because:" + err.Error())
+    return
+  }
+  for _, ep := range eps {
+    if ep.Spec.Host != host || ep.ObjectMeta.DeletionTimestamp != nil {
+      continue
+    }
+    status, err := agent.StatusGetter(ep)
+    if err != nil {
+      log.Println("WARNING: status of DanmEp:" + ep.ObjectMeta.Name + " could not be read because:" + err.Error())
+      continue
+    }
+    if !danmep.IsStatusChanged(&ep.Status, status, agent.StatsInterval) {
+      continue
+    }
+    err = danmep.UpdateDanmEpStatus(context.TODO(), agent.DanmClient, ep, status)
+    if err != nil {
+      log.Println("WARNING: status of DanmEp:" + ep.ObjectMeta.Name + " could not be updated because:" + err.Error())
+    }
+  }
+}
+
+func (agent *Agent) listen() (net.Listener,error) {
+  err := os.MkdirAll(filepath.Dir(agent.SocketPath), 0700)
+  if err != nil {
+    return nil, errors.New("cannot create directory for socket:" + agent.SocketPath + " because:" + err.Error())
+  }
+  //A socket file left behind by a previous instance would make the bind fail
+  os.Remove(agent.SocketPath)
+  listener, err := net.Listen("unix", agent.SocketPath)
+  if err != nil {
+    return nil, errors.New("cannot listen on socket:" + agent.SocketPath + " because:" + err.Error())
+  }
+  err = os.Chmod(agent.SocketPath, 0600)
+  if err != nil {
+    listener.Close()
+    return nil, errors.New("cannot restrict permissions of socket:" + agent.SocketPath + " because:" + err.Error())
+  }
+  return listener, nil
+}
+
+func (agent *Agent) serveConnection(conn net.Conn) {
+  defer conn.Close()
+  var request CniRequest
+  err := json.NewDecoder(conn).Decode(&request)
+  if err != nil {
+    log.Println("ERROR: DANM agent received a malformed CNI request:" + err.Error())
+    return
+  }
+  response := agent.handleRequest(&request)
+  err = json.NewEncoder(conn).Encode(response)
+  if err != nil {
+    log.Println("ERROR: DANM agent could not send CNI response because:" + err.Error())
+  }
+}
+
+func (agent *Agent) handleRequest(request *CniRequest) (response *CniResponse) {
+  //A panic of one CNI operation must not take down the agent, and with it every CNI operation of the node
+  defer func() {
+    if r := recover(); r != nil {
+      log.Println("ERROR: DANM agent recovered from a panic during CNI " + request.Env["CNI_COMMAND"] + " of CID:" + request.Env["CNI_CONTAINERID"] + ":" + fmt.Sprint(r) + "\n" + string(debug.Stack()))
+      response = &CniResponse{Error: types.NewError(types.ErrInternal, "CNI " + request.Env["CNI_COMMAND"] + " failed unexpectedly:" + fmt.Sprint(r), "")}
+    }
+  }()
+  ctx, cancel := getRequestContext(request)
+  defer cancel()
+  agent.mux.RLock()
+  defer agent.mux.RUnlock()
+  unlock := agent.lockContainer(request.Env["CNI_CONTAINERID"])
+  defer unlock()
+  //The shim already gave up on a request which waited for its turn until its deadline, executing it would only leave orphaned interfaces behind
+  if ctx.Err() != nil {
+    log.Println("WARNING: DANM agent dropped CNI " + request.Env["CNI_COMMAND"] + " of CID:" + request.Env["CNI_CONTAINERID"] + ", because its deadline passed before it could be executed")
+    return &CniResponse{Error: types.NewError(types.ErrInternal, "CNI " + request.Env["CNI_COMMAND"] + " was dropped, because its deadline passed before it could be executed", "")}
+  }
+  args := &skel.CmdArgs {
+    ContainerID: request.Env["CNI_CONTAINERID"],
+    Netns:       request.Env["CNI_NETNS"],
+    IfName:      request.Env["CNI_IFNAME"],
+    Args:        request.Env["CNI_ARGS"],
+    Path:        request.Env["CNI_PATH"],
+    StdinData:   request.Config,
+  }
+  response = &CniResponse{}
+  var err error
+  switch request.Env["CNI_COMMAND"] {
+  case CniAddOp:
+    cniResult, addErr := metacni.SetupInterfaces(ctx, args)
+    if addErr == nil {
+      response.Result, addErr = json.Marshal(cniResult)
+    }
+    err = addErr
+  case CniDelOp:
+    err = metacni.DeleteInterfaces(ctx, args)
+  case CniCheckOp:
+    err = metacni.GetInterfaces(ctx, args)
+  default:
+    err = types.NewError(types.ErrInvalidEnvironmentVariables, "unknown CNI_COMMAND:" + request.Env["CNI_COMMAND"], "")
+  }
+  if err != nil {
+    response.Error = convertToCniError(err)
+  }
+  return response
+}
+
+//The operation shall return before the shim stops waiting for it, requests without a deadline are only bound by the timeouts of the operation itself
+func getRequestContext(request *CniRequest) (context.Context, context.CancelFunc) {
+  if request.Deadline.IsZero() {
+    return context.WithCancel(context.Background())
+  }
+  return context.WithDeadline(context.Background(), request.Deadline.Add(-responseMargin))
+}
+
+//The lock of a container only lives while there are operations of the container executed, or waiting
+func (agent *Agent) lockContainer(cid string) func() {
+  agent.containerLocksMux.Lock()
+  if agent.containerLocks == nil {
+    agent.containerLocks = make(map[string]*containerLock)
+  }
+  lock, isLocked := agent.containerLocks[cid]
+  if !isLocked {
+    lock = &containerLock{}
+    agent.containerLocks[cid] = lock
+  }
+  lock.waiters++
+  agent.containerLocksMux.Unlock()
+  lock.Lock()
+  return func() {
+    lock.Unlock()
+    agent.containerLocksMux.Lock()
+    lock.waiters--
+    if lock.waiters == 0 {
+      delete(agent.containerLocks, cid)
+    }
+    agent.containerLocksMux.Unlock()
+  }
+}
+
+func convertToCniError(err error) *types.Error {
+  if cniErr, isCniError := err.(*types.Error); isCniError {
+    return cniErr
+  }
+  return types.NewError(types.ErrInternal, err.Error(), "")
+}
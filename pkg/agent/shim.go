@@ -0,0 +1,97 @@
+package agent
+
+import (
+  "errors"
+  "net"
+  "time"
+  "encoding/json"
+  "github.com/containernetworking/cni/pkg/skel"
+  "github.com/nokia/danm/pkg/datastructs"
+  "github.com/nokia/danm/pkg/syncher"
+)
+
+const (
+  dialTimeout = 1 * time.Second
+  //The agent finishes the operation this much before the deadline of the request, so its response arrives before the shim gives up
+  responseMargin = 5 * time.Second
+)
+
+var (
+  // ErrAgentUnavailable is returned when there is no DANM agent listening on the node, so the CNI operation shall be executed locally
+  ErrAgentUnavailable = errors.New("DANM agent is not available")
+)
+
+// ForwardRequest sends a CNI operation to the node-local DANM agent through its unix socket
+// It returns the raw CNI result of a successful ADD operation, and ErrAgentUnavailable if no agent could be reached
+func ForwardRequest(cniOpType string, args *skel.CmdArgs) ([]byte,error) {
+  netConf := getNetConf(args.StdinData)
+  conn, err := net.DialTimeout("unix", getSocketPath(netConf), dialTimeout)
+  if err != nil {
+    return nil, ErrAgentUnavailable
+  }
+  defer conn.Close()
+  //A hung agent must not block the runtime forever, the operation times out in the agent well before the deadline
+  deadline := time.Now().Add(getResponseTimeout(cniOpType, netConf))
+  err = conn.SetDeadline(deadline)
+  if err != nil {
+    return nil, errors.New("deadline of the CNI request to DANM agent could not be set because:" + err.Error())
+  }
+  request := CniRequest {
+    Env: map[string]string {
+      "CNI_COMMAND":     cniOpType,
+      "CNI_CONTAINERID": args.ContainerID,
+      "CNI_NETNS":       args.Netns,
+      "CNI_IFNAME":      args.IfName,
+      "CNI_ARGS":        args.Args,
+      "CNI_PATH":        args.Path,
+    },
+    Config: args.StdinData,
+    Deadline: deadline,
+  }
+  err = json.NewEncoder(conn).Encode(request)
+  if err != nil {
+    return nil, errors.New("CNI request could not be forwarded to DANM agent because:" + err.Error())
+  }
+  var response CniResponse
+  err = json.NewDecoder(conn).Decode(&response)
+  if err != nil {
+    return nil, errors.New("CNI response could not be read from DANM agent because:" + err.Error())
+  }
+  if response.Error != nil {
+    return nil, response.Error
+  }
+  return response.Result, nil
+}
+
+//An invalid config is reported by the agent itself, the shim only needs the socket, and the timeouts from it
+func getNetConf(rawConfig []byte) *datastructs.NetConf {
+  netConf := &datastructs.NetConf{}
+  json.Unmarshal(rawConfig, netConf)
+  return netConf
+}
+
+func getSocketPath(netConf *datastructs.NetConf) string {
+  if netConf.AgentSocket == "" {
+    return DefaultSocketPath
+  }
+  return netConf.AgentSocket
+}
+
+//A failed, or timed-out ADD is rolled back by the agent within the ADD timeout, before it responds
+func getResponseTimeout(cniOpType string, netConf *datastructs.NetConf) time.Duration {
+  switch cniOpType {
+  case CniAddOp:
+    return getOpTimeout(netConf.AddTimeout) + responseMargin
+  case CniDelOp:
+    return getOpTimeout(netConf.DelTimeout) + responseMargin
+  default:
+    return syncher.DefaultTimeout + responseMargin
+  }
+}
+
+func getOpTimeout(seconds int) time.Duration {
+  if seconds == 0 {
+    return syncher.DefaultTimeout
+  }
+  return time.Duration(seconds) * time.Second
+}
@@ -4,6 +4,8 @@ import (
   "errors"
   "encoding/json"
   "io/ioutil"
+  "path/filepath"
+  "strconv"
   danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
   "github.com/nokia/danm/pkg/netcontrol"
   "github.com/nokia/danm/pkg/datastructs"
@@ -12,20 +14,48 @@ import (
 
 //This function creates CNI configuration for all static-level backends
 //The CNI binary matching with NetowrkType is invoked with the CNI config file matching with NetworkID parameter
-func readCniConfigFile(cniconfDir string, netInfo *danmtypes.DanmNet, ipamOptions datastructs.IpamConfig) ([]byte, error) {
+//When there is no such file, but a CNI config list exists with the same name, all the plugins of the list are invoked in a chain
+func readCniConfigFile(cniconfDir string, netInfo *danmtypes.DanmNet, ipamOptions datastructs.IpamConfig) ([]delegatePlugin, error) {
   cniConfig := netInfo.Spec.NetworkID
-  rawConfig, err := ioutil.ReadFile(cniconfDir + "/" + cniConfig + ".conf")
-  if err != nil {
-    return nil, errors.New("Could not load CNI config file: " + cniConfig +".conf for plugin:" + netInfo.Spec.NetworkType + " from directory:" + cniconfDir)
+  var plugins []delegatePlugin
+  rawConfig, err := ioutil.ReadFile(filepath.Join(cniconfDir, cniConfig + ".conf"))
+  if err == nil {
+    plugins = []delegatePlugin{{Type: netInfo.Spec.NetworkType, Config: rawConfig}}
+  } else {
+    plugins, err = readCniConfigList(filepath.Join(cniconfDir, cniConfig + ".conflist"))
+    if err != nil {
+      return nil, errors.New("Could not load CNI config file: " + cniConfig +".conf, or config list: " + cniConfig + ".conflist for plugin:" + netInfo.Spec.NetworkType + " from directory:" + cniconfDir + ", because:" + err.Error())
+    }
   }
   //Only overwrite "ipam" of the static CNI config if user wants
+  //In a chain it is always the first plugin which creates the interface, so that is the one getting the IPs
   if len(ipamOptions.Ips) > 0 {
     ipamRaw,_ := json.Marshal(ipamOptions)
     ipamInGenericFormat := map[string]interface{}{}
     json.Unmarshal(ipamRaw, &ipamInGenericFormat)
-    rawConfig = netcontrol.PatchCniConf(rawConfig, "ipam", ipamInGenericFormat)
+    plugins[0].Config = netcontrol.PatchCniConf(plugins[0].Config, "ipam", ipamInGenericFormat)
   }
-  return rawConfig, nil
+  return plugins, nil
+}
+
+func readCniConfigList(confListPath string) ([]delegatePlugin, error) {
+  rawConfList, err := ioutil.ReadFile(confListPath)
+  if err != nil {
+    return nil, err
+  }
+  pluginConfs, err := netcontrol.GetPluginConfsFromConfList(rawConfList)
+  if err != nil {
+    return nil, err
+  }
+  plugins := make([]delegatePlugin, 0, len(pluginConfs))
+  for _, pluginConf := range pluginConfs {
+    var pluginType struct {
+      Type string `json:"type"`
+    }
+    json.Unmarshal(pluginConf, &pluginType)
+    plugins = append(plugins, delegatePlugin{Type: pluginType.Type, Config: pluginConf})
+  }
+  return plugins, nil
 }
 
 //This function creates CNI configuration for the dynamic-level SR-IOV backend
@@ -42,6 +72,18 @@ func getSriovCniConfig(netInfo *danmtypes.DanmNet, ipamOptions datastructs.IpamC
   sriovConfig.Master   = pfname
   sriovConfig.Vlan     = netInfo.Spec.Options.Vlan
   sriovConfig.DeviceID = ep.Spec.Iface.DeviceID
+  vf := mergeVfOptions(netInfo.Spec.Options.Vf, ep.Spec.Iface.Vf)
+  err = ValidateVfOptions(&vf)
+  if err != nil {
+    return nil, errors.New("invalid VF properties for device "+ ep.Spec.Iface.DeviceID +" due to:" + err.Error())
+  }
+  sriovConfig.SpoofChk  = vf.SpoofChk
+  sriovConfig.Trust     = vf.Trust
+  sriovConfig.LinkState = vf.LinkState
+  sriovConfig.MinTxRate = vf.MinTxRate
+  sriovConfig.MaxTxRate = vf.MaxTxRate
+  sriovConfig.VlanQoS   = vf.VlanQoS
+  sriovConfig.VlanProto = vf.VlanProto
   if len(ipamOptions.Ips) > 0 {
     sriovConfig.Ipam   = ipamOptions
   }
@@ -52,22 +94,119 @@ func getSriovCniConfig(netInfo *danmtypes.DanmNet, ipamOptions datastructs.IpamC
   return rawConfig, nil
 }
 
-//This function creates CNI configuration for the dynamic-level MACVLAN backend
-func getMacvlanCniConfig(netInfo *danmtypes.DanmNet, ipamOptions datastructs.IpamConfig, ep *danmtypes.DanmEp, cniVersion string) ([]byte, error) {
-  var macvlanConfig MacvlanNet
-  // initialize common fields of "github.com/containernetworking/cni/pkg/types".NetConf
-  macvlanConfig.CNIVersion = cniVersion
-  macvlanConfig.Name       = netInfo.Spec.NetworkID
-  // initialize MacvlanNet specific fields:
-  macvlanConfig.Master = netcontrol.DetermineHostDeviceName(netInfo)
-  macvlanConfig.Mode   = "bridge" //TODO: make these params configurable if required
-  macvlanConfig.MTU    = 1500
+//This function creates CNI configuration for the dynamic-level bridge backend
+//The bridge itself, and the enslaving of the host device, VLAN, or VxLAN interface to it is taken care of by netwatcher
+func getBridgeCniConfig(netInfo *danmtypes.DanmNet, ipamOptions datastructs.IpamConfig, ep *danmtypes.DanmEp, cniVersion string) ([]byte, error) {
+  var bridgeConfig BridgeNet
+  bridgeConfig.CNIVersion  = cniVersion
+  bridgeConfig.Name        = netInfo.Spec.NetworkID
+  bridgeConfig.Type        = "bridge"
+  bridgeConfig.BrName      = netcontrol.DetermineBridgeName(netInfo)
+  bridgeConfig.HairpinMode = netInfo.Spec.Options.HairpinMode
+  bridgeConfig.PromiscMode = netInfo.Spec.Options.PromiscMode
+  bridgeConfig.MTU         = netInfo.Spec.Options.MTU
   if len(ipamOptions.Ips) > 0 {
-    macvlanConfig.Ipam   = ipamOptions
+    bridgeConfig.Ipam      = ipamOptions
   }
-  rawConfig, err := json.Marshal(macvlanConfig)
+  rawConfig, err := json.Marshal(bridgeConfig)
   if err != nil {
-    return nil, errors.New("Error putting together CNI config for MACVLAN plugin: " + err.Error())
+    return nil, errors.New("Error putting together CNI config for bridge plugin: " + err.Error())
   }
   return rawConfig, nil
 }
+
+//This function creates CNI configuration for the dynamic-level OVS backend
+//The host_device of the network is the OVS bridge, which is ensured to exist by netwatcher
+func getOvsCniConfig(netInfo *danmtypes.DanmNet, ipamOptions datastructs.IpamConfig, ep *danmtypes.DanmEp, cniVersion string) ([]byte, error) {
+  var ovsConfig OvsNet
+  ovsConfig.CNIVersion = cniVersion
+  ovsConfig.Name       = netInfo.Spec.NetworkID
+  ovsConfig.Type       = "ovs"
+  ovsConfig.BrName     = netInfo.Spec.Options.Device
+  ovsConfig.Vlan       = netInfo.Spec.Options.Vlan
+  ovsConfig.MTU        = netInfo.Spec.Options.MTU
+  for _, trunk := range netInfo.Spec.Options.Trunk {
+    ovsConfig.Trunk = append(ovsConfig.Trunk, OvsTrunk{ID: trunk.ID, MinID: trunk.MinID, MaxID: trunk.MaxID})
+  }
+  if len(ipamOptions.Ips) > 0 {
+    ovsConfig.Ipam     = ipamOptions
+  }
+  rawConfig, err := json.Marshal(ovsConfig)
+  if err != nil {
+    return nil, errors.New("Error putting together CNI config for OVS plugin: " + err.Error())
+  }
+  return rawConfig, nil
+}
+
+//This function creates CNI configuration for the dynamic-level host-device backend
+//Devices coming from a K8s Device pool are identified by their PCI address, the ones selected by DANM by their name
+func getHostDeviceCniConfig(netInfo *danmtypes.DanmNet, ipamOptions datastructs.IpamConfig, ep *danmtypes.DanmEp, cniVersion string) ([]byte, error) {
+  if ep.Spec.Iface.DeviceID == "" {
+    return nil, errors.New("no host device was allocated for interface:" + ep.Spec.Iface.Name)
+  }
+  var hostDeviceConfig HostDeviceNet
+  hostDeviceConfig.CNIVersion = cniVersion
+  hostDeviceConfig.Name       = netInfo.Spec.NetworkID
+  hostDeviceConfig.Type       = hostDeviceNetworkType
+  if netInfo.Spec.Options.DevicePool != "" {
+    hostDeviceConfig.PCIAddr  = ep.Spec.Iface.DeviceID
+  } else {
+    hostDeviceConfig.Device   = ep.Spec.Iface.DeviceID
+  }
+  if len(ipamOptions.Ips) > 0 {
+    hostDeviceConfig.Ipam     = ipamOptions
+  }
+  rawConfig, err := json.Marshal(hostDeviceConfig)
+  if err != nil {
+    return nil, errors.New("Error putting together CNI config for host-device plugin: " + err.Error())
+  }
+  return rawConfig, nil
+}
+
+// ValidateVfOptions checks if the VF properties of a network, or a Pod interface are understood by the SR-IOV CNI plugin
+func ValidateVfOptions(vf *danmtypes.VfOptions) error {
+  if vf == nil {
+    return nil
+  }
+  if vf.SpoofChk != "" && vf.SpoofChk != "on" && vf.SpoofChk != "off" {
+    return errors.New("spoofchk must be either on, or off, but it is:" + vf.SpoofChk)
+  }
+  if vf.Trust != "" && vf.Trust != "on" && vf.Trust != "off" {
+    return errors.New("trust must be either on, or off, but it is:" + vf.Trust)
+  }
+  if vf.LinkState != "" && vf.LinkState != "auto" && vf.LinkState != "enable" && vf.LinkState != "disable" {
+    return errors.New("link_state must be one of auto, enable, or disable, but it is:" + vf.LinkState)
+  }
+  if vf.MinTxRate < 0 || vf.MaxTxRate < 0 {
+    return errors.New("TX rates cannot be negative")
+  }
+  if vf.MaxTxRate != 0 && vf.MinTxRate > vf.MaxTxRate {
+    return errors.New("min_tx_rate:" + strconv.Itoa(vf.MinTxRate) + " cannot be larger than max_tx_rate:" + strconv.Itoa(vf.MaxTxRate))
+  }
+  if vf.VlanQoS < 0 || vf.VlanQoS > maxVlanQoS {
+    return errors.New("vlan_qos must be between 0 and " + strconv.Itoa(maxVlanQoS) + ", but it is:" + strconv.Itoa(vf.VlanQoS))
+  }
+  if vf.VlanProto != "" && vf.VlanProto != "802.1q" && vf.VlanProto != "802.1ad" {
+    return errors.New("vlan_proto must be either 802.1q, or 802.1ad, but it is:" + vf.VlanProto)
+  }
+  return nil
+}
+
+//Properties set for the Pod interface take precedence over the ones of the network
+func mergeVfOptions(netVf, ifaceVf *danmtypes.VfOptions) danmtypes.VfOptions {
+  var vf danmtypes.VfOptions
+  if netVf != nil {
+    vf = *netVf
+  }
+  if ifaceVf == nil {
+    return vf
+  }
+  if ifaceVf.SpoofChk != ""  {vf.SpoofChk  = ifaceVf.SpoofChk}
+  if ifaceVf.Trust != ""     {vf.Trust     = ifaceVf.Trust}
+  if ifaceVf.LinkState != "" {vf.LinkState = ifaceVf.LinkState}
+  if ifaceVf.MinTxRate != 0  {vf.MinTxRate = ifaceVf.MinTxRate}
+  if ifaceVf.MaxTxRate != 0  {vf.MaxTxRate = ifaceVf.MaxTxRate}
+  if ifaceVf.VlanQoS != 0    {vf.VlanQoS   = ifaceVf.VlanQoS}
+  if ifaceVf.VlanProto != "" {vf.VlanProto = ifaceVf.VlanProto}
+  return vf
+}
@@ -6,41 +6,60 @@ import (
   "log"
   "os"
   "strings"
+  "encoding/json"
   "path/filepath"
   "github.com/containernetworking/cni/pkg/invoke"
   "github.com/containernetworking/cni/pkg/types"
   "github.com/containernetworking/cni/pkg/types/current"
   "github.com/containernetworking/cni/pkg/version"
+  "github.com/vishvananda/netlink"
+  "github.com/nokia/danm/pkg/cnierrors"
   "github.com/nokia/danm/pkg/datastructs"
+  "github.com/nokia/danm/pkg/epstore"
   "github.com/nokia/danm/pkg/ipam"
+  "github.com/nokia/danm/pkg/netcontrol"
   danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
 )
 
 const (
   CniAddOp = "ADD"
   CniDelOp = "DEL"
+  CniCheckOp = "CHECK"
+  chainedPluginCniVersion = "0.3.1"
+  chainedPluginsKeySuffix = "-chained"
+  hostDeviceNetworkType = "host-device"
+  maxVlanQoS = 7
 )
 
 var (
   ipamType = "fakeipam"
-  defaultDataDir = "/var/lib/cni/networks"
-  flannelBridge = GetEnv("FLANNEL_BRIDGE", "cbr0")
+  danmNativeTypes = []string{"ipvlan", "macvlan", "vlan", "veth"}
 )
 
 // IsDelegationRequired decides if the interface creation operations should be delegated to a 3rd party CNI, or can be handled by DANM
 // Decision is made based on the NetworkType parameter of the network object
 func IsDelegationRequired(netInfo *danmtypes.DanmNet) bool {
-  neType := strings.ToLower(netInfo.Spec.NetworkType)
-  if neType == "ipvlan" || neType == "" {
-    return false
+  return !IsDanmNativeType(netInfo.Spec.NetworkType)
+}
+
+// IsDanmNativeType returns true for the NetworkTypes whose interfaces DANM creates itself, without invoking any CNI binary
+func IsDanmNativeType(neType string) bool {
+  neType = strings.ToLower(neType)
+  for _, nativeType := range danmNativeTypes {
+    if neType == nativeType {
+      return true
+    }
   }
-  return true
+  return neType == ""
 }
 
 // DelegateInterfaceSetup delegates K8s Pod network interface setup task to the input 3rd party CNI plugin
 // Returns the CNI compatible result object, or an error if interface creation was unsuccessful, or if the 3rd party CNI config could not be loaded
+// The delegated plugin is killed if the context is done before it finishes
+// The rendered config, and the result are cached on the node, so DEL and CHECK can replay exactly what ADD executed
+// Plugins declaring capabilities receive the matching part of the runtimeConfig DANM was invoked with
 //TODO: I hate myself for the bool input parameter, but that's what we are going with for the time being. Could be this information cleverly defaulted from existing DanmEp spec in all cases?
-func DelegateInterfaceSetup(netConf *datastructs.NetConf, wasIpReservedByDanmIpam bool, netInfo *danmtypes.DanmNet, ep *danmtypes.DanmEp) (*current.Result,error) {
+func DelegateInterfaceSetup(ctx context.Context, netConf *datastructs.NetConf, wasIpReservedByDanmIpam bool, netInfo *danmtypes.DanmNet, ep *danmtypes.DanmEp, runtimeConfig map[string]interface{}) (*current.Result,error) {
   var (
     err error
     ipamOptions datastructs.IpamConfig
@@ -48,23 +67,37 @@ func DelegateInterfaceSetup(netConf *datastructs.NetConf, wasIpReservedByDanmIpa
   if wasIpReservedByDanmIpam {
     ipamOptions = getCniIpamConfig(netInfo, ep.Spec.Iface.Address, ep.Spec.Iface.AddressIPv6)
   }
-  rawConfig, err := getCniPluginConfig(netConf, netInfo, ipamOptions, ep)
+  plugins, err := getCniPluginConfig(netConf, netInfo, ipamOptions, ep)
   if err != nil {
     return nil, err
   }
-  cniType := netInfo.Spec.NetworkType
-  cniResult,err := execCniPlugin(cniType, CniAddOp, netInfo, rawConfig, ep)
+  plugins, err = addRuntimeConfig(plugins, runtimeConfig)
   if err != nil {
-    return nil, errors.New("Error delegating ADD to CNI plugin:" + cniType + " because:" + err.Error())
+    return nil, err
   }
+  cniResult,err := execCniChain(ctx, netConf, plugins, nil, netInfo, ep)
+  if err != nil {
+    return nil, err
+  }
+  saveDelegateConfig(netConf, ep, ep.Spec.Iface.Name, plugins, cniResult)
   if cniResult != nil {
     setEpIfaceAddress(cniResult, &ep.Spec.Iface)
   }
   return cniResult, nil
 }
 
+// GetNativeBackend returns the dynamic-level backend the interfaces of the network are created with, or false if the network is delegated statically
+// Backends like bridge only handle networks with a host_device, otherwise their CNI config is read from the CNI config directory as before
+func GetNativeBackend(netInfo *danmtypes.DanmNet) (*datastructs.CniBackendConfig, bool) {
+  cni, ok := LookupBackend(netInfo.Spec.NetworkType)
+  if !ok || (cni.HostDeviceNeeded && netInfo.Spec.Options.Device == "") {
+    return nil, false
+  }
+  return cni, true
+}
+
 func IsDanmIpamNeededForDelegation(iface datastructs.Interface, netInfo *danmtypes.DanmNet) bool {
-  if cni, ok := SupportedNativeCnis[strings.ToLower(netInfo.Spec.NetworkType)]; ok {
+  if cni, ok := GetNativeBackend(netInfo); ok {
     return cni.IpamNeeded
   }
   //For static delegates we should only overwrite the original IPAM if an IP was explicitly "requested" from the Pod, and the request "makes sense"
@@ -82,13 +115,82 @@ func IsDanmIpamNeededForDelegation(iface datastructs.Interface, netInfo *danmtyp
 }
 
 func IsDeviceNeeded(cniType string) bool {
-  if cni, ok := SupportedNativeCnis[strings.ToLower(cniType)]; ok {
+  if cni, ok := LookupBackend(cniType); ok {
     return cni.DeviceNeeded
   } else {
     return false
   }
 }
 
+// IsDevicePoolUsed decides if the device of the interface is one of the K8s Devices allocated to the Pod from the device_pool of the network
+func IsDevicePoolUsed(netInfo *danmtypes.DanmNet) bool {
+  return IsDeviceNeeded(netInfo.Spec.NetworkType) || (isHostDeviceNetwork(netInfo) && netInfo.Spec.Options.DevicePool != "")
+}
+
+// IsHostDeviceSelectionNeeded decides if DANM needs to select a free host device for the interface from the ones listed in the network
+func IsHostDeviceSelectionNeeded(netInfo *danmtypes.DanmNet) bool {
+  return isHostDeviceNetwork(netInfo) && netInfo.Spec.Options.DevicePool == ""
+}
+
+func isHostDeviceNetwork(netInfo *danmtypes.DanmNet) bool {
+  return strings.ToLower(netInfo.Spec.NetworkType) == hostDeviceNetworkType
+}
+
+// ReserveHostDevice selects a host device of the network which is neither reserved for another infra container, nor moved away from the host netns yet
+// Selection, and reservation happen under a node-wide lock, so concurrent Pods on the same node never get the same device
+func ReserveHostDevice(netConf *datastructs.NetConf, netInfo *danmtypes.DanmNet, cid string) (string, error) {
+  store := epstore.NewStore(netConf.StoreDir)
+  unlock, err := store.LockDevices()
+  if err != nil {
+    return "", errors.New("host device of network:" + netInfo.ObjectMeta.Name + " could not be selected because:" + err.Error())
+  }
+  defer unlock()
+  for _, device := range getHostDevices(netInfo) {
+    holder, err := store.FindDeviceReservation(device)
+    if err != nil {
+      return "", err
+    }
+    if holder != "" {
+      continue
+    }
+    _, err = netlink.LinkByName(device)
+    if err != nil {
+      continue
+    }
+    err = store.ReserveDevice(device, cid)
+    if err != nil {
+      return "", err
+    }
+    return device, nil
+  }
+  return "", cnierrors.NewWithDetails(cnierrors.ErrPoolExhausted, "there are no free host devices left in network:" + netInfo.ObjectMeta.Name, netInfo.ObjectMeta.Name, nil)
+}
+
+// ReleaseHostDevice frees the host device selected for the interface, so it can be handed out to other Pods again
+func ReleaseHostDevice(netConf *datastructs.NetConf, netInfo *danmtypes.DanmNet, ep *danmtypes.DanmEp) error {
+  if !IsHostDeviceSelectionNeeded(netInfo) || ep.Spec.Iface.DeviceID == "" {
+    return nil
+  }
+  store := epstore.NewStore(netConf.StoreDir)
+  unlock, err := store.LockDevices()
+  if err != nil {
+    return errors.New("host device:" + ep.Spec.Iface.DeviceID + " could not be released because:" + err.Error())
+  }
+  defer unlock()
+  return store.ReleaseDevice(ep.Spec.Iface.DeviceID, ep.Spec.CID)
+}
+
+//A single host_device is also accepted as the list of devices, so TenantNetworks can get theirs from the interface profiles of the TenantConfig
+func getHostDevices(netInfo *danmtypes.DanmNet) []string {
+  if len(netInfo.Spec.Options.HostDevices) > 0 {
+    return netInfo.Spec.Options.HostDevices
+  }
+  if netInfo.Spec.Options.Device != "" {
+    return []string{netInfo.Spec.Options.Device}
+  }
+  return nil
+}
+
 func getCniIpamConfig(netinfo *danmtypes.DanmNet, ip4, ip6 string) datastructs.IpamConfig {
   var ipSlice = []datastructs.IpamIp{}
   if ip4 != "" && ip4 != ipam.NoneAllocType {
@@ -109,21 +211,278 @@ func getCniIpamConfig(netinfo *danmtypes.DanmNet, ip4, ip6 string) datastructs.I
           }
 }
 
-func getCniPluginConfig(netConf *datastructs.NetConf, netInfo *danmtypes.DanmNet, ipamOptions datastructs.IpamConfig, ep *danmtypes.DanmEp) ([]byte, error) {
-  if cni, ok := SupportedNativeCnis[strings.ToLower(netInfo.Spec.NetworkType)]; ok {
-    return cni.ReadConfig(netInfo, ipamOptions, ep, cni.CNIVersion)
+func getCniPluginConfig(netConf *datastructs.NetConf, netInfo *danmtypes.DanmNet, ipamOptions datastructs.IpamConfig, ep *danmtypes.DanmEp) ([]delegatePlugin, error) {
+  if cni, ok := GetNativeBackend(netInfo); ok {
+    rawConfig, err := cni.ReadConfig(netInfo, ipamOptions, ep, cni.CNIVersion)
+    if err != nil {
+      return nil, err
+    }
+    return []delegatePlugin{{Type: netInfo.Spec.NetworkType, Config: rawConfig}}, nil
   } else {
     return readCniConfigFile(netConf.CniConfigDir, netInfo, ipamOptions)
   }
 }
 
-func execCniPlugin(cniType, cniOpType string, netInfo *danmtypes.DanmNet, rawConfig []byte, ep *danmtypes.DanmEp) (*current.Result,error) {
-  cniPath, cniArgs, err := getExecCniParams(cniType, cniOpType, netInfo, ep)
+//Plugins of a chain are invoked in order, every plugin receiving the result of the previous one as prevResult
+//If a plugin fails, the ones already executed are deleted in reverse order, so a failed chain does not leave half-configured interfaces behind
+func execCniChain(ctx context.Context, netConf *datastructs.NetConf, plugins []delegatePlugin, cniResult *current.Result, netInfo *danmtypes.DanmNet, ep *danmtypes.DanmEp) (*current.Result,error) {
+  for index, plugin := range plugins {
+    rawConfig, err := addPrevResult(plugin.Config, cniResult)
+    if err != nil {
+      deleteCniChain(ctx, netConf, plugins[:index], nil, netInfo, ep)
+      return nil, errors.New("prevResult could not be passed to CNI plugin:" + plugin.Type + " because:" + err.Error())
+    }
+    pluginResult, err := execCniPlugin(ctx, netConf, plugin.Type, CniAddOp, netInfo, rawConfig, ep)
+    if err != nil {
+      deleteCniChain(ctx, netConf, plugins[:index], nil, netInfo, ep)
+      return nil, errors.New("Error delegating ADD to CNI plugin:" + plugin.Type + " because:" + err.Error())
+    }
+    //Meta plugins might not print anything, in which case the result of the chain does not change
+    if pluginResult != nil && (cniResult == nil || !isResultEmpty(pluginResult)) {
+      cniResult = pluginResult
+    }
+  }
+  return cniResult, nil
+}
+
+//DEL is invoked for every plugin of the chain in reverse order, even if some of them fail
+//Plugins supporting at least CNI 0.4.0 also receive the result of the whole chain as prevResult, when it is known
+func deleteCniChain(ctx context.Context, netConf *datastructs.NetConf, plugins []delegatePlugin, prevResult *current.Result, netInfo *danmtypes.DanmNet, ep *danmtypes.DanmEp) error {
+  var delErrors []string
+  for i := len(plugins)-1; i >= 0; i-- {
+    rawConfig := plugins[i].Config
+    if isPrevResultSupported(rawConfig) {
+      rawConfigWithResult, err := addPrevResult(rawConfig, prevResult)
+      if err == nil {
+        rawConfig = rawConfigWithResult
+      }
+    }
+    _, err := execCniPlugin(ctx, netConf, plugins[i].Type, CniDelOp, netInfo, rawConfig, ep)
+    if err != nil {
+      delErrors = append(delErrors, "Error delegating DEL to CNI plugin:" + plugins[i].Type + " because:" + err.Error())
+    }
+  }
+  if len(delErrors) > 0 {
+    return errors.New(strings.Join(delErrors, "; "))
+  }
+  return nil
+}
+
+// ExecChainedPlugins invokes the chained plugins configured for the network after its interface was created, regardless of the type of the network
+// The first plugin receives the result of the interface creation as prevResult, and the result of the last plugin is returned
+// Plugins declaring capabilities, like portmap, receive the matching part of the runtimeConfig DANM was invoked with
+func ExecChainedPlugins(ctx context.Context, netConf *datastructs.NetConf, netInfo *danmtypes.DanmNet, ep *danmtypes.DanmEp, cniResult *current.Result, runtimeConfig map[string]interface{}) (*current.Result,error) {
+  if len(netInfo.Spec.Options.ChainedPlugins) == 0 {
+    return cniResult, nil
+  }
+  plugins, err := getChainedPluginConfigs(netInfo)
+  if err != nil {
+    return nil, err
+  }
+  plugins, err = addRuntimeConfig(plugins, runtimeConfig)
+  if err != nil {
+    return nil, err
+  }
+  chainResult, err := execCniChain(ctx, netConf, plugins, cniResult, netInfo, ep)
+  if err != nil {
+    return nil, err
+  }
+  if chainResult == nil {
+    chainResult = cniResult
+  }
+  saveDelegateConfig(netConf, ep, ep.Spec.Iface.Name + chainedPluginsKeySuffix, plugins, chainResult)
+  return chainResult, nil
+}
+
+// DeleteChainedPlugins invokes DEL for the chained plugins executed during ADD, in reverse order
+// The configs cached during ADD are used when available, otherwise they are re-rendered from the network
+func DeleteChainedPlugins(ctx context.Context, netConf *datastructs.NetConf, netInfo *danmtypes.DanmNet, ep *danmtypes.DanmEp) error {
+  cacheKey := ep.Spec.Iface.Name + chainedPluginsKeySuffix
+  plugins, prevResult := loadDelegateConfig(netConf, ep, cacheKey)
+  if plugins == nil {
+    if len(netInfo.Spec.Options.ChainedPlugins) == 0 {
+      return nil
+    }
+    var err error
+    plugins, err = getChainedPluginConfigs(netInfo)
+    if err != nil {
+      return err
+    }
+  }
+  err := deleteCniChain(ctx, netConf, plugins, prevResult, netInfo, ep)
+  removeDelegateConfig(netConf, ep, cacheKey)
+  return err
+}
+
+//The CNI config of a chained plugin is its args, completed with the mandatory fields every CNI config must contain
+func getChainedPluginConfigs(netInfo *danmtypes.DanmNet) ([]delegatePlugin, error) {
+  plugins := make([]delegatePlugin, 0, len(netInfo.Spec.Options.ChainedPlugins))
+  for _, plugin := range netInfo.Spec.Options.ChainedPlugins {
+    pluginConf := map[string]interface{}{}
+    if len(plugin.Args.Raw) > 0 {
+      err := json.Unmarshal(plugin.Args.Raw, &pluginConf)
+      if err != nil {
+        return nil, errors.New("args of chained plugin:" + plugin.Type + " are not a valid JSON object:" + err.Error())
+      }
+    }
+    if pluginConf == nil {
+      pluginConf = map[string]interface{}{}
+    }
+    pluginConf["cniVersion"] = chainedPluginCniVersion
+    pluginConf["name"] = netInfo.Spec.NetworkID
+    pluginConf["type"] = plugin.Type
+    rawConfig, err := json.Marshal(pluginConf)
+    if err != nil {
+      return nil, errors.New("config of chained plugin:" + plugin.Type + " could not be marshalled:" + err.Error())
+    }
+    plugins = append(plugins, delegatePlugin{Type: plugin.Type, Config: rawConfig})
+  }
+  return plugins, nil
+}
+
+//Same as libcni, a plugin only gets the runtimeConfig of the capabilities it enables in its own config
+//It is injected before the configs are cached, so DEL also replays it
+func addRuntimeConfig(plugins []delegatePlugin, runtimeConfig map[string]interface{}) ([]delegatePlugin, error) {
+  if len(runtimeConfig) == 0 {
+    return plugins, nil
+  }
+  for index, plugin := range plugins {
+    var pluginConf map[string]interface{}
+    err := json.Unmarshal(plugin.Config, &pluginConf)
+    if err != nil {
+      return nil, errors.New("config of CNI plugin:" + plugin.Type + " is not a valid JSON object:" + err.Error())
+    }
+    capabilities, _ := pluginConf["capabilities"].(map[string]interface{})
+    pluginRuntimeConfig := map[string]interface{}{}
+    for capability, isEnabled := range capabilities {
+      if enabled, _ := isEnabled.(bool); !enabled {
+        continue
+      }
+      if value, ok := runtimeConfig[capability]; ok {
+        pluginRuntimeConfig[capability] = value
+      }
+    }
+    if len(pluginRuntimeConfig) == 0 {
+      continue
+    }
+    pluginConf["runtimeConfig"] = pluginRuntimeConfig
+    rawConfig, err := json.Marshal(pluginConf)
+    if err != nil {
+      return nil, errors.New("runtimeConfig could not be added to the config of CNI plugin:" + plugin.Type + " because:" + err.Error())
+    }
+    plugins[index].Config = rawConfig
+  }
+  return plugins, nil
+}
+
+func addPrevResult(rawConfig []byte, prevResult *current.Result) ([]byte, error) {
+  if prevResult == nil {
+    return rawConfig, nil
+  }
+  confVersion, err := (&version.ConfigDecoder{}).Decode(rawConfig)
+  if err != nil {
+    return nil, err
+  }
+  versionedResult, err := prevResult.GetAsVersion(confVersion)
+  if err != nil {
+    return nil, err
+  }
+  rawResult, err := json.Marshal(versionedResult)
+  if err != nil {
+    return nil, err
+  }
+  return netcontrol.PatchCniConf(rawConfig, "prevResult", json.RawMessage(rawResult)), nil
+}
+
+//prevResult is only part of DEL, and CHECK since CNI 0.4.0, older plugins might not even parse it
+func isPrevResultSupported(rawConfig []byte) bool {
+  confVersion, err := (&version.ConfigDecoder{}).Decode(rawConfig)
+  if err != nil {
+    return false
+  }
+  isSupported, err := version.GreaterThanOrEqualTo(confVersion, "0.4.0")
+  return err == nil && isSupported
+}
+
+//Failing to cache the config only means DEL falls back to re-rendering it, so it shall not fail the ADD
+func saveDelegateConfig(netConf *datastructs.NetConf, ep *danmtypes.DanmEp, key string, plugins []delegatePlugin, cniResult *current.Result) {
+  if ep.Spec.CID == "" {
+    return
+  }
+  rec := epstore.DelegateRecord{IfName: ep.Spec.Iface.Name, Result: cniResult}
+  for _, plugin := range plugins {
+    rec.Plugins = append(rec.Plugins, epstore.DelegatePlugin{Type: plugin.Type, Config: json.RawMessage(plugin.Config)})
+  }
+  err := epstore.NewStore(netConf.StoreDir).SaveDelegate(ep.Spec.CID, key, &rec)
+  if err != nil {
+    log.Println("WARNING: ADD: delegated CNI config of interface:" + ep.Spec.Iface.Name + " could not be cached because:" + err.Error())
+  }
+}
+
+func loadDelegateConfig(netConf *datastructs.NetConf, ep *danmtypes.DanmEp, key string) ([]delegatePlugin, *current.Result) {
+  rec, err := epstore.NewStore(netConf.StoreDir).FindDelegate(ep.Spec.CID, key)
+  if err != nil {
+    log.Println("WARNING: DEL: cached CNI config of interface:" + ep.Spec.Iface.Name + " could not be loaded, it is re-rendered instead because:" + err.Error())
+    return nil, nil
+  }
+  if rec == nil {
+    return nil, nil
+  }
+  return convertDelegatePlugins(rec.Plugins), rec.Result
+}
+
+func removeDelegateConfig(netConf *datastructs.NetConf, ep *danmtypes.DanmEp, key string) {
+  err := epstore.NewStore(netConf.StoreDir).RemoveDelegate(ep.Spec.CID, key)
+  if err != nil {
+    log.Println("WARNING: DEL: cached CNI config of interface:" + ep.Spec.Iface.Name + " could not be removed because:" + err.Error())
+  }
+}
+
+func convertDelegatePlugins(cachedPlugins []epstore.DelegatePlugin) []delegatePlugin {
+  plugins := make([]delegatePlugin, 0, len(cachedPlugins))
+  for _, plugin := range cachedPlugins {
+    plugins = append(plugins, delegatePlugin{Type: plugin.Type, Config: []byte(plugin.Config)})
+  }
+  return plugins
+}
+
+// CheckDelegatedInterfaces invokes CHECK for all the delegated, and chained plugins cached for an infra container during ADD
+// Every plugin receives the cached result of its chain as prevResult. Plugins older than CNI 0.4.0 do not implement CHECK, so they are skipped
+func CheckDelegatedInterfaces(ctx context.Context, netConf *datastructs.NetConf, cid string) error {
+  recs, err := epstore.NewStore(netConf.StoreDir).FindDelegatesByCid(cid)
+  if err != nil {
+    return errors.New("cached CNI configs could not be loaded because:" + err.Error())
+  }
+  for _, rec := range recs {
+    ep := &danmtypes.DanmEp{Spec: danmtypes.DanmEpSpec{CID: cid, Iface: danmtypes.DanmEpIface{Name: rec.IfName}}}
+    for _, plugin := range convertDelegatePlugins(rec.Plugins) {
+      if !isPrevResultSupported(plugin.Config) {
+        continue
+      }
+      rawConfig, err := addPrevResult(plugin.Config, rec.Result)
+      if err != nil {
+        return errors.New("prevResult could not be passed to CNI plugin:" + plugin.Type + " because:" + err.Error())
+      }
+      _, err = execCniPlugin(ctx, netConf, plugin.Type, CniCheckOp, nil, rawConfig, ep)
+      if err != nil {
+        return errors.New("Error delegating CHECK of interface:" + rec.IfName + " to CNI plugin:" + plugin.Type + " because:" + err.Error())
+      }
+    }
+  }
+  return nil
+}
+
+func isResultEmpty(cniResult *current.Result) bool {
+  return len(cniResult.Interfaces) == 0 && len(cniResult.IPs) == 0 && len(cniResult.Routes) == 0
+}
+
+func execCniPlugin(ctx context.Context, netConf *datastructs.NetConf, cniType, cniOpType string, netInfo *danmtypes.DanmNet, rawConfig []byte, ep *danmtypes.DanmEp) (*current.Result,error) {
+  cniPath, cniArgs, err := getExecCniParams(netConf, cniType, cniOpType, ep)
   if err != nil {
     return nil, errors.New("exec CNI params couldn't be gathered:" + err.Error())
   }
   exec := invoke.RawExec{Stderr: os.Stderr}
-  rawResult, err := exec.ExecPlugin(context.Background(), cniPath, rawConfig, cniArgs)
+  rawResult, err := exec.ExecPlugin(ctx, cniPath, rawConfig, cniArgs)
   if err != nil {
     return nil, errors.New("OS exec call failed:" + err.Error())
   }
@@ -140,19 +499,19 @@ func execCniPlugin(cniType, cniOpType string, netInfo *danmtypes.DanmNet, rawCon
   return finalResult, nil
 }
 
-func getExecCniParams(cniType, cniOpType string, netInfo *danmtypes.DanmNet, ep *danmtypes.DanmEp) (string,[]string,error) {
-  cniPaths := filepath.SplitList(os.Getenv("CNI_PATH"))
+func getExecCniParams(netConf *datastructs.NetConf, cniType, cniOpType string, ep *danmtypes.DanmEp) (string,[]string,error) {
+  cniPaths := filepath.SplitList(netConf.CniEnv.Path)
   cniPath, err := invoke.FindInPath(cniType, cniPaths)
   if err != nil {
     return "", nil, err
   }
   cniArgs := []string {
     "CNI_COMMAND="     + cniOpType,
-    "CNI_CONTAINERID=" + os.Getenv("CNI_CONTAINERID"),
-    "CNI_NETNS="       + os.Getenv("CNI_NETNS"),
+    "CNI_CONTAINERID=" + netConf.CniEnv.ContainerId,
+    "CNI_NETNS="       + netConf.CniEnv.Netns,
     "CNI_IFNAME="      + ep.Spec.Iface.Name,
-    "CNI_ARGS="        + os.Getenv("CNI_ARGS"),
-    "CNI_PATH="        + os.Getenv("CNI_PATH"),
+    "CNI_ARGS="        + netConf.CniEnv.Args,
+    "CNI_PATH="        + netConf.CniEnv.Path,
     "PATH="            + os.Getenv("PATH"),
   }
   return cniPath, cniArgs, nil
@@ -170,54 +529,39 @@ func setEpIfaceAddress(cniResult *current.Result, epIface *danmtypes.DanmEpIface
 }
 
 // DelegateInterfaceDelete delegates Ks8 Pod network interface delete task to the input 3rd party CNI plugin
+// The config cached during ADD is used when available, so DEL is not affected by changes of the network, or the CNI config files since then
 // Returns an error if interface creation was unsuccessful, or if the 3rd party CNI config could not be loaded
-func DelegateInterfaceDelete(netConf *datastructs.NetConf, netInfo *danmtypes.DanmNet, ep *danmtypes.DanmEp) error {
-  var ip4, ip6 string
-  if ipam.WasIpAllocatedByDanm(ep.Spec.Iface.Address, netInfo.Spec.Options.Cidr) {
-    ip4 = ep.Spec.Iface.Address
+func DelegateInterfaceDelete(ctx context.Context, netConf *datastructs.NetConf, netInfo *danmtypes.DanmNet, ep *danmtypes.DanmEp) error {
+  plugins, prevResult := loadDelegateConfig(netConf, ep, ep.Spec.Iface.Name)
+  if plugins == nil {
+    var ip4, ip6 string
+    if ipam.WasIpAllocatedByDanm(ep.Spec.Iface.Address, netInfo.Spec.Options.Cidr) {
+      ip4 = ep.Spec.Iface.Address
+    }
+    if ipam.WasIpAllocatedByDanm(ep.Spec.Iface.AddressIPv6, netInfo.Spec.Options.Net6) {
+      ip6 = ep.Spec.Iface.AddressIPv6
+    }
+    ipamForDelete := getCniIpamConfig(netInfo, ip4, ip6)
+    var err error
+    plugins, err = getCniPluginConfig(netConf, netInfo, ipamForDelete, ep)
+    if err != nil {
+      FreeDelegatedIps(netConf, ep)
+      return err
+    }
   }
-  if ipam.WasIpAllocatedByDanm(ep.Spec.Iface.AddressIPv6, netInfo.Spec.Options.Net6) {
-    ip6 = ep.Spec.Iface.AddressIPv6
+  err := deleteCniChain(ctx, netConf, plugins, prevResult, netInfo, ep)
+  //DANM never fails a DEL towards the runtime, so nobody would retry with the cached config anyway
+  removeDelegateConfig(netConf, ep, ep.Spec.Iface.Name)
+  //A device failed to be moved back is not present in the host netns, so it is not handed out again until it re-appears anyway
+  relErr := ReleaseHostDevice(netConf, netInfo, ep)
+  if relErr != nil {
+    log.Println("WARNING: DEL: host device:" + ep.Spec.Iface.DeviceID + " could not be released because:" + relErr.Error())
   }
-  ipamForDelete := getCniIpamConfig(netInfo, ip4, ip6)
-  rawConfig, err := getCniPluginConfig(netConf, netInfo, ipamForDelete, ep)
   if err != nil {
-    FreeDelegatedIps(netInfo, ep.Spec.Iface.Address, ep.Spec.Iface.AddressIPv6)
+    FreeDelegatedIps(netConf, ep)
     return err
   }
-  cniType := netInfo.Spec.NetworkType
-  _, err = execCniPlugin(cniType, CniDelOp, netInfo, rawConfig, ep)
-  if err != nil {
-    FreeDelegatedIps(netInfo, ep.Spec.Iface.Address, ep.Spec.Iface.AddressIPv6)
-    return errors.New("Error delegating DEL to CNI plugin:" + cniType + " because:" + err.Error())
-  }
-  return FreeDelegatedIps(netInfo, ep.Spec.Iface.Address, ep.Spec.Iface.AddressIPv6)
-}
-
-func FreeDelegatedIps(netInfo *danmtypes.DanmNet, ip4, ip6 string) error {
-  err4 := freeDelegatedIp(netInfo, ip4)
-  err6 := freeDelegatedIp(netInfo, ip6)
-  if err4 != nil {
-    return err4
-  }
-  return err6
-}
-
-func freeDelegatedIp(netInfo *danmtypes.DanmNet, ip string) error {
-  if netInfo.Spec.NetworkType == "flannel" && ip != "" && ip != ipam.NoneAllocType {
-    flannelIpExhaustionWorkaround(ip)
-  }
-  return nil
-}
-
-// Host-local IPAM management plugin uses disk-local Store by default.
-// Right now it is buggy in a sense that it does not try to free IPs if the container being deleted does not exist already.
-// But it should!
-// Exception handling 101 dear readers: ALWAYS try and reset your environment to the best of your ability during an exception
-// TODO: remove this once the problem is solved upstream
-func flannelIpExhaustionWorkaround(ip string) {
-  var dataDir = filepath.Join(defaultDataDir, flannelBridge)
-  os.Remove(filepath.Join(dataDir, ip))
+  return FreeDelegatedIps(netConf, ep)
 }
 
 // ConvertCniResult converts a CNI result from an older API version to the latest format
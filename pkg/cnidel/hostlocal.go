@@ -0,0 +1,149 @@
+package cnidel
+
+import (
+  "errors"
+  "log"
+  "net"
+  "os"
+  "strings"
+  "syscall"
+  "time"
+  "io/ioutil"
+  "path/filepath"
+  danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
+  "github.com/nokia/danm/pkg/datastructs"
+)
+
+const (
+  DefaultHostLocalDataDir = "/var/lib/cni/networks"
+  // Reservations younger than this are never considered stale, as their interface might still be under creation
+  StaleReservationGracePeriod = 10 * time.Minute
+  hostLocalLockFile = "lock"
+)
+
+// HostLocalReservation is an IP reserved by the host-local IPAM plugin, as found in its data directory
+type HostLocalReservation struct {
+  Network string
+  Ip      string
+  Cid     string
+  IfName  string
+  ModTime time.Time
+}
+
+// FreeDelegatedIps removes the host-local reservations of a Pod interface which were not released by its delegated plugin, e.g. because the container was already gone
+// Reservations are matched by container ID, and interface name; legacy reservations without an interface name by their IP
+func FreeDelegatedIps(netConf *datastructs.NetConf, ep *danmtypes.DanmEp) error {
+  ips := []string{stripPrefix(ep.Spec.Iface.Address), stripPrefix(ep.Spec.Iface.AddressIPv6)}
+  _, err := removeHostLocalReservations(getHostLocalDataDir(netConf), func(res HostLocalReservation) bool {
+    if res.Cid == ep.Spec.CID && res.Cid != "" && res.IfName == ep.Spec.Iface.Name {
+      return true
+    }
+    if res.IfName != "" || (res.Cid != "" && res.Cid != ep.Spec.CID) {
+      return false
+    }
+    for _, ip := range ips {
+      if ip != "" && ip == res.Ip {
+        return true
+      }
+    }
+    return false
+  })
+  return err
+}
+
+// CleanupStaleHostLocalReservations removes the host-local reservations of all the containers which do not exist on the node anymore
+// Reservations held by the provided live container IDs, or younger than the grace period are kept
+func CleanupStaleHostLocalReservations(netConf *datastructs.NetConf, liveCids map[string]bool) ([]HostLocalReservation, error) {
+  now := time.Now()
+  return removeHostLocalReservations(getHostLocalDataDir(netConf), func(res HostLocalReservation) bool {
+    return res.Cid != "" && !liveCids[res.Cid] && now.Sub(res.ModTime) > StaleReservationGracePeriod
+  })
+}
+
+//Every network directory is locked the same way host-local locks it, so reservations are never removed while the plugin is working on them
+func removeHostLocalReservations(dataDir string, shouldRemove func(HostLocalReservation) bool) ([]HostLocalReservation, error) {
+  removed := make([]HostLocalReservation, 0)
+  networks, err := ioutil.ReadDir(dataDir)
+  if err != nil {
+    if os.IsNotExist(err) {
+      return removed, nil
+    }
+    return nil, errors.New("host-local data directory:" + dataDir + " could not be read because:" + err.Error())
+  }
+  for _, network := range networks {
+    if !network.IsDir() {
+      continue
+    }
+    netRemoved, err := removeNetworkReservations(filepath.Join(dataDir, network.Name()), shouldRemove)
+    if err != nil {
+      log.Println("WARNING: host-local reservations of network:" + network.Name() + " could not be cleaned because:" + err.Error())
+    }
+    removed = append(removed, netRemoved...)
+  }
+  return removed, nil
+}
+
+func removeNetworkReservations(netDir string, shouldRemove func(HostLocalReservation) bool) ([]HostLocalReservation, error) {
+  removed := make([]HostLocalReservation, 0)
+  lockFile, err := os.OpenFile(filepath.Join(netDir, hostLocalLockFile), os.O_RDWR, 0)
+  if err != nil {
+    //host-local creates its lock file together with the directory, so directories without it are not owned by host-local, and are left untouched
+    if os.IsNotExist(err) {
+      return removed, nil
+    }
+    return removed, err
+  }
+  defer lockFile.Close()
+  err = syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX)
+  if err != nil {
+    return removed, err
+  }
+  defer syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN)
+  files, err := ioutil.ReadDir(netDir)
+  if err != nil {
+    return removed, err
+  }
+  for _, file := range files {
+    if file.IsDir() || net.ParseIP(file.Name()) == nil {
+      continue
+    }
+    res, err := readHostLocalReservation(netDir, file)
+    if err != nil || !shouldRemove(res) {
+      continue
+    }
+    err = os.Remove(filepath.Join(netDir, file.Name()))
+    if err != nil && !os.IsNotExist(err) {
+      log.Println("WARNING: host-local reservation of IP:" + res.Ip + " in network:" + res.Network + " could not be removed because:" + err.Error())
+      continue
+    }
+    log.Println("INFO: removed host-local reservation of IP:" + res.Ip + " in network:" + res.Network + " held by container:" + res.Cid + " interface:" + res.IfName)
+    removed = append(removed, res)
+  }
+  return removed, nil
+}
+
+//host-local stores the container ID, and since CNI plugins v0.8 also the interface name in the file named after the reserved IP, separated by a line break
+func readHostLocalReservation(netDir string, file os.FileInfo) (HostLocalReservation, error) {
+  res := HostLocalReservation{Network: filepath.Base(netDir), Ip: file.Name(), ModTime: file.ModTime()}
+  content, err := ioutil.ReadFile(filepath.Join(netDir, file.Name()))
+  if err != nil {
+    return res, err
+  }
+  lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+  res.Cid = strings.TrimSpace(lines[0])
+  if len(lines) > 1 {
+    res.IfName = strings.TrimSpace(lines[1])
+  }
+  return res, nil
+}
+
+func getHostLocalDataDir(netConf *datastructs.NetConf) string {
+  if netConf == nil || netConf.HostLocalDataDir == "" {
+    return DefaultHostLocalDataDir
+  }
+  return netConf.HostLocalDataDir
+}
+
+func stripPrefix(address string) string {
+  return strings.Split(address, "/")[0]
+}
@@ -0,0 +1,170 @@
+package cnidel
+
+import (
+  "bytes"
+  "errors"
+  "log"
+  "strings"
+  "sync"
+  "encoding/json"
+  "io/ioutil"
+  "path/filepath"
+  "text/template"
+  danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
+  "github.com/nokia/danm/pkg/datastructs"
+  "github.com/nokia/danm/pkg/netcontrol"
+)
+
+const (
+  DefaultBackendDir = "/etc/danm/backends"
+  defaultBackendCniVersion = "0.3.1"
+)
+
+var (
+  pluggableBackends = map[string]*datastructs.CniBackendConfig{}
+  backendLock sync.RWMutex
+)
+
+// BackendDefinition declares a dynamic-level backend without compiling it into DANM
+// The CNI config of the backend is rendered from a Go template for every interface
+type BackendDefinition struct {
+  // NetworkType handled by the backend, also the name of the CNI binary DANM delegates to
+  Type             string `json:"type"`
+  CNIVersion       string `json:"cniVersion,omitempty"`
+  IpamNeeded       bool   `json:"ipamNeeded,omitempty"`
+  DeviceNeeded     bool   `json:"deviceNeeded,omitempty"`
+  HostDeviceNeeded bool   `json:"hostDeviceNeeded,omitempty"`
+  // Inline template of the CNI config
+  Template         string `json:"template,omitempty"`
+  // Template file of the CNI config, relative paths are interpreted from the directory of the definition
+  TemplateFile     string `json:"templateFile,omitempty"`
+}
+
+// BackendTemplateData is what the CNI config template of a pluggable backend can refer to
+type BackendTemplateData struct {
+  CNIVersion string
+  Name       string
+  Type       string
+  Network    *danmtypes.DanmNet
+  Options    danmtypes.DanmNetOption
+  // IPAM config pointing to DANM's fakeipam plugin, empty when no IPs were allocated
+  Ipam       datastructs.IpamConfig
+  DeviceID   string
+  // The VLAN, VxLAN, or host device interfaces of the network shall be connected to
+  HostDevice string
+  IfaceName  string
+}
+
+// LoadBackends replaces the pluggable backends with the definitions found in the *.json files of the directory
+// A missing directory means there are no pluggable backends, while invalid definitions are skipped
+func LoadBackends(dir string) error {
+  backends := map[string]*datastructs.CniBackendConfig{}
+  files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+  if err != nil {
+    return errors.New("backend definitions could not be listed in directory:" + dir + " because:" + err.Error())
+  }
+  for _, file := range files {
+    name, backend, err := readBackendDefinition(file)
+    if err != nil {
+      log.Println("WARNING: backend definition:" + file + " is skipped, because:" + err.Error())
+      continue
+    }
+    backends[name] = backend
+  }
+  backendLock.Lock()
+  pluggableBackends = backends
+  backendLock.Unlock()
+  return nil
+}
+
+func readBackendDefinition(file string) (string, *datastructs.CniBackendConfig, error) {
+  rawDef, err := ioutil.ReadFile(file)
+  if err != nil {
+    return "", nil, err
+  }
+  var def BackendDefinition
+  decoder := json.NewDecoder(bytes.NewReader(rawDef))
+  decoder.DisallowUnknownFields()
+  err = decoder.Decode(&def)
+  if err != nil {
+    return "", nil, errors.New("definition could not be decoded:" + err.Error())
+  }
+  name := strings.ToLower(def.Type)
+  if name == "" {
+    return "", nil, errors.New("type is mandatory")
+  }
+  if _, ok := SupportedNativeCnis[name]; ok || IsDanmNativeType(name) {
+    return "", nil, errors.New("type:" + name + " is a built-in backend, it cannot be redefined")
+  }
+  if (def.Template == "") == (def.TemplateFile == "") {
+    return "", nil, errors.New("exactly one of template, or templateFile shall be defined")
+  }
+  text := def.Template
+  if def.TemplateFile != "" {
+    templatePath := def.TemplateFile
+    if !filepath.IsAbs(templatePath) {
+      templatePath = filepath.Join(filepath.Dir(file), templatePath)
+    }
+    rawTemplate, err := ioutil.ReadFile(templatePath)
+    if err != nil {
+      return "", nil, errors.New("template file could not be read:" + err.Error())
+    }
+    text = string(rawTemplate)
+  }
+  tmpl, err := template.New(name).Option("missingkey=error").Funcs(template.FuncMap{"json": toJson}).Parse(text)
+  if err != nil {
+    return "", nil, errors.New("template could not be parsed:" + err.Error())
+  }
+  if def.CNIVersion == "" {
+    def.CNIVersion = defaultBackendCniVersion
+  }
+  return name, &datastructs.CniBackendConfig {
+    CNIVersion: def.CNIVersion,
+    ReadConfig: renderBackendConfig(tmpl),
+    IpamNeeded: def.IpamNeeded,
+    DeviceNeeded: def.DeviceNeeded,
+    HostDeviceNeeded: def.HostDeviceNeeded,
+  }, nil
+}
+
+func renderBackendConfig(tmpl *template.Template) datastructs.CniConfigReader {
+  return func(netInfo *danmtypes.DanmNet, ipamOptions datastructs.IpamConfig, ep *danmtypes.DanmEp, cniVersion string) ([]byte, error) {
+    data := BackendTemplateData {
+      CNIVersion: cniVersion,
+      Name:       netInfo.Spec.NetworkID,
+      Type:       netInfo.Spec.NetworkType,
+      Network:    netInfo,
+      Options:    netInfo.Spec.Options,
+      Ipam:       ipamOptions,
+      DeviceID:   ep.Spec.Iface.DeviceID,
+      HostDevice: netcontrol.DetermineHostDeviceName(netInfo),
+      IfaceName:  ep.Spec.Iface.Name,
+    }
+    var rawConfig bytes.Buffer
+    err := tmpl.Execute(&rawConfig, data)
+    if err != nil {
+      return nil, errors.New("CNI config of backend:" + tmpl.Name() + " could not be rendered because:" + err.Error())
+    }
+    if !json.Valid(rawConfig.Bytes()) {
+      return nil, errors.New("rendered CNI config of backend:" + tmpl.Name() + " is not a valid JSON:" + rawConfig.String())
+    }
+    return rawConfig.Bytes(), nil
+  }
+}
+
+func toJson(value interface{}) (string, error) {
+  rawValue, err := json.Marshal(value)
+  return string(rawValue), err
+}
+
+// LookupBackend returns the built-in, or pluggable dynamic-level backend registered for the NetworkType
+func LookupBackend(neType string) (*datastructs.CniBackendConfig, bool) {
+  neType = strings.ToLower(neType)
+  if cni, ok := SupportedNativeCnis[neType]; ok {
+    return cni, true
+  }
+  backendLock.RLock()
+  defer backendLock.RUnlock()
+  cni, ok := pluggableBackends[neType]
+  return cni, ok
+}
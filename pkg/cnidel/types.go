@@ -14,9 +14,23 @@ var(
       IpamNeeded: true,
       DeviceNeeded: true,
     },
-    "macvlan": &datastructs.CniBackendConfig {
+    "bridge": &datastructs.CniBackendConfig {
       CNIVersion: "0.3.1",
-      ReadConfig: datastructs.CniConfigReader(getMacvlanCniConfig),
+      ReadConfig: datastructs.CniConfigReader(getBridgeCniConfig),
+      IpamNeeded: true,
+      DeviceNeeded: false,
+      HostDeviceNeeded: true,
+    },
+    "ovs": &datastructs.CniBackendConfig {
+      CNIVersion: "0.3.1",
+      ReadConfig: datastructs.CniConfigReader(getOvsCniConfig),
+      IpamNeeded: true,
+      DeviceNeeded: false,
+      HostDeviceNeeded: true,
+    },
+    "host-device": &datastructs.CniBackendConfig {
+      CNIVersion: "0.3.1",
+      ReadConfig: datastructs.CniConfigReader(getHostDeviceCniConfig),
       IpamNeeded: true,
       DeviceNeeded: false,
     },
@@ -26,18 +40,67 @@ var(
 // sriovNet represent the configuration of sriov cni v1.0.0
 type SriovNet struct {
   sriov_types.NetConf
+  // VF properties, understood by the SR-IOV CNI plugin from v2.6 onwards
+  SpoofChk  string `json:"spoofchk,omitempty"`
+  Trust     string `json:"trust,omitempty"`
+  LinkState string `json:"link_state,omitempty"`
+  MinTxRate int    `json:"min_tx_rate,omitempty"`
+  MaxTxRate int    `json:"max_tx_rate,omitempty"`
+  VlanQoS   int    `json:"vlanQoS,omitempty"`
+  VlanProto string `json:"vlanProto,omitempty"`
   // IPAM configuration to be used for this network
   Ipam   datastructs.IpamConfig `json:"ipam,omitEmpty"`
 }
 
-type MacvlanNet struct {
+type BridgeNet struct {
+  types.NetConf
+  //Name of the host bridge the Pod is connected to
+  BrName      string `json:"bridge"`
+  //Whether the veth port of the Pod is put into hairpin mode
+  HairpinMode bool   `json:"hairpinMode,omitempty"`
+  //Whether the bridge is put into promiscuous mode
+  PromiscMode bool   `json:"promiscMode,omitempty"`
+  //MTU to be set to the bridge, and the veth pair (default is the MTU of the bridge)
+  MTU         int    `json:"mtu,omitempty"`
+  //IPAM configuration to be used for this network
+  Ipam   datastructs.IpamConfig `json:"ipam,omitEmpty"`
+}
+
+type OvsNet struct {
   types.NetConf
-  //Name of the master NIC the MACVLAN slave needs to be connected to
-  Master string `json:"master"`
-  //The mode in which the MACVLAN slave is configured (default bridge)
-  Mode   string `json:"mode"`
-  //MTU to be set to the MACVLAN slave interface (default 1500)
-  MTU    int    `json:"mtu"`
+  //Name of the OVS bridge the Pod is connected to
+  BrName string      `json:"bridge"`
+  //VLAN tag of the OVS port of the Pod
+  Vlan   int         `json:"vlan,omitempty"`
+  //VLANs carried by the OVS port of the Pod when it is a trunk
+  Trunk  []OvsTrunk  `json:"trunk,omitempty"`
+  //MTU to be set to the veth pair (default is the MTU of the bridge)
+  MTU    int         `json:"mtu,omitempty"`
   //IPAM configuration to be used for this network
   Ipam   datastructs.IpamConfig `json:"ipam,omitEmpty"`
 }
+
+//OvsTrunk is either a single VLAN, or a range of VLANs trunked by an OVS port
+type OvsTrunk struct {
+  ID    int `json:"id,omitempty"`
+  MinID int `json:"minID,omitempty"`
+  MaxID int `json:"maxID,omitempty"`
+}
+
+type HostDeviceNet struct {
+  types.NetConf
+  //Name of the host network interface moved into the Pod
+  Device  string `json:"device,omitempty"`
+  //PCI address of the host device moved into the Pod, when it comes from a K8s Device pool
+  PCIAddr string `json:"pciBusID,omitempty"`
+  //IPAM configuration to be used for this network
+  Ipam   datastructs.IpamConfig `json:"ipam,omitEmpty"`
+}
+
+//delegatePlugin is one link of the plugin chain a delegated CNI operation is executed with
+type delegatePlugin struct {
+  //Name of the CNI binary to be invoked
+  Type   string
+  //The raw CNI config the binary is invoked with
+  Config []byte
+}
@@ -0,0 +1,90 @@
+package cnierrors
+
+import (
+  "errors"
+  "github.com/containernetworking/cni/pkg/types"
+)
+
+// DANM specific CNI error codes. The CNI specification reserves codes below 100 for well-known errors
+const (
+  ErrNetworkNotFound uint = 100 + iota
+  ErrTenantNotAllowed
+  ErrPoolExhausted
+  ErrDelegateFailed
+  ErrMultipleFailures
+)
+
+var reasons = map[uint]string {
+  ErrNetworkNotFound:  "NetworkNotFound",
+  ErrTenantNotAllowed: "TenantNotAllowed",
+  ErrPoolExhausted:    "PoolExhausted",
+  ErrDelegateFailed:   "DelegateFailed",
+  ErrMultipleFailures: "MultipleFailures",
+}
+
+// New returns a CNI error with the provided code, which is printed to the runtime as a structured error
+func New(code uint, msg string) *types.Error {
+  return types.NewError(code, msg, "")
+}
+
+// NewWithDetails returns a CNI error with the provided code, which also tells the failed network, and the underlying cause in its details
+func NewWithDetails(code uint, msg, networkName string, cause error) *types.Error {
+  return types.NewError(code, msg, Details(networkName, cause))
+}
+
+// Details describes the failed network, and the underlying cause of the failure in the format of the details field of CNI errors
+func Details(networkName string, cause error) string {
+  details := "network:" + networkName
+  if cause != nil {
+    details += ", cause:" + cause.Error()
+  }
+  return details
+}
+
+// Wrap prefixes the message of an error the same way DANM always did, but keeps the code of typed CNI errors
+func Wrap(err error, msg string) error {
+  if cniErr, isCniError := err.(*types.Error); isCniError {
+    return types.NewError(cniErr.Code, msg + cniErr.Msg, cniErr.Details)
+  }
+  return errors.New(msg + err.Error())
+}
+
+// Append extends the message of an error with a suffix, but keeps the code of typed CNI errors
+func Append(err error, msg string) error {
+  if cniErr, isCniError := err.(*types.Error); isCniError {
+    return types.NewError(cniErr.Code, cniErr.Msg + msg, cniErr.Details)
+  }
+  return errors.New(err.Error() + msg)
+}
+
+// CodeOf returns the CNI error code of an error, untyped errors are considered internal errors
+func CodeOf(err error) uint {
+  if cniErr, isCniError := err.(*types.Error); isCniError {
+    return cniErr.Code
+  }
+  return types.ErrInternal
+}
+
+// MessageOf returns the message of an error without the details of typed CNI errors
+func MessageOf(err error) string {
+  if cniErr, isCniError := err.(*types.Error); isCniError {
+    return cniErr.Msg
+  }
+  return err.Error()
+}
+
+// DetailsOf returns the details of typed CNI errors, untyped errors have no details
+func DetailsOf(err error) string {
+  if cniErr, isCniError := err.(*types.Error); isCniError {
+    return cniErr.Details
+  }
+  return ""
+}
+
+// Reason returns the short, CamelCase name of a CNI error code, as used in the reason field of K8s Events
+func Reason(code uint) string {
+  if reason, ok := reasons[code]; ok {
+    return reason
+  }
+  return "NetworkAttachmentFailed"
+}
@@ -0,0 +1,129 @@
+package danmep
+
+import (
+  "errors"
+  "log"
+  "net"
+  "strconv"
+  "syscall"
+  "time"
+  "github.com/j-keck/arping"
+  danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
+  "github.com/nokia/danm/pkg/ipam"
+)
+
+const (
+  DefaultAnnounceCount = 1
+  MaxAnnounceCount = 10
+  MaxAnnounceInterval = 1000
+  icmpv6NeighborAdvert = 136
+  naOverrideFlag = 0x20
+  targetLinkLayerOption = 2
+)
+
+var (
+  allNodesMulticast = net.ParseIP("ff02::1")
+)
+
+// ValidateNeighAnnounce checks whether the repetition of neighbour announcements stays in the range DANM allows
+// The announcements are sent synchronously during CNI ADD, so their overall duration is limited
+func ValidateNeighAnnounce(announce *danmtypes.NeighAnnounce) error {
+  if announce == nil {
+    return nil
+  }
+  for _, count := range []int{announce.GarpCount, announce.NaCount} {
+    if count < 0 || count > MaxAnnounceCount {
+      return errors.New("announcement count:" + strconv.Itoa(count) + " must be between 0 and " + strconv.Itoa(MaxAnnounceCount))
+    }
+  }
+  for _, interval := range []int{announce.GarpInterval, announce.NaInterval} {
+    if interval < 0 || interval > MaxAnnounceInterval {
+      return errors.New("announcement interval:" + strconv.Itoa(interval) + " must be between 0 and " + strconv.Itoa(MaxAnnounceInterval) + " milliseconds")
+    }
+  }
+  return nil
+}
+
+//Neighbours of the Pod might still have stale ARP, and NDP entries for its addresses e.g. when the Pod was moved to another node
+//Failing announcements are only logged, as they do not make the interface unusable
+func announceAddresses(iface *net.Interface, ep *danmtypes.DanmEp, dnet *danmtypes.DanmNet) {
+  garpCount, garpInterval, naCount, naInterval := getAnnounceRepetition(dnet)
+  if ep.Spec.Iface.Address != "" && ep.Spec.Iface.Address != ipam.NoneAllocType {
+    addr,_,_ := net.ParseCIDR(ep.Spec.Iface.Address)
+    repeat(garpCount, garpInterval, func() error {
+      return arping.GratuitousArpOverIface(addr, *iface)
+    }, "sending gARP")
+  }
+  if ep.Spec.Iface.AddressIPv6 != "" && ep.Spec.Iface.AddressIPv6 != ipam.NoneAllocType {
+    addr,_,_ := net.ParseCIDR(ep.Spec.Iface.AddressIPv6)
+    repeat(naCount, naInterval, func() error {
+      return sendUnsolicitedNa(addr, iface)
+    }, "sending unsolicited NA")
+  }
+}
+
+func getAnnounceRepetition(dnet *danmtypes.DanmNet) (int, time.Duration, int, time.Duration) {
+  announce := dnet.Spec.Options.NeighAnnounce
+  if announce == nil {
+    return DefaultAnnounceCount, 0, DefaultAnnounceCount, 0
+  }
+  garpCount, naCount := announce.GarpCount, announce.NaCount
+  if garpCount == 0 {
+    garpCount = DefaultAnnounceCount
+  }
+  if naCount == 0 {
+    naCount = DefaultAnnounceCount
+  }
+  return garpCount, time.Duration(announce.GarpInterval) * time.Millisecond, naCount, time.Duration(announce.NaInterval) * time.Millisecond
+}
+
+func repeat(count int, interval time.Duration, send func() error, action string) {
+  for i := 0; i < count; i++ {
+    if i > 0 {
+      time.Sleep(interval)
+    }
+    err := send()
+    if err != nil {
+      log.Println("WARNING: " + action + " failed with error:" + err.Error() + ", but we will ignore that for now!")
+      return
+    }
+  }
+}
+
+//The kernel fills the ICMPv6 checksum of raw ICMPv6 sockets, so only the message itself needs to be built
+func sendUnsolicitedNa(addr net.IP, iface *net.Interface) error {
+  fd, err := syscall.Socket(syscall.AF_INET6, syscall.SOCK_RAW, syscall.IPPROTO_ICMPV6)
+  if err != nil {
+    return errors.New("cannot open ICMPv6 socket because:" + err.Error())
+  }
+  defer syscall.Close(fd)
+  //Neighbours drop NDP messages which passed a router, so the hop limit must be 255
+  err = syscall.SetsockoptInt(fd, syscall.IPPROTO_IPV6, syscall.IPV6_MULTICAST_HOPS, 255)
+  if err != nil {
+    return errors.New("cannot set hop limit of ICMPv6 socket because:" + err.Error())
+  }
+  err = syscall.SetsockoptInt(fd, syscall.IPPROTO_IPV6, syscall.IPV6_MULTICAST_IF, iface.Index)
+  if err != nil {
+    return errors.New("cannot bind ICMPv6 socket to interface:" + iface.Name + " because:" + err.Error())
+  }
+  src := &syscall.SockaddrInet6{ZoneId: uint32(iface.Index)}
+  copy(src.Addr[:], addr.To16())
+  err = syscall.Bind(fd, src)
+  if err != nil {
+    return errors.New("cannot bind ICMPv6 socket to address:" + addr.String() + " because:" + err.Error())
+  }
+  dst := &syscall.SockaddrInet6{ZoneId: uint32(iface.Index)}
+  copy(dst.Addr[:], allNodesMulticast)
+  return syscall.Sendto(fd, buildNeighborAdvert(addr, iface.HardwareAddr), 0, dst)
+}
+
+func buildNeighborAdvert(addr net.IP, mac net.HardwareAddr) []byte {
+  msg := []byte{icmpv6NeighborAdvert, 0, 0, 0, naOverrideFlag, 0, 0, 0}
+  msg = append(msg, addr.To16()...)
+  //Interfaces without a link-layer address, like tun devices, advertise without the target link-layer address option
+  if len(mac) == 6 {
+    msg = append(msg, targetLinkLayerOption, 1)
+    msg = append(msg, mac...)
+  }
+  return msg
+}
@@ -0,0 +1,89 @@
+package danmep
+
+import (
+  "errors"
+  "log"
+  "runtime"
+  "github.com/vishvananda/netlink"
+  "github.com/containernetworking/plugins/pkg/ns"
+  danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
+)
+
+const (
+  BondModeActiveBackup = "active-backup"
+  BondMode8023ad = "802.3ad"
+  DefaultBondMiimon = 100
+)
+
+// CreateBondInterface creates the bond interface of a Pod, and enslaves the already existing slave interfaces of the Pod to it
+// IPs are put on the bond, the slaves are expected to be connected without any
+func CreateBondInterface(ep *danmtypes.DanmEp) error {
+  if ep.Spec.Iface.Bond == nil {
+    return nil
+  }
+  runtime.LockOSThread()
+  defer runtime.UnlockOSThread()
+  origns, err := ns.GetCurrentNS()
+  if err != nil {
+    return errors.New("getting current namespace failed")
+  }
+  hns, err := ns.GetNS(ep.Spec.Netns)
+  if err != nil {
+    return errors.New("cannot open network namespace:" + ep.Spec.Netns)
+  }
+  defer func() {
+    hns.Close()
+    err = origns.Set()
+    if err != nil {
+      log.Println("Could not switch back to default ns during bond interface creation:" + err.Error())
+    }
+  }()
+  err = hns.Set()
+  if err != nil {
+    return errors.New("failed to enter network namespace of CID:" + ep.Spec.Netns + " with error:" + err.Error())
+  }
+  bond := netlink.NewLinkBond(netlink.LinkAttrs{Name: ep.Spec.Iface.Name})
+  bond.Mode = netlink.StringToBondMode(ep.Spec.Iface.Bond.Mode)
+  bond.Miimon = ep.Spec.Iface.Bond.Miimon
+  err = netlink.LinkAdd(bond)
+  if err != nil {
+    return errors.New("cannot create bond interface:" + ep.Spec.Iface.Name + " because:" + err.Error())
+  }
+  bondLink, err := netlink.LinkByName(ep.Spec.Iface.Name)
+  if err != nil {
+    return errors.New("cannot find created bond interface because:" + err.Error())
+  }
+  err = enslaveLinks(bondLink, ep.Spec.Iface.Bond.Slaves)
+  if err == nil {
+    err = configureLink(bondLink, ep)
+  }
+  if err != nil {
+    netlink.LinkDel(bondLink)
+    return err
+  }
+  return nil
+}
+
+//Links can only be enslaved while they are down, the bond brings them up again together with itself
+func enslaveLinks(bond netlink.Link, slaves []string) error {
+  for _, slaveName := range slaves {
+    slave, err := netlink.LinkByName(slaveName)
+    if err != nil {
+      return errors.New("cannot find bond slave:" + slaveName + " because:" + err.Error())
+    }
+    err = netlink.LinkSetDown(slave)
+    if err != nil {
+      return errors.New("cannot set bond slave:" + slaveName + " DOWN because:" + err.Error())
+    }
+    err = netlink.LinkSetMasterByIndex(slave, bond.Attrs().Index)
+    if err != nil {
+      return errors.New("cannot enslave:" + slaveName + " to bond:" + bond.Attrs().Name + " because:" + err.Error())
+    }
+  }
+  return nil
+}
+
+// DeleteBondInterface deletes the bond interface of a Pod, which also releases its slaves
+func DeleteBondInterface(ep *danmtypes.DanmEp) error {
+  return deleteEp(ep)
+}
@@ -12,12 +12,18 @@ import (
   "time"
   "github.com/containernetworking/plugins/pkg/ns"
   "github.com/containernetworking/plugins/pkg/utils/sysctl"
+  apierrors "k8s.io/apimachinery/pkg/api/errors"
   meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+  "k8s.io/apimachinery/pkg/labels"
   sriov_utils "github.com/intel/sriov-cni/pkg/utils"
   danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
   danmclientset "github.com/nokia/danm/crd/client/clientset/versioned"
+  danmlisters "github.com/nokia/danm/crd/client/listers/danm/v1"
+  "github.com/nokia/danm/pkg/cnidel"
+  "github.com/nokia/danm/pkg/cnierrors"
   "github.com/nokia/danm/pkg/datastructs"
   "github.com/nokia/danm/pkg/ipam"
+  "github.com/nokia/danm/pkg/journal"
   "github.com/nokia/danm/pkg/netcontrol"
   "github.com/satori/go.uuid"
   "github.com/vishvananda/netlink"
@@ -56,8 +62,12 @@ const (
   RetryInterval = 100
 )
 
-// DeleteIpvlanInterface deletes a Pod's IPVLAN network interface based on the related DanmEp
-func DeleteIpvlanInterface(ep *danmtypes.DanmEp) (error) {
+// DeleteNativeInterface deletes a Pod's IPVLAN, MACVLAN, VLAN, or veth network interface based on the related DanmEp, together with its host routes in IPVLAN L3, and L3S modes
+// The host end of veth pairs, and its routes are deleted by the kernel together with the Pod end
+func DeleteNativeInterface(dnet *danmtypes.DanmNet, ep *danmtypes.DanmEp) (error) {
+  if IsIpvlanRouted(dnet) {
+    deleteIpvlanHostRoutes(dnet, ep)
+  }
   return deleteEp(ep)
 }
 
@@ -89,6 +99,21 @@ func FindByCid(client danmclientset.Interface, cid string)([]danmtypes.DanmEp, e
   return ret, nil
 }
 
+// FindByCidInCache returns the DanmEps belonging to the same infra container ID from an informer backed cache
+func FindByCidInCache(lister danmlisters.DanmEpLister, cid string)([]danmtypes.DanmEp, error) {
+  eplist, err := lister.List(labels.Everything())
+  if err != nil {
+    return nil, errors.New("cannot list DanmEps from cache because:" + err.Error())
+  }
+  ret := make([]danmtypes.DanmEp, 0)
+  for _, ep := range eplist {
+    if ep.Spec.CID == cid {
+      ret = append(ret, *ep.DeepCopy())
+    }
+  }
+  return ret, nil
+}
+
 // CidsByHost returns a map of Eps
 // The Eps in the map are indexed with the name of the K8s host their Pods are running on
 func CidsByHost(client danmclientset.Interface, host string)(map[string]danmtypes.DanmEp, error) {
@@ -151,12 +176,12 @@ func FindByPodUid(client danmclientset.Interface, podUid, ns string) ([]danmtype
   return ret, nil
 }
 
-
-func AddIpvlanInterface(dnet *danmtypes.DanmNet, ep *danmtypes.DanmEp) error {
-  if ep.Spec.NetworkType != "ipvlan" {
+// AddNativeInterface creates the IPVLAN, MACVLAN, VLAN, or veth network interface of a Pod, which DANM provisions without invoking any CNI binary
+func AddNativeInterface(dnet *danmtypes.DanmNet, ep *danmtypes.DanmEp) error {
+  if !cnidel.IsDanmNativeType(ep.Spec.NetworkType) {
     return nil
   }
-  return createIpvlanInterface(dnet, ep)
+  return createNativeInterface(dnet, ep)
 }
 
 func PostProcessInterface(ep *danmtypes.DanmEp, dnet *danmtypes.DanmNet) error {
@@ -201,7 +226,41 @@ func PostProcessInterface(ep *danmtypes.DanmEp, dnet *danmtypes.DanmNet) error {
   if err != nil {
     return errors.New("failed to disable DAD for address" + ep.Spec.Iface.AddressIPv6 + " because:" + err.Error())
   }
-  return addIpRoutes(link, ep, dnet)
+  err = attachToVrf(link, dnet)
+  if err != nil {
+    return err
+  }
+  err = addIpRoutes(link, ep, dnet)
+  if err != nil {
+    return err
+  }
+  //There is no ARP, or NDP in IPVLAN L3, and L3S modes, and the dummy interfaces of DPDK bound VFs are not connected to anything
+  if IsIpvlanRouted(dnet) || isVfAttachedToDpdkDriver {
+    return nil
+  }
+  iface, err := net.InterfaceByName(ep.Spec.Iface.Name)
+  if err != nil {
+    log.Println("WARNING: addresses of interface:" + ep.Spec.Iface.Name + " were not announced because:" + err.Error())
+    return nil
+  }
+  announceAddresses(iface, ep, dnet)
+  return nil
+}
+
+// IsInterfaceInNetns checks whether the network interface represented by a DanmEp exists in the network namespace of its Pod
+// Interfaces of VFs bound to a DPDK driver are not visible to the kernel, so they are considered existing as long as their VF is still bound
+func IsInterfaceInNetns(ep *danmtypes.DanmEp) bool {
+  if ep.Spec.Iface.DeviceID != "" {
+    isVfAttachedToDpdkDriver,_ := sriov_utils.HasDpdkDriver(ep.Spec.Iface.DeviceID)
+    if isVfAttachedToDpdkDriver {
+      return true
+    }
+  }
+  err := ns.WithNetNSPath(ep.Spec.Netns, func(_ ns.NetNS) error {
+    _, err := netlink.LinkByName(ep.Spec.Iface.Name)
+    return err
+  })
+  return err == nil
 }
 
 func setDanmEpSysctls(ep *danmtypes.DanmEp) error {
@@ -256,26 +315,44 @@ func ArePodsConnectedToNetwork(client danmclientset.Interface, dnet *danmtypes.D
 
 //CreateDanmEp is a RAII-like API to automatically reserve IP allocations whenever an object holding these allocations is created
 //It helps making sure IPs are for sure universally reserved upon DanmEp creation itself
+//The IP reservation, and the DanmEp creation are recorded into the journal as separate steps, so a failing ADD can undo exactly what it did
 //TODO: I hate myself for the bool input parameter, but ipam absolutely should not depend on cnidel. Could be changed to cleverly defaulting iface attributes to sthing?
-func CreateDanmEp(danmClient danmclientset.Interface, namingScheme string, isIpReservationNeeded bool, netInfo *danmtypes.DanmNet, iface datastructs.Interface, args *datastructs.CniArgs) (*danmtypes.DanmEp,*danmtypes.DanmNet,error) {
+func CreateDanmEp(ctx context.Context, steps *journal.Journal, danmClient danmclientset.Interface, namingScheme string, isIpReservationNeeded bool, netInfo *danmtypes.DanmNet, iface datastructs.Interface, args *datastructs.CniArgs) (*danmtypes.DanmEp,*danmtypes.DanmNet,error) {
   var (
     ip4 = iface.Ip
     ip6 = iface.Ip6
     err error
   )
+  if ctx.Err() != nil {
+    return nil, netInfo, errors.New("DanmEp creation was cancelled because:" + ctx.Err().Error())
+  }
   if isIpReservationNeeded {
-    ip4, ip6, err = ipam.Reserve(danmClient, *netInfo, iface.Ip, iface.Ip6)
+    ip4, ip6, err = ipam.Reserve(ctx, danmClient, *netInfo, iface.Ip, iface.Ip6)
     if err != nil {
-      return nil, netInfo, errors.New("IP address reservation failed for network:" + netInfo.ObjectMeta.Name + " with error:" + err.Error())
+      return nil, netInfo, cnierrors.Wrap(err, "IP address reservation failed for network:" + netInfo.ObjectMeta.Name + " with error:")
     }
+    reservedNet, reservedIp4, reservedIp6 := *netInfo, ip4, ip6
+    steps.Record("IP reservation", func(ctx context.Context) error {
+      //Reserve only updated its own copy of the network, so the reserved bits are only visible in the API server
+      freshNet, err := netcontrol.RefreshNetwork(danmClient, reservedNet)
+      if err != nil {
+        return errors.New("network manifest could not be refreshed to free IP allocations due to error:" + err.Error())
+      }
+      return ipam.GarbageCollectIps(ctx, danmClient, freshNet, reservedIp4, reservedIp6)
+    })
   }
   epSpec := danmtypes.DanmEpIface {
-    Name: calculateIfaceName(namingScheme, netInfo.Spec.Options.Prefix, iface.DefaultIfaceName, iface.SequenceId),
+    Name: CalculateIfaceName(namingScheme, netInfo.Spec.Options.Prefix, iface.DefaultIfaceName, iface.SequenceId),
     Address:     ip4,
     AddressIPv6: ip6,
     Proutes:     iface.Proutes,
     Proutes6:    iface.Proutes6,
+    ExtendedProutes: iface.ExtendedProutes,
     DeviceID:    iface.Device,
+    Vf:          iface.Vf,
+  }
+  if iface.Bond != nil {
+    epSpec.Bond = &danmtypes.DanmEpBond{Mode: iface.Bond.Mode, Miimon: iface.Bond.Miimon, Slaves: iface.Bond.SlaveNames}
   }
   var hwAddress net.HardwareAddr
   if iface.Device != "" {
@@ -284,10 +361,28 @@ func CreateDanmEp(danmClient danmclientset.Interface, namingScheme string, isIpR
       epSpec.MacAddress = hwAddress.String()
     }
   }
-  ep, err := createDanmEp(danmClient, epSpec, netInfo, args)
+  if usesRoutingTable(netInfo, iface) {
+    rtableMutex.Lock()
+    defer rtableMutex.Unlock()
+  }
+  epSpec.RTable, err = allocateRoutingTable(danmClient, netInfo, iface, args)
+  if err != nil {
+    return nil, netInfo, errors.New("routing table of the interface could not be allocated due to error:" + err.Error())
+  }
+  epSpec.Vrf = netInfo.Spec.Options.Vrf
+  ep, err := createDanmEp(ctx, danmClient, epSpec, netInfo, args)
   if err != nil {
     return nil, netInfo, errors.New("DanmEp object could not be created due to error:" + err.Error())
   }
+  //The IPs are freed by the previous step, so the DanmEp must not be deleted via the RAII-like API
+  epNamespace, epName := ep.ObjectMeta.Namespace, ep.ObjectMeta.Name
+  steps.Record("DanmEp creation", func(ctx context.Context) error {
+    err := danmClient.DanmV1().DanmEps(epNamespace).Delete(ctx, epName, meta_v1.DeleteOptions{})
+    if apierrors.IsNotFound(err) {
+      return nil
+    }
+    return err
+  })
   //As netInfo is only copied to IPAM above, the IP allocation is not refreshed in the original copy.
   //Without re-reading the network body we risk leaking IPs if an error happens later on within the same thread!
   dnet, err := netcontrol.GetNetworkFromEp(danmClient, ep)
@@ -301,7 +396,7 @@ func CreateDanmEp(danmClient danmclientset.Interface, namingScheme string, isIpR
 // If a name is explicitly set in the related network API object, the NIC will be named accordingly.
 // If a name is not explicitly set, then DANM names the interface ethX where X=sequence number of the interface
 // When legacy naming scheme is configured container_prefix behaves as the exact name of an interface, rather than its name suggest
-func calculateIfaceName(namingScheme, chosenName, defaultName string, sequenceId int) string {
+func CalculateIfaceName(namingScheme, chosenName, defaultName string, sequenceId int) string {
   //Kubelet expects the first interface to be literally named "eth0", so...
   if sequenceId == 0 {
     return "eth0"
@@ -315,7 +410,7 @@ func calculateIfaceName(namingScheme, chosenName, defaultName string, sequenceId
   return defaultName + strconv.Itoa(sequenceId)
 }
 
-func createDanmEp(danmClient danmclientset.Interface, epInput danmtypes.DanmEpIface, netInfo *danmtypes.DanmNet, args *datastructs.CniArgs) (*danmtypes.DanmEp, error) {
+func createDanmEp(ctx context.Context, danmClient danmclientset.Interface, epInput danmtypes.DanmEpIface, netInfo *danmtypes.DanmNet, args *datastructs.CniArgs) (*danmtypes.DanmEp, error) {
   epidInt, err := uuid.NewV4()
   if err != nil {
     return nil, errors.New("uuid.NewV4 returned error during EP creation:" + err.Error())
@@ -352,7 +447,7 @@ func createDanmEp(danmClient danmclientset.Interface, epInput danmtypes.DanmEpIf
     ObjectMeta: meta,
     Spec: epSpec,
   }
-  newEp, err := danmClient.DanmV1().DanmEps(ep.Namespace).Create(context.TODO(), &ep, meta_v1.CreateOptions{})
+  newEp, err := danmClient.DanmV1().DanmEps(ep.Namespace).Create(ctx, &ep, meta_v1.CreateOptions{})
   if err != nil {
     return newEp, errors.New("DanmEp object could not be PUT to K8s API server due to error:" + err.Error())
   }
@@ -360,11 +455,12 @@ func createDanmEp(danmClient danmclientset.Interface, epInput danmtypes.DanmEpIf
 }
 
 // UpdateDanmEp is a more network outage resilient version of the one provided by the base K8s client
-func UpdateDanmEp(client danmclientset.Interface, ep *danmtypes.DanmEp) error {
+// Re-tries are stopped when the context is done
+func UpdateDanmEp(ctx context.Context, client danmclientset.Interface, ep *danmtypes.DanmEp) error {
   var err error
   for i := 0; i < MaxRetryCount; i++ {
-    _, err = client.DanmV1().DanmEps(ep.Namespace).Update(context.TODO(), ep, meta_v1.UpdateOptions{})
-    if err == nil {
+    _, err = client.DanmV1().DanmEps(ep.Namespace).Update(ctx, ep, meta_v1.UpdateOptions{})
+    if err == nil || ctx.Err() != nil {
       break
     }
     time.Sleep(RetryInterval * time.Millisecond)
@@ -374,19 +470,19 @@ func UpdateDanmEp(client danmclientset.Interface, ep *danmtypes.DanmEp) error {
 
 //DeleteDanmEp is a RAII-like API to automatically free IP allocations whenever the resource holding these allocations is deleted
 //It helps making sure IPs are always and only freed when a DanmEp is indeed deleted
-func DeleteDanmEp(danmClient danmclientset.Interface, ep *danmtypes.DanmEp, dnet *danmtypes.DanmNet) error {
+func DeleteDanmEp(ctx context.Context, danmClient danmclientset.Interface, ep *danmtypes.DanmEp, dnet *danmtypes.DanmNet) error {
   var err error
   if (ep.Spec.Iface.Address != "" || ep.Spec.Iface.AddressIPv6 != "") && dnet == nil {
     return errors.New("DanmEp:" + ep.ObjectMeta.Name + " cannot be safely deleted because its linked network is not available to free DANM IPAM allocated IPs")
   }
   //We only need to Free an IP if it was allocated by DANM IPAM, and it was allocated by DANM only if it falls into any of the defined subnets
-  if ipam.WasIpAllocatedByDanm(ep.Spec.Iface.Address, dnet.Spec.Options.Cidr) || ipam.WasIpAllocatedByDanm(ep.Spec.Iface.AddressIPv6, dnet.Spec.Options.Pool6.Cidr) {
-    err = ipam.GarbageCollectIps(danmClient, dnet, ep.Spec.Iface.Address, ep.Spec.Iface.AddressIPv6)
+  if dnet != nil && (ipam.WasIpAllocatedByDanm(ep.Spec.Iface.Address, dnet.Spec.Options.Cidr) || ipam.WasIpAllocatedByDanm(ep.Spec.Iface.AddressIPv6, dnet.Spec.Options.Pool6.Cidr)) {
+    err = ipam.GarbageCollectIps(ctx, danmClient, dnet, ep.Spec.Iface.Address, ep.Spec.Iface.AddressIPv6)
     if err != nil {
       return errors.New("DanmEp:" + ep.ObjectMeta.Name + " cannot be safely deleted because freeing its reserved IP addresses failed with error:" + err.Error())
     }
   }
-  return danmClient.DanmV1().DanmEps(ep.ObjectMeta.Namespace).Delete(context.TODO(), ep.ObjectMeta.Name, meta_v1.DeleteOptions{})
+  return danmClient.DanmV1().DanmEps(ep.ObjectMeta.Namespace).Delete(ctx, ep.ObjectMeta.Name, meta_v1.DeleteOptions{})
 }
 
 func getVfMac(pciId string) net.HardwareAddr {
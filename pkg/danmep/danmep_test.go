@@ -0,0 +1,234 @@
+package danmep
+
+import (
+  "bytes"
+  "net"
+  "testing"
+  "time"
+  "github.com/vishvananda/netlink"
+  danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
+)
+
+//The loopback device exists in every network namespace, so it can serve as the parent of the links without any privileges
+const testParent = "lo"
+
+var nativeLinkTcs = []struct {
+  tcName string
+  neType string
+  options danmtypes.DanmNetOption
+  expectedKind string
+  expectedMtu int
+  expectedMode string
+  expectedFlag string
+  isErrorExpected bool
+}{
+  {"macvlanDefaultMode", "macvlan", danmtypes.DanmNetOption{Device: testParent}, "macvlan", 0, "bridge", "", false},
+  {"macvlanPrivateMode", "MACVLAN", danmtypes.DanmNetOption{Device: testParent, MacvlanMode: "Private", MTU: 1400}, "macvlan", 1400, "private", "", false},
+  {"macvlanSourceMode", "macvlan", danmtypes.DanmNetOption{Device: testParent, MacvlanMode: "source"}, "macvlan", 0, "source", "", false},
+  {"vlanWithMtu", "vlan", danmtypes.DanmNetOption{Device: testParent, Vlan: 500, MTU: 1400}, "vlan", 1400, "", "", false},
+  {"vlanWithoutId", "vlan", danmtypes.DanmNetOption{Device: testParent}, "", 0, "", "", true},
+  {"ipvlanDefaults", "ipvlan", danmtypes.DanmNetOption{Device: testParent}, "ipvlan", 0, "l2", "bridge", false},
+  {"ipvlanL3sPrivate", "", danmtypes.DanmNetOption{Device: testParent, IpvlanMode: "L3S", IpvlanFlag: "private"}, "ipvlan", 0, "l3s", "private", false},
+  {"ipvlanNoParent", "ipvlan", danmtypes.DanmNetOption{Device: "nosuchdevice"}, "", 0, "", "", true},
+  {"vethWithMtu", "veth", danmtypes.DanmNetOption{MTU: 1400}, "veth", 1400, "", "", false},
+}
+
+var routeTcs = []struct {
+  tcName string
+  route danmtypes.IpRoute
+  expectedGw string
+  expectedScope netlink.Scope
+  expectedHops []int
+  isOnlink bool
+  isErrorExpected bool
+}{
+  {"singleNexthop", danmtypes.IpRoute{Dst: "10.10.0.0/16", Nexthops: []danmtypes.Nexthop{{Gw: "10.0.0.1"}}, Metric: 200, Src: "10.0.0.5", Scope: "Site", MTU: 1400}, "10.0.0.1", netlink.SCOPE_SITE, nil, false, false},
+  {"onlinkNexthop", danmtypes.IpRoute{Dst: "10.10.0.0/16", Nexthops: []danmtypes.Nexthop{{Gw: "192.168.0.1", Onlink: true}}}, "192.168.0.1", netlink.SCOPE_UNIVERSE, nil, true, false},
+  {"directRoute", danmtypes.IpRoute{Dst: "fd00::/64", Scope: "link"}, "", netlink.SCOPE_LINK, nil, false, false},
+  {"ecmpRoute", danmtypes.IpRoute{Dst: "10.10.0.0/16", Nexthops: []danmtypes.Nexthop{{Gw: "10.0.0.1"}, {Gw: "10.0.0.2", Weight: 3, Onlink: true}}}, "", netlink.SCOPE_UNIVERSE, []int{0, 2}, false, false},
+  {"invalidDestination", danmtypes.IpRoute{Dst: "10.10.0.0", Nexthops: []danmtypes.Nexthop{{Gw: "10.0.0.1"}}}, "", 0, nil, false, true},
+  {"mixedFamilies", danmtypes.IpRoute{Dst: "10.10.0.0/16", Nexthops: []danmtypes.Nexthop{{Gw: "fd00::1"}}}, "", 0, nil, false, true},
+  {"invalidScope", danmtypes.IpRoute{Dst: "10.10.0.0/16", Scope: "galaxy"}, "", 0, nil, false, true},
+}
+
+var routesOfFamilyTcs = []struct {
+  tcName string
+  allocatedIp string
+  expectedLegacyDsts []string
+  expectedDsts []string
+}{
+  {"ipv4", "10.0.0.5/24", []string{"10.20.0.0/16"}, []string{"10.10.0.0/16"}},
+  {"ipv6", "fd00::5/64", []string{"10.20.0.0/16"}, []string{"fd01::/64"}},
+  {"noneAllocated", "none", nil, nil},
+  {"noAddress", "", nil, nil},
+}
+
+var (
+  testLegacyRoutes = map[string]string{"10.20.0.0/16": "10.0.0.1"}
+  testExtendedRoutes = []danmtypes.IpRoute{{Dst: "10.10.0.0/16"}, {Dst: "fd01::/64"}, {Dst: "malformed"}}
+)
+
+var announceRepetitionTcs = []struct {
+  tcName string
+  announce *danmtypes.NeighAnnounce
+  expectedGarpCount int
+  expectedGarpInterval time.Duration
+  expectedNaCount int
+  expectedNaInterval time.Duration
+}{
+  {"defaults", nil, DefaultAnnounceCount, 0, DefaultAnnounceCount, 0},
+  {"onlyNaRepeated", &danmtypes.NeighAnnounce{NaCount: 3, NaInterval: 200}, DefaultAnnounceCount, 0, 3, 200 * time.Millisecond},
+  {"bothRepeated", &danmtypes.NeighAnnounce{GarpCount: 2, GarpInterval: 100, NaCount: 4, NaInterval: 50}, 2, 100 * time.Millisecond, 4, 50 * time.Millisecond},
+}
+
+func TestNewNativeLink(t *testing.T) {
+  parent, err := netlink.LinkByName(testParent)
+  if err != nil {
+    t.Fatalf("parent device of the test links cannot be found because:%s", err.Error())
+  }
+  for _, tc := range nativeLinkTcs {
+    t.Run(tc.tcName, func(t *testing.T) {
+      dnet := &danmtypes.DanmNet{Spec: danmtypes.DanmNetSpec{NetworkType: tc.neType, NetworkID: "native", Options: tc.options}}
+      ep := &danmtypes.DanmEp{Spec: danmtypes.DanmEpSpec{NetworkType: tc.neType, EndpointID: "0123456789abcdef"}}
+      link, err := newNativeLink(dnet, ep, "tmpname")
+      if (err != nil && !tc.isErrorExpected) || (err == nil && tc.isErrorExpected) {
+        t.Fatalf("Received error:%v does not match with expectation:%t", err, tc.isErrorExpected)
+      }
+      if tc.isErrorExpected {
+        return
+      }
+      if link.Type() != tc.expectedKind || link.Attrs().Name != "tmpname" {
+        t.Errorf("link of kind:%s with name:%s was created instead of kind:%s", link.Type(), link.Attrs().Name, tc.expectedKind)
+      }
+      expectedMtu := tc.expectedMtu
+      if expectedMtu == 0 {
+        expectedMtu = parent.Attrs().MTU
+      }
+      if link.Attrs().MTU != expectedMtu {
+        t.Errorf("link was created with MTU:%d instead of:%d", link.Attrs().MTU, expectedMtu)
+      }
+      switch typedLink := link.(type) {
+      case *netlink.Macvlan:
+        if typedLink.ParentIndex != parent.Attrs().Index || typedLink.Mode != macvlanModes[tc.expectedMode] {
+          t.Errorf("MACVLAN was created with parent:%d, mode:%d", typedLink.ParentIndex, typedLink.Mode)
+        }
+      case *netlink.Vlan:
+        if typedLink.ParentIndex != parent.Attrs().Index || typedLink.VlanId != tc.options.Vlan {
+          t.Errorf("VLAN was created with parent:%d, VLAN ID:%d", typedLink.ParentIndex, typedLink.VlanId)
+        }
+      case *netlink.IPVlan:
+        if typedLink.ParentIndex != parent.Attrs().Index || typedLink.Mode != ipvlanModes[tc.expectedMode] || typedLink.Flag != ipvlanFlags[tc.expectedFlag] {
+          t.Errorf("IPVLAN was created with parent:%d, mode:%d, flag:%d", typedLink.ParentIndex, typedLink.Mode, typedLink.Flag)
+        }
+      case *netlink.Veth:
+        if typedLink.PeerName != getVethHostName(ep) {
+          t.Errorf("veth was created with host end:%s instead of:%s", typedLink.PeerName, getVethHostName(ep))
+        }
+      }
+    })
+  }
+}
+
+func TestIpvlanModeAndFlag(t *testing.T) {
+  dnet := &danmtypes.DanmNet{Spec: danmtypes.DanmNetSpec{Options: danmtypes.DanmNetOption{IpvlanMode: "L3S", IpvlanFlag: "VEPA"}}}
+  if getIpvlanMode(dnet) != netlink.IPVLAN_MODE_L3S || getIpvlanFlag(dnet) != netlink.IPVLAN_FLAG_VEPA {
+    t.Errorf("IPVLAN mode, and flag of the network were not taken case insensitively")
+  }
+  if !IsIpvlanRouted(dnet) {
+    t.Errorf("IPVLAN network in L3S mode is not routed")
+  }
+  dnet.Spec.Options = danmtypes.DanmNetOption{}
+  if getIpvlanMode(dnet) != netlink.IPVLAN_MODE_L2 || getIpvlanFlag(dnet) != netlink.IPVLAN_FLAG_BRIDGE || IsIpvlanRouted(dnet) {
+    t.Errorf("IPVLAN interfaces are not created in L2 mode with bridge flag by default")
+  }
+}
+
+func TestNewRoute(t *testing.T) {
+  link := &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Index: 42}}
+  for _, tc := range routeTcs {
+    t.Run(tc.tcName, func(t *testing.T) {
+      nlRoute, err := newRoute(tc.route, link)
+      if (err != nil && !tc.isErrorExpected) || (err == nil && tc.isErrorExpected) {
+        t.Fatalf("Received error:%v does not match with expectation:%t", err, tc.isErrorExpected)
+      }
+      if tc.isErrorExpected {
+        return
+      }
+      if nlRoute.LinkIndex != 42 || nlRoute.Dst.String() != tc.route.Dst {
+        t.Errorf("route was created towards:%s on link:%d", nlRoute.Dst.String(), nlRoute.LinkIndex)
+      }
+      if nlRoute.Priority != tc.route.Metric || nlRoute.MTU != tc.route.MTU || !nlRoute.Src.Equal(net.ParseIP(tc.route.Src)) || nlRoute.Scope != tc.expectedScope {
+        t.Errorf("route was created with metric:%d, MTU:%d, src:%s, scope:%d", nlRoute.Priority, nlRoute.MTU, nlRoute.Src, nlRoute.Scope)
+      }
+      if !nlRoute.Gw.Equal(net.ParseIP(tc.expectedGw)) {
+        t.Errorf("route was created with gateway:%s instead of:%s", nlRoute.Gw, tc.expectedGw)
+      }
+      if (nlRoute.Flags & int(netlink.FLAG_ONLINK) != 0) != tc.isOnlink {
+        t.Errorf("onlink flag of the route does not match with expectation:%t", tc.isOnlink)
+      }
+      if len(nlRoute.MultiPath) != len(tc.expectedHops) {
+        t.Fatalf("route was created with %d next-hops instead of:%d", len(nlRoute.MultiPath), len(tc.expectedHops))
+      }
+      for i, nexthop := range nlRoute.MultiPath {
+        if nexthop.LinkIndex != 42 || nexthop.Hops != tc.expectedHops[i] || !nexthop.Gw.Equal(net.ParseIP(tc.route.Nexthops[i].Gw)) {
+          t.Errorf("next-hop:%d was created via:%s on link:%d with hops:%d", i, nexthop.Gw, nexthop.LinkIndex, nexthop.Hops)
+        }
+        if (nexthop.Flags & int(netlink.FLAG_ONLINK) != 0) != tc.route.Nexthops[i].Onlink {
+          t.Errorf("onlink flag of next-hop:%d does not match with expectation:%t", i, tc.route.Nexthops[i].Onlink)
+        }
+      }
+    })
+  }
+}
+
+func TestGetRoutesOfFamily(t *testing.T) {
+  for _, tc := range routesOfFamilyTcs {
+    t.Run(tc.tcName, func(t *testing.T) {
+      legacyRoutes, routes := getRoutesOfFamily(testLegacyRoutes, testExtendedRoutes, tc.allocatedIp)
+      checkRouteDsts(t, "legacy", legacyRoutes, tc.expectedLegacyDsts)
+      checkRouteDsts(t, "extended", routes, tc.expectedDsts)
+    })
+  }
+}
+
+func TestGetAnnounceRepetition(t *testing.T) {
+  for _, tc := range announceRepetitionTcs {
+    t.Run(tc.tcName, func(t *testing.T) {
+      dnet := &danmtypes.DanmNet{Spec: danmtypes.DanmNetSpec{Options: danmtypes.DanmNetOption{NeighAnnounce: tc.announce}}}
+      garpCount, garpInterval, naCount, naInterval := getAnnounceRepetition(dnet)
+      if garpCount != tc.expectedGarpCount || garpInterval != tc.expectedGarpInterval || naCount != tc.expectedNaCount || naInterval != tc.expectedNaInterval {
+        t.Errorf("announcements are repeated %d times every %s for gARP, %d times every %s for NA", garpCount, garpInterval, naCount, naInterval)
+      }
+    })
+  }
+}
+
+func TestBuildNeighborAdvert(t *testing.T) {
+  addr := net.ParseIP("fd00::5")
+  mac, _ := net.ParseMAC("02:42:ac:11:00:02")
+  msg := buildNeighborAdvert(addr, mac)
+  if len(msg) != 32 || msg[0] != icmpv6NeighborAdvert || msg[4] != naOverrideFlag {
+    t.Fatalf("unsolicited NA is not an overriding neighbor advertisement:%v", msg)
+  }
+  if !net.IP(msg[8:24]).Equal(addr) {
+    t.Errorf("unsolicited NA advertises:%s instead of:%s", net.IP(msg[8:24]), addr)
+  }
+  if msg[24] != targetLinkLayerOption || msg[25] != 1 || !bytes.Equal(msg[26:], mac) {
+    t.Errorf("unsolicited NA does not carry the MAC address of the interface:%v", msg[24:])
+  }
+  msg = buildNeighborAdvert(addr, nil)
+  if len(msg) != 24 {
+    t.Errorf("unsolicited NA of an interface without MAC address carries a target link-layer address option:%v", msg)
+  }
+}
+
+func checkRouteDsts(t *testing.T, kind string, routes []danmtypes.IpRoute, expectedDsts []string) {
+  if len(routes) != len(expectedDsts) {
+    t.Fatalf("%d %s routes were returned instead of:%v", len(routes), kind, expectedDsts)
+  }
+  for i, route := range routes {
+    if route.Dst != expectedDsts[i] {
+      t.Errorf("%s route towards:%s was returned instead of:%s", kind, route.Dst, expectedDsts[i])
+    }
+  }
+}
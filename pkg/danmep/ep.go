@@ -11,16 +11,15 @@ import (
   "github.com/vishvananda/netlink"
   "github.com/containernetworking/plugins/pkg/ns"
   danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
+  "github.com/nokia/danm/pkg/cnidel"
   "github.com/nokia/danm/pkg/ipam"
-  "github.com/nokia/danm/pkg/netcontrol"
-  "github.com/j-keck/arping"
 )
 
 const (
   InvalidMacAddress = "00:00:00:00:00:00"
 )
 
-func createIpvlanInterface(dnet *danmtypes.DanmNet, ep *danmtypes.DanmEp) error {
+func createNativeInterface(dnet *danmtypes.DanmNet, ep *danmtypes.DanmEp) error {
   host, err := os.Hostname()
   if err != nil {
     return errors.New("cannot get hostname because:" + err.Error())
@@ -32,11 +31,30 @@ func createIpvlanInterface(dnet *danmtypes.DanmNet, ep *danmtypes.DanmEp) error
   if ns.IsNSorErr(ep.Spec.Netns) != nil {
     return errors.New("Cannot get container pid!")
   }
-  device := netcontrol.DetermineHostDeviceName(dnet)
-  return createContainerIface(ep, dnet, device)
+  err = createContainerIface(ep, dnet)
+  if err != nil {
+    return err
+  }
+  if isVethNetwork(dnet) {
+    err = setupVethHostEnd(dnet, ep)
+    if err != nil {
+      deleteEp(ep)
+    }
+    return err
+  }
+  if !IsIpvlanRouted(dnet) {
+    return nil
+  }
+  err = addIpvlanHostRoutes(dnet, ep)
+  if err != nil {
+    deleteIpvlanHostRoutes(dnet, ep)
+    deleteEp(ep)
+    return err
+  }
+  return nil
 }
 
-func createContainerIface(ep *danmtypes.DanmEp, dnet *danmtypes.DanmNet, device string) error {
+func createContainerIface(ep *danmtypes.DanmEp, dnet *danmtypes.DanmNet) error {
   runtime.LockOSThread()
   defer runtime.UnlockOSThread()
   origns, err := ns.GetCurrentNS()
@@ -51,56 +69,43 @@ func createContainerIface(ep *danmtypes.DanmEp, dnet *danmtypes.DanmNet, device
     hns.Close()
     err = origns.Set()
     if err != nil {
-      log.Println("Could not switch back to default ns during IPVLAN interface creation:" + err.Error())
+      log.Println("Could not switch back to default ns during " + getLinkKind(ep) + " interface creation:" + err.Error())
     }
   }()
-  iface, err := netlink.LinkByName(device)
-  if err != nil {
-    return errors.New("cannot find host device because:" + err.Error())
-  }
   outer := ep.Spec.EndpointID
-  ipvlan := &netlink.IPVlan {
-    LinkAttrs: netlink.LinkAttrs {
-      Name:        outer[0:15],
-      ParentIndex: iface.Attrs().Index,
-      MTU:         iface.Attrs().MTU,
-    },
-    Mode: netlink.IPVLAN_MODE_L2,
+  link, err := newNativeLink(dnet, ep, outer[0:15])
+  if err != nil {
+    return err
   }
-  err = netlink.LinkAdd(ipvlan)
+  kind := getLinkKind(ep)
+  err = netlink.LinkAdd(link)
   if err != nil {
-    return errors.New("cannot create IPVLAN interface because:" + err.Error())
+    return errors.New("cannot create " + kind + " interface because:" + err.Error())
   }
   peer, err := netlink.LinkByName(outer[0:15])
   if err != nil {
-    return errors.New("cannot find created IPVLAN interface because:" + err.Error())
+    return errors.New("cannot find created " + kind + " interface because:" + err.Error())
+  }
+  err = setupMacvlanSourceMacs(peer, dnet)
+  if err != nil {
+    netlink.LinkDel(peer)
+    return err
   }
   err = netlink.LinkSetNsFd(peer, int(hns.Fd()))
   if err != nil {
     netlink.LinkDel(peer)
-    return errors.New("cannot move IPVLAN interface to netns because:" + err.Error())
+    return errors.New("cannot move " + kind + " interface to netns because:" + err.Error())
   }
   // now change to network namespace
   err = hns.Set()
   if err != nil {
     return errors.New("failed to enter network namespace of CID:"+ep.Spec.Netns+" with error:"+err.Error())
   }
-  iface, err = netlink.LinkByName(outer[0:15])
+  iface, err := netlink.LinkByName(outer[0:15])
   if err != nil {
-    return errors.New("cannot find IPVLAN interface in network namespace:" + err.Error())
+    return errors.New("cannot find " + kind + " interface in network namespace:" + err.Error())
   }
-  err = configureLink(iface, ep)
-  if err != nil {
-    return err
-  }
-  if ep.Spec.Iface.Address != "" && ep.Spec.Iface.Address != ipam.NoneAllocType {
-    addr,_,_ := net.ParseCIDR(ep.Spec.Iface.Address)
-    err = arping.GratuitousArpOverIfaceByName(addr, ep.Spec.Iface.Name)
-    if err != nil {
-      log.Println("WARNING: sending gARP failed with error:" + err.Error(), ", but we will ignore that for now!")
-    }
-  }
-  return nil
+  return configureLink(iface, ep)
 }
 
 func configureLink(iface netlink.Link, ep *danmtypes.DanmEp) error {
@@ -147,60 +152,72 @@ func addIpToLink(ip string, iface netlink.Link) error {
 
 func addIpRoutes(link netlink.Link, ep *danmtypes.DanmEp, dnet *danmtypes.DanmNet) error {
   defaultRoutingTable := 0
-  err := addRouteForLink(dnet.Spec.Options.Routes, ep.Spec.Iface.Address, defaultRoutingTable, link)
+  //Interfaces enslaved to a VRF only use the table of the VRF, so every route goes there, and no rules are needed
+  if dnet.Spec.Options.Vrf != "" {
+    defaultRoutingTable = dnet.Spec.Options.RTables
+  }
+  legacyRoutes, routes := getRoutesOfFamily(dnet.Spec.Options.Routes, dnet.Spec.Options.ExtendedRoutes, ep.Spec.Iface.Address)
+  legacyRoutes6, routes6 := getRoutesOfFamily(dnet.Spec.Options.Routes6, dnet.Spec.Options.ExtendedRoutes, ep.Spec.Iface.AddressIPv6)
+  legacyProutes, proutes := getRoutesOfFamily(ep.Spec.Iface.Proutes, ep.Spec.Iface.ExtendedProutes, ep.Spec.Iface.Address)
+  legacyProutes6, proutes6 := getRoutesOfFamily(ep.Spec.Iface.Proutes6, ep.Spec.Iface.ExtendedProutes, ep.Spec.Iface.AddressIPv6)
+  err := addRouteForLink(append(legacyRoutes, legacyRoutes6...), append(routes, routes6...), defaultRoutingTable, link)
   if err != nil {
     return err
   }
-  err = addRouteForLink(dnet.Spec.Options.Routes6, ep.Spec.Iface.AddressIPv6, defaultRoutingTable, link)
-  if err != nil {
-    return err
+  if dnet.Spec.Options.Vrf != "" {
+    return addRouteForLink(append(legacyProutes, legacyProutes6...), append(proutes, proutes6...), defaultRoutingTable, link)
+  }
+  //DanmEps created before routing tables were allocated automatically do not record their table
+  policyRoutingTable := ep.Spec.Iface.RTable
+  if policyRoutingTable == 0 {
+    policyRoutingTable = dnet.Spec.Options.RTables
   }
-  err = addPolicyRouteForLink(dnet.Spec.Options.RTables, ep.Spec.Iface.Address, ep.Spec.Iface.Proutes, link)
+  err = addPolicyRouteForLink(policyRoutingTable, ep.Spec.Iface.Address, legacyProutes, proutes, link)
   if err != nil {
     return err
   }
-  err = addPolicyRouteForLink(dnet.Spec.Options.RTables, ep.Spec.Iface.AddressIPv6, ep.Spec.Iface.Proutes6, link)
+  err = addPolicyRouteForLink(policyRoutingTable, ep.Spec.Iface.AddressIPv6, legacyProutes6, proutes6, link)
   if err != nil {
     return err
   }
   return nil
 }
 
-func addRouteForLink(routes map[string]string, allocatedIp string, rtable int, link netlink.Link) error {
-  if routes == nil || allocatedIp == "" || allocatedIp == ipam.NoneAllocType {
-    return nil
-  }
-  for key, value := range routes {
-    _, ipnet, err := net.ParseCIDR(key)
+//Malformed, or unreachable entries of the legacy route maps never failed the interface, so they are only logged and skipped
+//Extended routes are validated by the webhook, so any of them failing does fail the interface
+func addRouteForLink(legacyRoutes, routes []danmtypes.IpRoute, rtable int, link netlink.Link) error {
+  for _, route := range legacyRoutes {
+    err := addRoute(route, rtable, link)
     if err != nil {
-      //Bad destination in IP route, ignoring the route
-      continue
-    }
-    ip := net.ParseIP(value)
-    if ip == nil {
-      //Bad gateway in IP route, ignoring the route
-      continue
+      log.Println("WARNING: IP route with destination:" + route.Dst + " is skipped, because:" + err.Error())
     }
-    route := netlink.Route {
-      LinkIndex: link.Attrs().Index,
-      Dst:   ipnet,
-      Gw:    ip,
-    }
-    if rtable == 0 {
-      route.Scope = netlink.SCOPE_UNIVERSE
-    } else {
-      route.Table = rtable
-    }
-    err = netlink.RouteAdd(&route)
+  }
+  for _, route := range routes {
+    err := addRoute(route, rtable, link)
     if err != nil {
-      return errors.New("Adding IP route with destination:" + ipnet.String() + " and gateway:" + ip.String() + "failed with error:" + err.Error())
+      return err
     }
   }
   return nil
 }
 
-func addPolicyRouteForLink(rtable int, cidr string, proutes map[string]string, link netlink.Link) error {
-  if rtable == 0 || cidr == "" || cidr == ipam.NoneAllocType || proutes == nil {
+func addRoute(route danmtypes.IpRoute, rtable int, link netlink.Link) error {
+  nlRoute, err := newRoute(route, link)
+  if err != nil {
+    return errors.New("IP route is invalid because:" + err.Error())
+  }
+  if rtable != 0 {
+    nlRoute.Table = rtable
+  }
+  err = netlink.RouteAdd(nlRoute)
+  if err != nil {
+    return errors.New("Adding IP route with destination:" + route.Dst + " failed with error:" + err.Error())
+  }
+  return nil
+}
+
+func addPolicyRouteForLink(rtable int, cidr string, legacyProutes, proutes []danmtypes.IpRoute, link netlink.Link) error {
+  if rtable == 0 || cidr == "" || cidr == ipam.NoneAllocType || len(legacyProutes) + len(proutes) == 0 {
     return nil
   }
   srcIp, srcNet, _ := net.ParseCIDR(cidr)
@@ -212,7 +229,7 @@ func addPolicyRouteForLink(rtable int, cidr string, proutes map[string]string, l
   if err != nil {
     return errors.New("cannot add rule for policy-based IP routes because:" + err.Error())
   }
-  err = addRouteForLink(proutes, cidr, rtable, link)
+  err = addRouteForLink(legacyProutes, proutes, rtable, link)
   if err != nil {
     return err
   }
@@ -339,7 +356,7 @@ func createDummyInterface(ep *danmtypes.DanmEp, dnet *danmtypes.DanmNet) error {
 }
 
 func disableDadOnIface(link netlink.Link, ep *danmtypes.DanmEp) error {
-  if  ep.Spec.NetworkType == "ipvlan" || ep.Spec.Iface.AddressIPv6 == "" || ep.Spec.Iface.AddressIPv6 == ipam.NoneAllocType {
+  if  cnidel.IsDanmNativeType(ep.Spec.NetworkType) || ep.Spec.Iface.AddressIPv6 == "" || ep.Spec.Iface.AddressIPv6 == ipam.NoneAllocType {
     return nil
   }
   addr, pref, _ := net.ParseCIDR(ep.Spec.Iface.AddressIPv6)
@@ -0,0 +1,218 @@
+package danmep
+
+import (
+  "errors"
+  "log"
+  "net"
+  "os"
+  "path/filepath"
+  "strings"
+  "syscall"
+  "github.com/vishvananda/netlink"
+  danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
+  "github.com/nokia/danm/pkg/ipam"
+  "github.com/nokia/danm/pkg/netcontrol"
+)
+
+const (
+  IpvlanModeL2 = "l2"
+  IpvlanModeL3 = "l3"
+  IpvlanModeL3S = "l3s"
+  IpvlanFlagBridge = "bridge"
+  IpvlanFlagPrivate = "private"
+  IpvlanFlagVepa = "vepa"
+  ipvlanHostSlavePrefix = "l3_"
+  ipvlanLockDir = "/var/run/danm/ipvlan"
+)
+
+var (
+  ipvlanModes = map[string]netlink.IPVlanMode {
+    IpvlanModeL2: netlink.IPVLAN_MODE_L2,
+    IpvlanModeL3: netlink.IPVLAN_MODE_L3,
+    IpvlanModeL3S: netlink.IPVLAN_MODE_L3S,
+  }
+  ipvlanFlags = map[string]netlink.IPVlanFlag {
+    IpvlanFlagBridge: netlink.IPVLAN_FLAG_BRIDGE,
+    IpvlanFlagPrivate: netlink.IPVLAN_FLAG_PRIVATE,
+    IpvlanFlagVepa: netlink.IPVLAN_FLAG_VEPA,
+  }
+)
+
+// IsIpvlanModeSupported returns true if DANM can create IPVLAN interfaces in the mode, empty meaning the default L2 mode
+func IsIpvlanModeSupported(mode string) bool {
+  _, ok := ipvlanModes[strings.ToLower(mode)]
+  return ok || mode == ""
+}
+
+// IsIpvlanFlagSupported returns true if DANM can create IPVLAN interfaces with the flag, empty meaning the default bridge flag
+func IsIpvlanFlagSupported(flag string) bool {
+  _, ok := ipvlanFlags[strings.ToLower(flag)]
+  return ok || flag == ""
+}
+
+// IsIpvlanRouted returns true for networks whose IPVLAN interfaces work in L3, or L3S mode
+// No ARP is possible in these modes, so the host reaches the Pods through routes instead
+func IsIpvlanRouted(dnet *danmtypes.DanmNet) bool {
+  neType := strings.ToLower(dnet.Spec.NetworkType)
+  if neType != "" && neType != "ipvlan" {
+    return false
+  }
+  mode := getIpvlanMode(dnet)
+  return mode == netlink.IPVLAN_MODE_L3 || mode == netlink.IPVLAN_MODE_L3S
+}
+
+func getIpvlanMode(dnet *danmtypes.DanmNet) netlink.IPVlanMode {
+  if mode, ok := ipvlanModes[strings.ToLower(dnet.Spec.Options.IpvlanMode)]; ok {
+    return mode
+  }
+  return netlink.IPVLAN_MODE_L2
+}
+
+func getIpvlanFlag(dnet *danmtypes.DanmNet) netlink.IPVlanFlag {
+  if flag, ok := ipvlanFlags[strings.ToLower(dnet.Spec.Options.IpvlanFlag)]; ok {
+    return flag
+  }
+  return netlink.IPVLAN_FLAG_BRIDGE
+}
+
+//The IPVLAN master cannot talk to its own slaves, so host routes towards the Pod addresses point to a dedicated IPVLAN slave in the host netns
+func addIpvlanHostRoutes(dnet *danmtypes.DanmNet, ep *danmtypes.DanmEp) error {
+  unlock, err := lockIpvlanHostSlave(dnet)
+  if err != nil {
+    return err
+  }
+  defer unlock()
+  slave, err := setupIpvlanHostSlave(dnet)
+  if err != nil {
+    return err
+  }
+  for _, address := range getEpAddresses(ep) {
+    err = netlink.RouteReplace(&netlink.Route{LinkIndex: slave.Attrs().Index, Dst: address, Scope: netlink.SCOPE_LINK})
+    if err != nil {
+      return errors.New("cannot add host route towards Pod address:" + address.String() + " because:" + err.Error())
+    }
+  }
+  return nil
+}
+
+//The host IPVLAN interface is shared by every Pod of the network on the node, and CNI operations of different Pods run in parallel
+//Its creation, and the addition of routes are serialized with its deletion, so the last Pod leaving cannot delete it while a new Pod is adding its route
+func lockIpvlanHostSlave(dnet *danmtypes.DanmNet) (func(), error) {
+  err := os.MkdirAll(ipvlanLockDir, 0700)
+  if err != nil {
+    return nil, errors.New("cannot create lock directory of host IPVLAN interfaces because:" + err.Error())
+  }
+  lockFile, err := os.OpenFile(filepath.Join(ipvlanLockDir, dnet.Spec.NetworkID + ".lock"), os.O_RDWR | os.O_CREATE, 0600)
+  if err != nil {
+    return nil, errors.New("cannot open lock file of host IPVLAN interface of network:" + dnet.ObjectMeta.Name + " because:" + err.Error())
+  }
+  err = syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX)
+  if err != nil {
+    lockFile.Close()
+    return nil, errors.New("cannot lock host IPVLAN interface of network:" + dnet.ObjectMeta.Name + " because:" + err.Error())
+  }
+  unlock := func() {
+    syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN)
+    lockFile.Close()
+  }
+  return unlock, nil
+}
+
+func setupIpvlanHostSlave(dnet *danmtypes.DanmNet) (netlink.Link, error) {
+  slaveName := ipvlanHostSlavePrefix + dnet.Spec.NetworkID
+  device := netcontrol.DetermineHostDeviceName(dnet)
+  parent, err := netlink.LinkByName(device)
+  if err != nil {
+    return nil, errors.New("cannot find host device:" + device + " because:" + err.Error())
+  }
+  slave, err := netlink.LinkByName(slaveName)
+  if err == nil {
+    return checkIpvlanHostSlave(slave, parent, device)
+  }
+  err = netlink.LinkAdd(&netlink.IPVlan {
+    LinkAttrs: netlink.LinkAttrs {
+      Name:        slaveName,
+      ParentIndex: parent.Attrs().Index,
+      MTU:         parent.Attrs().MTU,
+    },
+    Mode: getIpvlanMode(dnet),
+    Flag: getIpvlanFlag(dnet),
+  })
+  //An interface created since the lookup, e.g. by a DANM version not taking the lock yet during an upgrade, is used the same way as one found by the lookup
+  if err != nil && !os.IsExist(err) {
+    return nil, errors.New("cannot create host IPVLAN interface:" + slaveName + " because:" + err.Error())
+  }
+  slave, err = netlink.LinkByName(slaveName)
+  if err != nil {
+    return nil, errors.New("cannot find created host IPVLAN interface:" + slaveName + " because:" + err.Error())
+  }
+  slave, err = checkIpvlanHostSlave(slave, parent, device)
+  if err != nil {
+    return nil, err
+  }
+  err = netlink.LinkSetUp(slave)
+  if err != nil {
+    return nil, errors.New("cannot set host IPVLAN interface:" + slaveName + " UP because:" + err.Error())
+  }
+  return slave, nil
+}
+
+func checkIpvlanHostSlave(slave, parent netlink.Link, device string) (netlink.Link, error) {
+  if slave.Attrs().ParentIndex != parent.Attrs().Index {
+    return nil, errors.New("host IPVLAN interface:" + slave.Attrs().Name + " already exists on another host device than:" + device)
+  }
+  return slave, nil
+}
+
+//The host IPVLAN interface is removed together with the route of the last Pod it served
+func deleteIpvlanHostRoutes(dnet *danmtypes.DanmNet, ep *danmtypes.DanmEp) {
+  unlock, err := lockIpvlanHostSlave(dnet)
+  if err != nil {
+    log.Println("WARNING: host routes of DanmEp:" + ep.ObjectMeta.Name + " are not deleted, because:" + err.Error())
+    return
+  }
+  defer unlock()
+  slaveName := ipvlanHostSlavePrefix + dnet.Spec.NetworkID
+  slave, err := netlink.LinkByName(slaveName)
+  if err != nil {
+    return
+  }
+  for _, address := range getEpAddresses(ep) {
+    netlink.RouteDel(&netlink.Route{LinkIndex: slave.Attrs().Index, Dst: address, Scope: netlink.SCOPE_LINK})
+  }
+  routes, err := netlink.RouteList(slave, netlink.FAMILY_ALL)
+  if err != nil {
+    return
+  }
+  for _, route := range routes {
+    if route.Dst != nil {
+      ones, bits := route.Dst.Mask.Size()
+      if ones == bits {
+        return
+      }
+    }
+  }
+  err = netlink.LinkDel(slave)
+  if err != nil {
+    log.Println("WARNING: unused host IPVLAN interface:" + slaveName + " could not be deleted because:" + err.Error())
+  }
+}
+
+func getEpAddresses(ep *danmtypes.DanmEp) []*net.IPNet {
+  addresses := make([]*net.IPNet, 0)
+  for _, address := range []string{ep.Spec.Iface.Address, ep.Spec.Iface.AddressIPv6} {
+    if address == "" || address == ipam.NoneAllocType {
+      continue
+    }
+    ip, _, err := net.ParseCIDR(address)
+    if err != nil {
+      continue
+    }
+    if ip.To4() != nil {
+      addresses = append(addresses, &net.IPNet{IP: ip.To4(), Mask: net.CIDRMask(32, 32)})
+    } else {
+      addresses = append(addresses, &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)})
+    }
+  }
+  return addresses
+}
@@ -0,0 +1,106 @@
+package danmep
+
+import (
+  "errors"
+  "net"
+  "strings"
+  "github.com/vishvananda/netlink"
+  danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
+  "github.com/nokia/danm/pkg/netcontrol"
+)
+
+const (
+  MacvlanNetworkType = "macvlan"
+  VlanNetworkType = "vlan"
+  defaultMacvlanMode = "bridge"
+)
+
+var (
+  macvlanModes = map[string]netlink.MacvlanMode {
+    "bridge": netlink.MACVLAN_MODE_BRIDGE,
+    "private": netlink.MACVLAN_MODE_PRIVATE,
+    "vepa": netlink.MACVLAN_MODE_VEPA,
+    "passthru": netlink.MACVLAN_MODE_PASSTHRU,
+    "source": netlink.MACVLAN_MODE_SOURCE,
+  }
+)
+
+//The interface is created in the host netns with a temporary name, so it does not collide with anything before being moved into the Pod
+func newNativeLink(dnet *danmtypes.DanmNet, ep *danmtypes.DanmEp, name string) (netlink.Link, error) {
+  neType := strings.ToLower(ep.Spec.NetworkType)
+  //veth pairs are not connected to any host device, the host end stays in the host netns
+  if neType == VethNetworkType {
+    attrs := netlink.LinkAttrs{Name: name, MTU: dnet.Spec.Options.MTU}
+    return &netlink.Veth{LinkAttrs: attrs, PeerName: getVethHostName(ep)}, nil
+  }
+  device := netcontrol.DetermineHostDeviceName(dnet)
+  //The Pod gets the VLAN sub-interface itself, so it sits directly on the host device
+  if neType == VlanNetworkType {
+    device = dnet.Spec.Options.Device
+  }
+  parent, err := netlink.LinkByName(device)
+  if err != nil {
+    return nil, errors.New("cannot find host device because:" + err.Error())
+  }
+  attrs := netlink.LinkAttrs {
+    Name:        name,
+    ParentIndex: parent.Attrs().Index,
+    MTU:         parent.Attrs().MTU,
+  }
+  switch neType {
+  case MacvlanNetworkType:
+    if dnet.Spec.Options.MTU != 0 {
+      attrs.MTU = dnet.Spec.Options.MTU
+    }
+    return &netlink.Macvlan{LinkAttrs: attrs, Mode: getMacvlanMode(dnet)}, nil
+  case VlanNetworkType:
+    if dnet.Spec.Options.Vlan == 0 {
+      return nil, errors.New("cannot create VLAN interface because the network does not define a VLAN ID")
+    }
+    if dnet.Spec.Options.MTU != 0 {
+      attrs.MTU = dnet.Spec.Options.MTU
+    }
+    return &netlink.Vlan{LinkAttrs: attrs, VlanId: dnet.Spec.Options.Vlan}, nil
+  }
+  return &netlink.IPVlan{LinkAttrs: attrs, Mode: getIpvlanMode(dnet), Flag: getIpvlanFlag(dnet)}, nil
+}
+
+func getMacvlanMode(dnet *danmtypes.DanmNet) netlink.MacvlanMode {
+  mode := strings.ToLower(dnet.Spec.Options.MacvlanMode)
+  if mode == "" {
+    mode = defaultMacvlanMode
+  }
+  return macvlanModes[mode]
+}
+
+//The kernel does not take the MAC list of source mode MACVLANs at creation, so it is set on the freshly created interface
+func setupMacvlanSourceMacs(link netlink.Link, dnet *danmtypes.DanmNet) error {
+  if _, isMacvlan := link.(*netlink.Macvlan); !isMacvlan || getMacvlanMode(dnet) != netlink.MACVLAN_MODE_SOURCE {
+    return nil
+  }
+  macs := make([]net.HardwareAddr, 0, len(dnet.Spec.Options.MacvlanSourceMacs))
+  for _, macStr := range dnet.Spec.Options.MacvlanSourceMacs {
+    mac, err := net.ParseMAC(macStr)
+    if err != nil {
+      return errors.New("source MAC:" + macStr + " of MACVLAN interface is invalid:" + err.Error())
+    }
+    macs = append(macs, mac)
+  }
+  err := netlink.MacvlanMACAddrSet(link, macs)
+  if err != nil {
+    return errors.New("cannot set the source MACs of MACVLAN interface because:" + err.Error())
+  }
+  return nil
+}
+
+func getLinkKind(ep *danmtypes.DanmEp) string {
+  switch strings.ToLower(ep.Spec.NetworkType) {
+  case MacvlanNetworkType:
+    return "MACVLAN"
+  case VlanNetworkType:
+    return "VLAN"
+  case VethNetworkType:
+    return "veth"
+  }
+  return "IPVLAN"
+}
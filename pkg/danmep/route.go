@@ -0,0 +1,120 @@
+package danmep
+
+import (
+  "errors"
+  "net"
+  "strconv"
+  "strings"
+  "github.com/vishvananda/netlink"
+  danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
+  "github.com/nokia/danm/pkg/ipam"
+)
+
+const (
+  MaxNexthopWeight = 256
+)
+
+var (
+  routeScopes = map[string]netlink.Scope {
+    "universe": netlink.SCOPE_UNIVERSE,
+    "site": netlink.SCOPE_SITE,
+    "link": netlink.SCOPE_LINK,
+    "host": netlink.SCOPE_HOST,
+  }
+)
+
+// ValidateIpRoute checks whether an extended IP route can be provisioned
+// Gateways which are not onlink are also checked to be in the subnet, when it is known
+func ValidateIpRoute(route danmtypes.IpRoute, subnet *net.IPNet) error {
+  _, dst, err := net.ParseCIDR(route.Dst)
+  if err != nil {
+    return errors.New("destination:" + route.Dst + " is not a valid CIDR")
+  }
+  isV6 := dst.IP.To4() == nil
+  for _, nexthop := range route.Nexthops {
+    gw := net.ParseIP(nexthop.Gw)
+    if gw == nil || (gw.To4() == nil) != isV6 {
+      return errors.New("gateway:" + nexthop.Gw + " of destination:" + route.Dst + " is not a valid address of the same IP family")
+    }
+    if subnet != nil && !nexthop.Onlink && !subnet.Contains(gw) {
+      return errors.New("gateway:" + nexthop.Gw + " of destination:" + route.Dst + " is not part of CIDR:" + subnet.String() + ", and it is not onlink")
+    }
+    if nexthop.Weight < 0 || nexthop.Weight > MaxNexthopWeight {
+      return errors.New("weight of gateway:" + nexthop.Gw + " must be between 1 and " + strconv.Itoa(MaxNexthopWeight))
+    }
+  }
+  if route.Src != "" {
+    src := net.ParseIP(route.Src)
+    if src == nil || (src.To4() == nil) != isV6 {
+      return errors.New("preferred source:" + route.Src + " of destination:" + route.Dst + " is not a valid address of the same IP family")
+    }
+  }
+  if _, ok := routeScopes[strings.ToLower(route.Scope)]; !ok && route.Scope != "" {
+    return errors.New("scope:" + route.Scope + " of destination:" + route.Dst + " is not one of universe, site, link, or host")
+  }
+  if route.Metric < 0 || route.MTU < 0 {
+    return errors.New("metric, and MTU of destination:" + route.Dst + " cannot be negative")
+  }
+  return nil
+}
+
+//The legacy destination-gateway maps are already separated per IP family, so they are only converted to routes with a single next-hop
+//Only the extended routes belonging to the IP family of the allocated address are returned besides them
+func getRoutesOfFamily(legacyRoutes map[string]string, routes []danmtypes.IpRoute, allocatedIp string) ([]danmtypes.IpRoute, []danmtypes.IpRoute) {
+  if allocatedIp == "" || allocatedIp == ipam.NoneAllocType {
+    return nil, nil
+  }
+  ip, _, err := net.ParseCIDR(allocatedIp)
+  if err != nil {
+    return nil, nil
+  }
+  isV6 := ip.To4() == nil
+  convertedRoutes := make([]danmtypes.IpRoute, 0)
+  for dst, gw := range legacyRoutes {
+    convertedRoutes = append(convertedRoutes, danmtypes.IpRoute{Dst: dst, Nexthops: []danmtypes.Nexthop{{Gw: gw}}})
+  }
+  familyRoutes := make([]danmtypes.IpRoute, 0)
+  for _, route := range routes {
+    _, dst, err := net.ParseCIDR(route.Dst)
+    if err != nil || (dst.IP.To4() == nil) != isV6 {
+      continue
+    }
+    familyRoutes = append(familyRoutes, route)
+  }
+  return convertedRoutes, familyRoutes
+}
+
+func newRoute(route danmtypes.IpRoute, link netlink.Link) (*netlink.Route, error) {
+  err := ValidateIpRoute(route, nil)
+  if err != nil {
+    return nil, err
+  }
+  _, dst, _ := net.ParseCIDR(route.Dst)
+  nlRoute := &netlink.Route {
+    LinkIndex: link.Attrs().Index,
+    Dst:       dst,
+    Priority:  route.Metric,
+    Src:       net.ParseIP(route.Src),
+    Scope:     routeScopes[strings.ToLower(route.Scope)],
+    MTU:       route.MTU,
+  }
+  if len(route.Nexthops) == 1 {
+    nlRoute.Gw = net.ParseIP(route.Nexthops[0].Gw)
+    if route.Nexthops[0].Onlink {
+      nlRoute.SetFlag(netlink.FLAG_ONLINK)
+    }
+    return nlRoute, nil
+  }
+  for _, nexthop := range route.Nexthops {
+    nlNexthop := &netlink.NexthopInfo{LinkIndex: link.Attrs().Index, Gw: net.ParseIP(nexthop.Gw)}
+    //The kernel stores the weight decremented by one
+    if nexthop.Weight > 1 {
+      nlNexthop.Hops = nexthop.Weight - 1
+    }
+    if nexthop.Onlink {
+      nlNexthop.Flags = int(netlink.FLAG_ONLINK)
+    }
+    nlRoute.MultiPath = append(nlRoute.MultiPath, nlNexthop)
+  }
+  return nlRoute, nil
+}
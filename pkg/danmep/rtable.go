@@ -0,0 +1,175 @@
+package danmep
+
+import (
+  "errors"
+  "net"
+  "runtime"
+  "strconv"
+  "sync"
+  "github.com/containernetworking/plugins/pkg/ns"
+  danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
+  danmclientset "github.com/nokia/danm/crd/client/clientset/versioned"
+  "github.com/nokia/danm/pkg/confman"
+  "github.com/nokia/danm/pkg/datastructs"
+  "github.com/nokia/danm/pkg/ipam"
+  "github.com/vishvananda/netlink"
+  "k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+)
+
+const (
+  MinRoutingTable = 1
+  //The default, main, and local tables are used by the Pod itself
+  MaxRoutingTable = 252
+)
+
+var (
+  //Interfaces of the same Pod are created in parallel, so the table chosen for one must already be visible when the next one chooses
+  rtableMutex sync.Mutex
+)
+
+// ValidateRoutingTables checks whether a routingTables range of a TenantConfig only contains tables DANM can allocate
+func ValidateRoutingTables(rtables string) error {
+  tables, err := cpuset.Parse(rtables)
+  if err != nil {
+    return errors.New("cannot be parsed because:" + err.Error())
+  }
+  invalidTables := tables.Filter(func(table int) bool {
+    return table < MinRoutingTable || table > MaxRoutingTable
+  })
+  if invalidTables.Size() > 0 {
+    return errors.New("can only contain routing tables between " + strconv.Itoa(MinRoutingTable) + " and " + strconv.Itoa(MaxRoutingTable))
+  }
+  return nil
+}
+
+//Routing tables are local to the network namespace of the Pod, so a table only has to be unique among the interfaces of the same Pod
+//Tables are chosen from the routingTables range of the TenantConfig, or counted up from the first usable table when no range is configured
+//Tables explicitly set via rt_tables are also recorded, so neither an allocated, nor an explicit table can collide with the table of another interface
+//The only exception are the interfaces of the same VRF, they share the table of their VRF
+func allocateRoutingTable(danmClient danmclientset.Interface, netInfo *danmtypes.DanmNet, iface datastructs.Interface, args *datastructs.CniArgs) (int, error) {
+  if !usesRoutingTable(netInfo, iface) {
+    return 0, nil
+  }
+  usedTables, err := getUsedTables(danmClient, args)
+  if err != nil {
+    return 0, err
+  }
+  vrf := netInfo.Spec.Options.Vrf
+  if netInfo.Spec.Options.RTables != 0 {
+    table := netInfo.Spec.Options.RTables
+    usingVrf, isUsed := usedTables[table]
+    if isUsed && (vrf == "" || usingVrf != vrf) {
+      return 0, errors.New("routing table:" + strconv.Itoa(table) + " of network:" + netInfo.ObjectMeta.Name + " is already used by another interface of the Pod")
+    }
+    return table, nil
+  }
+  tables, err := getAllocatableTables(danmClient)
+  if err != nil {
+    return 0, err
+  }
+  for _, table := range tables {
+    if _, isUsed := usedTables[table]; !isUsed {
+      return table, nil
+    }
+  }
+  return 0, errors.New("all the allocatable routing tables are already used by the Pod")
+}
+
+//Interfaces enslaved to a VRF always use the table of their VRF, other interfaces only need a table for their policy-based routes
+func usesRoutingTable(netInfo *danmtypes.DanmNet, iface datastructs.Interface) bool {
+  return netInfo.Spec.Options.Vrf != "" || hasPolicyRoutes(iface)
+}
+
+func hasPolicyRoutes(iface datastructs.Interface) bool {
+  return len(iface.Proutes) > 0 || len(iface.Proutes6) > 0 || len(iface.ExtendedProutes) > 0
+}
+
+//The used tables are mapped to the VRF using them, tables of interfaces without a VRF are mapped to an empty string
+func getUsedTables(danmClient danmclientset.Interface, args *datastructs.CniArgs) (map[int]string, error) {
+  eps, err := FindByPodUid(danmClient, string(args.Pod.ObjectMeta.UID), args.Namespace)
+  if err != nil {
+    return nil, errors.New("cannot find the routing tables already used by the Pod because:" + err.Error())
+  }
+  usedTables := make(map[int]string)
+  for _, ep := range eps {
+    if ep.Spec.Iface.RTable != 0 {
+      usedTables[ep.Spec.Iface.RTable] = ep.Spec.Iface.Vrf
+    }
+  }
+  return usedTables, nil
+}
+
+func getAllocatableTables(danmClient danmclientset.Interface) ([]int, error) {
+  rtables := strconv.Itoa(MinRoutingTable) + "-" + strconv.Itoa(MaxRoutingTable)
+  //Without a TenantConfig every usable table can be allocated
+  tconf, err := confman.GetTenantConfig(danmClient)
+  if err == nil && tconf.RoutingTables != "" {
+    rtables = tconf.RoutingTables
+  }
+  tables, err := cpuset.Parse(rtables)
+  if err != nil {
+    return nil, errors.New("routingTables of TenantConfig cannot be parsed because:" + err.Error())
+  }
+  return tables.ToSlice(), nil
+}
+
+// DeletePolicyRules removes the rules DANM added for the policy-based routes of a Pod interface
+// The routes of the table are flushed by the kernel together with the interface, but the rules would outlive it
+func DeletePolicyRules(ep *danmtypes.DanmEp) error {
+  if ep.Spec.Iface.RTable == 0 || ns.IsNSorErr(ep.Spec.Netns) != nil {
+    return nil
+  }
+  runtime.LockOSThread()
+  defer runtime.UnlockOSThread()
+  origns, err := ns.GetCurrentNS()
+  if err != nil {
+    return errors.New("getting the current netNS failed")
+  }
+  hns, err := ns.GetNS(ep.Spec.Netns)
+  if err != nil {
+    return errors.New("cannot open network namespace:" + ep.Spec.Netns)
+  }
+  defer func() {
+    hns.Close()
+    origns.Set()
+  }()
+  err = hns.Set()
+  if err != nil {
+    return errors.New("failed to enter network namespace" + ep.Spec.Netns)
+  }
+  for _, cidr := range []string{ep.Spec.Iface.Address, ep.Spec.Iface.AddressIPv6} {
+    err = deletePolicyRule(ep.Spec.Iface.RTable, cidr)
+    if err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+func deletePolicyRule(rtable int, cidr string) error {
+  if cidr == "" || cidr == ipam.NoneAllocType {
+    return nil
+  }
+  srcIp, _, err := net.ParseCIDR(cidr)
+  if err != nil {
+    return nil
+  }
+  family := netlink.FAMILY_V4
+  if srcIp.To4() == nil {
+    family = netlink.FAMILY_V6
+  }
+  rules, err := netlink.RuleList(family)
+  if err != nil {
+    return errors.New("cannot list rules because:" + err.Error())
+  }
+  for _, rule := range rules {
+    if rule.Table != rtable || rule.Src == nil || !rule.Src.IP.Equal(srcIp) {
+      continue
+    }
+    err = netlink.RuleDel(&rule)
+    if err != nil {
+      return errors.New("cannot delete rule of routing table:" + strconv.Itoa(rtable) + " because:" + err.Error())
+    }
+  }
+  return nil
+}
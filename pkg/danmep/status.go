@@ -0,0 +1,168 @@
+package danmep
+
+import (
+  "context"
+  "errors"
+  "reflect"
+  "runtime"
+  "sort"
+  "strconv"
+  "syscall"
+  "time"
+  "github.com/containernetworking/plugins/pkg/ns"
+  meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+  danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
+  danmclientset "github.com/nokia/danm/crd/client/clientset/versioned"
+  "github.com/vishvananda/netlink"
+)
+
+const (
+  NotPresentOperState = "notpresent"
+  //IFF_LOWER_UP is not exported by the syscall package
+  iffLowerUp = 0x10000
+)
+
+// GetInterfaceStatus reads the live state of the Pod interface represented by a DanmEp from the network namespace of its Pod
+// Interfaces missing from the network namespace are reported as not present, instead of failing the whole refresh
+func GetInterfaceStatus(ep *danmtypes.DanmEp) (*danmtypes.DanmEpStatus, error) {
+  runtime.LockOSThread()
+  defer runtime.UnlockOSThread()
+  origns, err := ns.GetCurrentNS()
+  if err != nil {
+    return nil, errors.New("getting the current netNS failed")
+  }
+  hns, err := ns.GetNS(ep.Spec.Netns)
+  if err != nil {
+    return nil, errors.New("cannot open network namespace:" + ep.Spec.Netns)
+  }
+  defer func() {
+    hns.Close()
+    origns.Set()
+  }()
+  err = hns.Set()
+  if err != nil {
+    return nil, errors.New("failed to enter network namespace" + ep.Spec.Netns)
+  }
+  status := &danmtypes.DanmEpStatus{LastUpdated: meta_v1.NewTime(time.Now())}
+  link, err := netlink.LinkByName(ep.Spec.Iface.Name)
+  if err != nil {
+    status.OperState = NotPresentOperState
+    return status, nil
+  }
+  attrs := link.Attrs()
+  status.OperState = attrs.OperState.String()
+  status.Carrier = attrs.RawFlags & iffLowerUp != 0
+  status.MTU = attrs.MTU
+  if attrs.Statistics != nil {
+    status.Statistics = &danmtypes.DanmEpStats {
+      RxBytes:   attrs.Statistics.RxBytes,
+      TxBytes:   attrs.Statistics.TxBytes,
+      RxPackets: attrs.Statistics.RxPackets,
+      TxPackets: attrs.Statistics.TxPackets,
+      RxErrors:  attrs.Statistics.RxErrors,
+      TxErrors:  attrs.Statistics.TxErrors,
+    }
+  }
+  addrs, err := netlink.AddrList(link, netlink.FAMILY_ALL)
+  if err != nil {
+    return nil, errors.New("cannot list addresses of interface:" + ep.Spec.Iface.Name + " because:" + err.Error())
+  }
+  for _, addr := range addrs {
+    status.Addresses = append(status.Addresses, addr.IPNet.String())
+  }
+  status.Routes, err = getLinkRoutes(link)
+  if err != nil {
+    return nil, errors.New("cannot list routes of interface:" + ep.Spec.Iface.Name + " because:" + err.Error())
+  }
+  return status, nil
+}
+
+//Routes of every table are listed, so policy-based routes, and the routes of VRFs are also reported
+//The local table is only filled by the kernel itself, so it is left out
+func getLinkRoutes(link netlink.Link) ([]string, error) {
+  routes, err := netlink.RouteListFiltered(netlink.FAMILY_ALL, &netlink.Route{Table: syscall.RT_TABLE_UNSPEC}, netlink.RT_FILTER_TABLE)
+  if err != nil {
+    return nil, err
+  }
+  linkRoutes := make([]string, 0)
+  for _, route := range routes {
+    if route.Table == syscall.RT_TABLE_LOCAL || !IsRouteOfLink(route, link.Attrs().Index) {
+      continue
+    }
+    linkRoutes = append(linkRoutes, FormatRoute(route))
+  }
+  sort.Strings(linkRoutes)
+  return linkRoutes, nil
+}
+
+// IsRouteOfLink tells whether a route goes through the link, either directly, or as one of its nexthops
+func IsRouteOfLink(route netlink.Route, linkIndex int) bool {
+  if route.LinkIndex == linkIndex {
+    return true
+  }
+  for _, nexthop := range route.MultiPath {
+    if nexthop.LinkIndex == linkIndex {
+      return true
+    }
+  }
+  return false
+}
+
+// FormatRoute renders a route similarly to iproute2, so it can be reported in the status of a DanmEp
+func FormatRoute(route netlink.Route) string {
+  routeStr := "default"
+  if route.Dst != nil {
+    routeStr = route.Dst.String()
+  }
+  if route.Gw != nil {
+    routeStr += " via " + route.Gw.String()
+  }
+  for _, nexthop := range route.MultiPath {
+    routeStr += " nexthop"
+    if nexthop.Gw != nil {
+      routeStr += " via " + nexthop.Gw.String()
+    }
+    routeStr += " weight " + strconv.Itoa(nexthop.Hops+1)
+  }
+  if route.Table != syscall.RT_TABLE_MAIN {
+    routeStr += " table " + strconv.Itoa(route.Table)
+  }
+  if route.Priority != 0 {
+    routeStr += " metric " + strconv.Itoa(route.Priority)
+  }
+  return routeStr
+}
+
+// IsStatusChanged tells whether the freshly read status of a Pod interface needs to be written into its DanmEp
+// Counters move constantly, so a change only in them is written after statsInterval passed since the last write
+func IsStatusChanged(oldStatus, newStatus *danmtypes.DanmEpStatus, statsInterval time.Duration) bool {
+  if oldStatus.OperState != newStatus.OperState || oldStatus.Carrier != newStatus.Carrier || oldStatus.MTU != newStatus.MTU ||
+     !areListsEqual(oldStatus.Addresses, newStatus.Addresses) || !areListsEqual(oldStatus.Routes, newStatus.Routes) {
+    return true
+  }
+  if reflect.DeepEqual(oldStatus.Statistics, newStatus.Statistics) {
+    return false
+  }
+  return newStatus.LastUpdated.Sub(oldStatus.LastUpdated.Time) >= statsInterval
+}
+
+//Empty lists are omitted from the stored status, so nil and empty lists must be treated the same
+func areListsEqual(oldList, newList []string) bool {
+  if len(oldList) != len(newList) {
+    return false
+  }
+  for i := range oldList {
+    if oldList[i] != newList[i] {
+      return false
+    }
+  }
+  return true
+}
+
+// UpdateDanmEpStatus writes the live state of a Pod interface into the status subresource of its DanmEp
+func UpdateDanmEpStatus(ctx context.Context, client danmclientset.Interface, ep *danmtypes.DanmEp, status *danmtypes.DanmEpStatus) error {
+  updatedEp := ep.DeepCopy()
+  updatedEp.Status = *status
+  _, err := client.DanmV1().DanmEps(ep.ObjectMeta.Namespace).UpdateStatus(ctx, updatedEp, meta_v1.UpdateOptions{})
+  return err
+}
@@ -0,0 +1,106 @@
+package danmep
+
+import (
+  "errors"
+  "fmt"
+  "net"
+  "strings"
+  "github.com/containernetworking/plugins/pkg/utils/sysctl"
+  "github.com/vishvananda/netlink"
+  danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
+  "github.com/nokia/danm/pkg/ipam"
+)
+
+const (
+  VethNetworkType = "veth"
+  vethHostPrefix = "dv"
+)
+
+func isVethNetwork(dnet *danmtypes.DanmNet) bool {
+  return strings.EqualFold(dnet.Spec.NetworkType, VethNetworkType)
+}
+
+//The host end is named after the DanmEp, so it can always be found without storing anything extra
+func getVethHostName(ep *danmtypes.DanmEp) string {
+  return vethHostPrefix + ep.Spec.EndpointID[0:13]
+}
+
+//Pods of veth networks are reached through host routes pointing to the host end of their veth pair, which answers ARP, and NDP on behalf of the rest of the network
+//The routes, and proxy NDP entries are removed by the kernel together with the veth pair
+func setupVethHostEnd(dnet *danmtypes.DanmNet, ep *danmtypes.DanmEp) error {
+  hostName := getVethHostName(ep)
+  link, err := netlink.LinkByName(hostName)
+  if err != nil {
+    return errors.New("cannot find host end:" + hostName + " of veth pair because:" + err.Error())
+  }
+  table, err := attachVethToVrf(link, dnet)
+  if err != nil {
+    return err
+  }
+  _, err = sysctl.Sysctl(fmt.Sprintf("net.ipv4.conf.%s.proxy_arp", hostName), "1")
+  if err != nil {
+    return errors.New("cannot enable proxy ARP on host end:" + hostName + " of veth pair because:" + err.Error())
+  }
+  if ep.Spec.Iface.AddressIPv6 != "" && ep.Spec.Iface.AddressIPv6 != ipam.NoneAllocType {
+    _, err = sysctl.Sysctl(fmt.Sprintf("net.ipv6.conf.%s.proxy_ndp", hostName), "1")
+    if err != nil {
+      return errors.New("cannot enable proxy NDP on host end:" + hostName + " of veth pair because:" + err.Error())
+    }
+    err = addProxyNdpEntries(link, dnet)
+    if err != nil {
+      return err
+    }
+  }
+  err = netlink.LinkSetUp(link)
+  if err != nil {
+    return errors.New("cannot set host end:" + hostName + " of veth pair UP because:" + err.Error())
+  }
+  for _, address := range getEpAddresses(ep) {
+    err = netlink.RouteReplace(&netlink.Route{LinkIndex: link.Attrs().Index, Dst: address, Scope: netlink.SCOPE_LINK, Table: table})
+    if err != nil {
+      return errors.New("cannot add host route towards Pod address:" + address.String() + " because:" + err.Error())
+    }
+  }
+  return nil
+}
+
+//Returns the host routing table of the network, which is the table of its VRF when the host end is enslaved to one
+func attachVethToVrf(link netlink.Link, dnet *danmtypes.DanmNet) (int, error) {
+  vrfName := dnet.Spec.Options.HostVrf
+  if vrfName == "" {
+    return dnet.Spec.Options.HostRTable, nil
+  }
+  master, err := netlink.LinkByName(vrfName)
+  if err != nil {
+    return 0, errors.New("cannot find host VRF:" + vrfName + " because:" + err.Error())
+  }
+  vrf, isVrf := master.(*netlink.Vrf)
+  if !isVrf {
+    return 0, errors.New("host interface:" + vrfName + " is not a VRF")
+  }
+  err = netlink.LinkSetMasterByIndex(link, vrf.Attrs().Index)
+  if err != nil {
+    return 0, errors.New("cannot enslave host end of veth pair to VRF:" + vrfName + " because:" + err.Error())
+  }
+  return int(vrf.Table), nil
+}
+
+//Unlike proxy ARP, proxy NDP only answers for explicitly listed addresses, so the IPv6 gateways of the network are listed
+func addProxyNdpEntries(link netlink.Link, dnet *danmtypes.DanmNet) error {
+  for _, gw := range dnet.Spec.Options.Routes6 {
+    gwIp := net.ParseIP(gw)
+    if gwIp == nil {
+      continue
+    }
+    err := netlink.NeighSet(&netlink.Neigh {
+      LinkIndex: link.Attrs().Index,
+      Family:    netlink.FAMILY_V6,
+      Flags:     netlink.NTF_PROXY,
+      IP:        gwIp,
+    })
+    if err != nil {
+      return errors.New("cannot add proxy NDP entry for gateway:" + gw + " on host interface:" + link.Attrs().Name + " because:" + err.Error())
+    }
+  }
+  return nil
+}
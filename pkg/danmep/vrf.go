@@ -0,0 +1,71 @@
+package danmep
+
+import (
+  "errors"
+  "fmt"
+  "os"
+  "strconv"
+  "github.com/containernetworking/plugins/pkg/utils/sysctl"
+  "github.com/vishvananda/netlink"
+  danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
+)
+
+//The VRF of a network is created by the first interface of the Pod connecting to it, and is shared by all the networks naming the same VRF
+//It lives as long as the Pod's netns, so it is never deleted explicitly
+func attachToVrf(link netlink.Link, dnet *danmtypes.DanmNet) error {
+  vrfName := dnet.Spec.Options.Vrf
+  if vrfName == "" {
+    return nil
+  }
+  vrf, err := setupVrf(vrfName, dnet.Spec.Options.RTables)
+  if err != nil {
+    return err
+  }
+  //Enslaving cycles the interface, which would flush its IPv6 addresses otherwise
+  _, err = sysctl.Sysctl(fmt.Sprintf("net.ipv6.conf.%s.keep_addr_on_down", link.Attrs().Name), "1")
+  if err != nil {
+    return errors.New("cannot keep IPv6 addresses of interface:" + link.Attrs().Name + " while enslaving it because:" + err.Error())
+  }
+  err = netlink.LinkSetMasterByIndex(link, vrf.Attrs().Index)
+  if err != nil {
+    return errors.New("cannot enslave interface:" + link.Attrs().Name + " to VRF:" + vrfName + " because:" + err.Error())
+  }
+  return nil
+}
+
+//Interfaces of the same Pod are created in parallel, so another one might create the same VRF between the lookup, and the creation
+//Such a VRF is used the same way as one existing before the lookup, if its table is also the same
+func setupVrf(vrfName string, table int) (netlink.Link, error) {
+  link, err := netlink.LinkByName(vrfName)
+  if err == nil {
+    return checkVrf(link, vrfName, table)
+  }
+  err = netlink.LinkAdd(&netlink.Vrf{LinkAttrs: netlink.LinkAttrs{Name: vrfName}, Table: uint32(table)})
+  if err != nil && !os.IsExist(err) {
+    return nil, errors.New("cannot create VRF:" + vrfName + " because:" + err.Error())
+  }
+  link, err = netlink.LinkByName(vrfName)
+  if err != nil {
+    return nil, errors.New("cannot find created VRF:" + vrfName + " because:" + err.Error())
+  }
+  vrf, err := checkVrf(link, vrfName, table)
+  if err != nil {
+    return nil, err
+  }
+  err = netlink.LinkSetUp(vrf)
+  if err != nil {
+    return nil, errors.New("cannot set VRF:" + vrfName + " UP because:" + err.Error())
+  }
+  return vrf, nil
+}
+
+func checkVrf(link netlink.Link, vrfName string, table int) (netlink.Link, error) {
+  vrf, isVrf := link.(*netlink.Vrf)
+  if !isVrf {
+    return nil, errors.New("interface:" + vrfName + " already exists in the Pod, but it is not a VRF")
+  }
+  if int(vrf.Table) != table {
+    return nil, errors.New("VRF:" + vrfName + " already exists in the Pod with table:" + strconv.Itoa(int(vrf.Table)) + " instead of:" + strconv.Itoa(table))
+  }
+  return vrf, nil
+}
@@ -28,6 +28,22 @@ type NetConf struct {
   Master              string `json:"master,omitempty"`
   Vlan                int    `json:"vlan,omitempty"`
   Vxlan               int    `json:"vxlan,omitempty"`
+  AgentSocket         string `json:"agentSocket,omitempty"`
+  StoreDir            string `json:"storeDir,omitempty"`
+  BackendDir          string `json:"backendDir,omitempty"`
+  HostLocalDataDir    string `json:"hostLocalDataDir,omitempty"`
+  AddTimeout          int    `json:"addTimeout,omitempty"`
+  DelTimeout          int    `json:"delTimeout,omitempty"`
+  CniEnv              CniEnv `json:"-"`
+}
+
+// CniEnv holds the parameters of the CNI invocation DANM passes on to the plugins it delegates to
+// They travel with the config of the operation, because the agent serves the operations of different containers in parallel
+type CniEnv struct {
+  ContainerId string
+  Netns string
+  Args string
+  Path string
 }
 
 type CniConfigReader func(netInfo *danmtypes.DanmNet, ipam IpamConfig, ep *danmtypes.DanmEp, cniVersion string) ([]byte, error)
@@ -37,6 +53,8 @@ type CniBackendConfig struct {
   ReadConfig CniConfigReader
   IpamNeeded bool
   DeviceNeeded bool
+  //Networks without a host_device are not handled by the backend, but delegated statically
+  HostDeviceNeeded bool
 }
 
 // Interface represents a request coming from the Pod to connect it to one DanmNet during CNI_ADD operation
@@ -51,11 +69,23 @@ type Interface struct {
   Ip6 string `json:"ip6,omitempty"`
   Proutes  map[string]string `json:"proutes,omitempty"`
   Proutes6 map[string]string `json:"proutes6,omitempty"`
+  ExtendedProutes []danmtypes.IpRoute `json:"extended_proutes,omitempty"`
+  Bond *Bond `json:"bond,omitempty"`
+  Vf *danmtypes.VfOptions `json:"vf,omitempty"`
   DefaultIfaceName string
   Device string
   SequenceId int
 }
 
+// Bond turns a network connection into a bond interface, created after, and on top of the other connections listed as its slaves
+// The bond gets its IPs, and routes from the network of the connection, the slaves are connected to their networks without IPs
+type Bond struct {
+  Mode   string      `json:"mode,omitempty"`
+  Miimon int         `json:"miimon,omitempty"`
+  Slaves []Interface `json:"slaves"`
+  SlaveNames []string
+}
+
 type IpamConfig struct {
   Type      string      `json:"type"`
   Ips       []IpamIp    `json:"ips,omitempty"`
@@ -75,4 +105,7 @@ type CniArgs struct {
   Interfaces []Interface
   Pod *core_v1.Pod
   DefaultNetwork *danmtypes.DanmNet
+  ExistingEps []danmtypes.DanmEp
+  RuntimeConfig map[string]interface{}
+  NetConf *NetConf
 }
\ No newline at end of file
@@ -0,0 +1,359 @@
+package epstore
+
+import (
+  "errors"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "strings"
+  "syscall"
+  "time"
+  "encoding/json"
+  "github.com/containernetworking/cni/pkg/types/current"
+  danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
+)
+
+const (
+  DefaultStoreDir = "/var/lib/cni/danm"
+  pendingReleasesDir = "pending-releases"
+  delegatesDir = "delegates"
+  devicesDir = "devices"
+  replayLockFile = ".replay.lock"
+  devicesLockFile = ".devices.lock"
+  recordSuffix = ".json"
+)
+
+// Record contains everything DANM needs to tear down a Pod interface, and to release its resources without consulting the K8s API server
+type Record struct {
+  Ep      danmtypes.DanmEp  `json:"ep"`
+  Network danmtypes.DanmNet `json:"network"`
+  Result  *current.Result   `json:"result,omitempty"`
+}
+
+// DelegateRecord is the exact CNI config a delegated plugin chain was invoked with during ADD, together with the result of the chain
+// DEL, and CHECK replay it, so they are not affected by changes of the network, or the CNI config files since the ADD
+type DelegateRecord struct {
+  IfName  string           `json:"ifName"`
+  Plugins []DelegatePlugin `json:"plugins"`
+  Result  *current.Result  `json:"result,omitempty"`
+}
+
+// DelegatePlugin is one plugin of a delegated plugin chain, together with its rendered CNI config
+type DelegatePlugin struct {
+  Type   string          `json:"type"`
+  Config json.RawMessage `json:"config"`
+}
+
+// DeviceReservation records which infra container a host device was handed out to
+type DeviceReservation struct {
+  Cid string `json:"cid"`
+}
+
+// Store is a directory on the node holding the Records of the existing Pod interfaces, one sub-directory per infra container
+// It enables CNI DEL to tear down Pod interfaces even when the K8s API server is not reachable
+// Records of interfaces which were already deleted, but whose DanmEp and IPs could not be released in the API are queued in a separate sub-directory
+type Store struct {
+  Dir string
+}
+
+// NewStore returns a Store rooted in the provided directory, or in the default one
+func NewStore(dir string) *Store {
+  if dir == "" {
+    dir = DefaultStoreDir
+  }
+  return &Store{Dir: dir}
+}
+
+// Save persists the Record of a freshly created Pod interface
+func (store *Store) Save(rec *Record) error {
+  if rec.Ep.Spec.CID == "" || rec.Ep.Spec.Iface.Name == "" {
+    return errors.New("DanmEp:" + rec.Ep.ObjectMeta.Name + " cannot be stored because its container ID, or interface name is missing")
+  }
+  return writeRecord(filepath.Join(store.Dir, rec.Ep.Spec.CID), rec.Ep.Spec.Iface.Name, "DanmEp:" + rec.Ep.ObjectMeta.Name, rec)
+}
+
+// FindByCid returns the Records of all the interfaces stored for the same infra container ID
+func (store *Store) FindByCid(cid string) ([]Record, error) {
+  if cid == "" {
+    return []Record{}, nil
+  }
+  return readRecords(filepath.Join(store.Dir, cid))
+}
+
+// Remove deletes the Record of an interface, together with the directory of its infra container when it was the last one
+func (store *Store) Remove(cid, ifName string) error {
+  cidDir := filepath.Join(store.Dir, cid)
+  err := os.Remove(filepath.Join(cidDir, ifName + recordSuffix))
+  if err != nil && !os.IsNotExist(err) {
+    return errors.New("stored record of interface:" + ifName + " could not be removed because:" + err.Error())
+  }
+  //Only succeeds when the directory is empty, which is exactly what we want
+  os.Remove(cidDir)
+  return nil
+}
+
+// QueueRelease puts the Record of an already deleted interface into the queue of DanmEps and IPs waiting to be released in the K8s API
+func (store *Store) QueueRelease(rec *Record) error {
+  return writeRecord(filepath.Join(store.Dir, pendingReleasesDir), rec.Ep.ObjectMeta.Name, "DanmEp:" + rec.Ep.ObjectMeta.Name, rec)
+}
+
+// PendingReleases returns all the queued Records whose DanmEps and IPs are still to be released in the K8s API
+func (store *Store) PendingReleases() ([]Record, error) {
+  return readRecords(filepath.Join(store.Dir, pendingReleasesDir))
+}
+
+// RemovePendingRelease removes a Record from the queue after its DanmEp and IPs were successfully released
+func (store *Store) RemovePendingRelease(epName string) error {
+  err := os.Remove(filepath.Join(store.Dir, pendingReleasesDir, epName + recordSuffix))
+  if err != nil && !os.IsNotExist(err) {
+    return errors.New("queued release of DanmEp:" + epName + " could not be removed because:" + err.Error())
+  }
+  return nil
+}
+
+// SaveDelegate persists the rendered config of a delegated plugin chain under a key unique within the infra container, e.g. the name of the interface
+func (store *Store) SaveDelegate(cid, key string, rec *DelegateRecord) error {
+  if cid == "" || key == "" {
+    return errors.New("delegate config cannot be stored because its container ID, or key is missing")
+  }
+  return writeRecord(filepath.Join(store.Dir, delegatesDir, cid), key, "delegate config:" + key, rec)
+}
+
+// FindDelegate returns the stored config of a delegated plugin chain, or nil if there is none
+func (store *Store) FindDelegate(cid, key string) (*DelegateRecord, error) {
+  if cid == "" || key == "" {
+    return nil, nil
+  }
+  rawRec, err := ioutil.ReadFile(filepath.Join(store.Dir, delegatesDir, cid, key + recordSuffix))
+  if err != nil {
+    if os.IsNotExist(err) {
+      return nil, nil
+    }
+    return nil, errors.New("stored delegate config:" + key + " could not be read because:" + err.Error())
+  }
+  var rec DelegateRecord
+  err = json.Unmarshal(rawRec, &rec)
+  if err != nil {
+    return nil, errors.New("stored delegate config:" + key + " could not be parsed because:" + err.Error())
+  }
+  return &rec, nil
+}
+
+// FindDelegatesByCid returns the stored configs of all the delegated plugin chains of the same infra container
+func (store *Store) FindDelegatesByCid(cid string) ([]DelegateRecord, error) {
+  recs := make([]DelegateRecord, 0)
+  if cid == "" {
+    return recs, nil
+  }
+  cidDir := filepath.Join(store.Dir, delegatesDir, cid)
+  files, err := ioutil.ReadDir(cidDir)
+  if err != nil {
+    if os.IsNotExist(err) {
+      return recs, nil
+    }
+    return nil, errors.New("store directory:" + cidDir + " could not be read because:" + err.Error())
+  }
+  for _, file := range files {
+    if file.IsDir() || strings.HasPrefix(file.Name(), ".") || !strings.HasSuffix(file.Name(), recordSuffix) {
+      continue
+    }
+    rec, err := store.FindDelegate(cid, strings.TrimSuffix(file.Name(), recordSuffix))
+    if err != nil {
+      return nil, err
+    }
+    recs = append(recs, *rec)
+  }
+  return recs, nil
+}
+
+// RemoveDelegate deletes the stored config of a delegated plugin chain, together with the directory of its infra container when it was the last one
+func (store *Store) RemoveDelegate(cid, key string) error {
+  if cid == "" || key == "" {
+    return nil
+  }
+  cidDir := filepath.Join(store.Dir, delegatesDir, cid)
+  err := os.Remove(filepath.Join(cidDir, key + recordSuffix))
+  if err != nil && !os.IsNotExist(err) {
+    return errors.New("stored delegate config:" + key + " could not be removed because:" + err.Error())
+  }
+  os.Remove(cidDir)
+  return nil
+}
+
+// LockDevices serializes the selection of host devices between the concurrent CNI operations of the node
+// It blocks until the lock is acquired, and the returned function releases it
+func (store *Store) LockDevices() (unlock func(), err error) {
+  err = os.MkdirAll(store.Dir, 0700)
+  if err != nil {
+    return nil, errors.New("store directory could not be created because:" + err.Error())
+  }
+  lockFile, err := os.OpenFile(filepath.Join(store.Dir, devicesLockFile), os.O_RDWR | os.O_CREATE, 0600)
+  if err != nil {
+    return nil, errors.New("device lock file could not be opened because:" + err.Error())
+  }
+  err = syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX)
+  if err != nil {
+    lockFile.Close()
+    return nil, errors.New("device lock could not be acquired because:" + err.Error())
+  }
+  unlock = func() {
+    syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN)
+    lockFile.Close()
+  }
+  return unlock, nil
+}
+
+// ReserveDevice hands out a host device to an infra container. Callers shall hold the lock of LockDevices while looking for, and reserving a free device
+func (store *Store) ReserveDevice(device, cid string) error {
+  if device == "" || cid == "" {
+    return errors.New("host device cannot be reserved because its name, or the container ID is missing")
+  }
+  return writeRecord(filepath.Join(store.Dir, devicesDir), device, "host device:" + device, &DeviceReservation{Cid: cid})
+}
+
+// FindDeviceReservation returns the ID of the infra container a host device is reserved for, or an empty string if the device is free
+func (store *Store) FindDeviceReservation(device string) (string, error) {
+  rawRec, err := ioutil.ReadFile(filepath.Join(store.Dir, devicesDir, device + recordSuffix))
+  if err != nil {
+    if os.IsNotExist(err) {
+      return "", nil
+    }
+    return "", errors.New("reservation of host device:" + device + " could not be read because:" + err.Error())
+  }
+  var rec DeviceReservation
+  err = json.Unmarshal(rawRec, &rec)
+  if err != nil {
+    return "", errors.New("reservation of host device:" + device + " could not be parsed because:" + err.Error())
+  }
+  return rec.Cid, nil
+}
+
+// ReleaseDevice frees a host device, but only if it is still reserved for the provided infra container
+func (store *Store) ReleaseDevice(device, cid string) error {
+  holder, err := store.FindDeviceReservation(device)
+  if err != nil || holder != cid {
+    return err
+  }
+  err = os.Remove(filepath.Join(store.Dir, devicesDir, device + recordSuffix))
+  if err != nil && !os.IsNotExist(err) {
+    return errors.New("reservation of host device:" + device + " could not be removed because:" + err.Error())
+  }
+  return nil
+}
+
+// ReleaseStaleDevices frees the host devices reserved for infra containers which do not exist on the node anymore, and returns their names
+// Reservations held by the provided live container IDs, or younger than the grace period are kept. The device lock is taken by the function itself
+func (store *Store) ReleaseStaleDevices(liveCids map[string]bool, gracePeriod time.Duration) ([]string, error) {
+  unlock, err := store.LockDevices()
+  if err != nil {
+    return nil, err
+  }
+  defer unlock()
+  released := make([]string, 0)
+  dir := filepath.Join(store.Dir, devicesDir)
+  files, err := ioutil.ReadDir(dir)
+  if err != nil {
+    if os.IsNotExist(err) {
+      return released, nil
+    }
+    return nil, errors.New("store directory:" + dir + " could not be read because:" + err.Error())
+  }
+  now := time.Now()
+  for _, file := range files {
+    if file.IsDir() || strings.HasPrefix(file.Name(), ".") || !strings.HasSuffix(file.Name(), recordSuffix) || now.Sub(file.ModTime()) <= gracePeriod {
+      continue
+    }
+    device := strings.TrimSuffix(file.Name(), recordSuffix)
+    holder, err := store.FindDeviceReservation(device)
+    if err != nil || holder == "" || liveCids[holder] {
+      continue
+    }
+    err = store.ReleaseDevice(device, holder)
+    if err != nil {
+      return released, err
+    }
+    released = append(released, device)
+  }
+  return released, nil
+}
+
+// LockReplay makes sure only one process replays the queued releases at a time, so the same IP is never freed twice
+// The returned function releases the lock. ok is false when another process already holds it
+func (store *Store) LockReplay() (unlock func(), ok bool, err error) {
+  err = os.MkdirAll(store.Dir, 0700)
+  if err != nil {
+    return nil, false, errors.New("store directory could not be created because:" + err.Error())
+  }
+  lockFile, err := os.OpenFile(filepath.Join(store.Dir, replayLockFile), os.O_RDWR | os.O_CREATE, 0600)
+  if err != nil {
+    return nil, false, errors.New("replay lock file could not be opened because:" + err.Error())
+  }
+  err = syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX | syscall.LOCK_NB)
+  if err != nil {
+    lockFile.Close()
+    if err == syscall.EWOULDBLOCK {
+      return nil, false, nil
+    }
+    return nil, false, errors.New("replay lock could not be acquired because:" + err.Error())
+  }
+  unlock = func() {
+    syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN)
+    lockFile.Close()
+  }
+  return unlock, true, nil
+}
+
+//Records are first written to a temporary file, and then renamed, so a crashing CNI invocation never leaves a half-written Record behind
+func writeRecord(dir, name, description string, rec interface{}) error {
+  err := os.MkdirAll(dir, 0700)
+  if err != nil {
+    return errors.New("store directory:" + dir + " could not be created because:" + err.Error())
+  }
+  rawRec, err := json.Marshal(rec)
+  if err != nil {
+    return errors.New("record of " + description + " could not be marshalled because:" + err.Error())
+  }
+  tmpFile, err := ioutil.TempFile(dir, "." + name)
+  if err != nil {
+    return errors.New("temporary file could not be created in store directory:" + dir + " because:" + err.Error())
+  }
+  _, err = tmpFile.Write(rawRec)
+  tmpFile.Close()
+  if err != nil {
+    os.Remove(tmpFile.Name())
+    return errors.New("record of " + description + " could not be written because:" + err.Error())
+  }
+  err = os.Rename(tmpFile.Name(), filepath.Join(dir, name + recordSuffix))
+  if err != nil {
+    os.Remove(tmpFile.Name())
+    return errors.New("record of " + description + " could not be saved because:" + err.Error())
+  }
+  return nil
+}
+
+func readRecords(dir string) ([]Record, error) {
+  recs := make([]Record, 0)
+  files, err := ioutil.ReadDir(dir)
+  if err != nil {
+    if os.IsNotExist(err) {
+      return recs, nil
+    }
+    return nil, errors.New("store directory:" + dir + " could not be read because:" + err.Error())
+  }
+  for _, file := range files {
+    if file.IsDir() || strings.HasPrefix(file.Name(), ".") || !strings.HasSuffix(file.Name(), recordSuffix) {
+      continue
+    }
+    rawRec, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+    if err != nil {
+      return nil, errors.New("stored record:" + file.Name() + " could not be read because:" + err.Error())
+    }
+    var rec Record
+    err = json.Unmarshal(rawRec, &rec)
+    if err != nil {
+      return nil, errors.New("stored record:" + file.Name() + " could not be parsed because:" + err.Error())
+    }
+    recs = append(recs, rec)
+  }
+  return recs, nil
+}
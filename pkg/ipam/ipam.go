@@ -1,6 +1,7 @@
 package ipam
 
 import (
+  "context"
   "errors"
   "math"
   "net"
@@ -13,6 +14,7 @@ import (
   danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
   danmclientset "github.com/nokia/danm/crd/client/clientset/versioned"
   "github.com/nokia/danm/pkg/bitarray"
+  "github.com/nokia/danm/pkg/cnierrors"
   "github.com/nokia/danm/pkg/datastructs"
   "github.com/nokia/danm/pkg/netcontrol"
 )
@@ -25,20 +27,23 @@ const (
 // Reserve inspects the network object received as an input, and allocates an IPv4 or IPv6 address from the appropriate allocation pool
 // In case static IP allocation is requested, it will try reserver the requested error. If it is not possible, it returns an error
 // The reserved IP addresses are represented by setting a bit in the network's BitArray type allocation matrices
-// The refreshed network object is modified in the K8s API server at the end
-func Reserve(danmClient danmclientset.Interface, netInfo danmtypes.DanmNet, req4, req6 string) (string, string, error) {
+// The refreshed network object is modified in the K8s API server at the end. Conflicting updates are retried until the context is done
+func Reserve(ctx context.Context, danmClient danmclientset.Interface, netInfo danmtypes.DanmNet, req4, req6 string) (string, string, error) {
   origSpec := netInfo.Spec
   tempNet := netInfo
   for {
+    if ctx.Err() != nil {
+      return "", "", errors.New("IP address reservation for network:" + netInfo.ObjectMeta.Name + " was cancelled because:" + ctx.Err().Error())
+    }
     ip4, ip6, err := allocateIps(&tempNet, req4, req6)
     if err != nil {
-      return "", "", errors.New("failed to allocate IP address for network:" + netInfo.ObjectMeta.Name + " with error:" + err.Error())
+      return "", "", cnierrors.Wrap(err, "failed to allocate IP address for network:" + netInfo.ObjectMeta.Name + " with error:")
     }
     //There is nothing to update in the API server if the network is unchanged after IP reservation
     if reflect.DeepEqual(origSpec, tempNet.Spec) {
       return ip4, ip6, nil
     }
-    retryNeeded, err, newNetSpec := updateIpAllocation(danmClient, tempNet)
+    retryNeeded, err, newNetSpec := updateIpAllocation(ctx, danmClient, tempNet)
     if err != nil {
       return "", "", err
     }
@@ -52,8 +57,8 @@ func Reserve(danmClient danmclientset.Interface, netInfo danmtypes.DanmNet, req4
 
 // Free inspects the network object received as an input, and releases an IPv4 or IPv6 address from the appropriate allocation pool
 // The IP address liberation is represented by unsetting a bit in the network's BitArray type allocation matrix
-// The refreshed network object is modified in the K8s API server at the end
-func Free(danmClient danmclientset.Interface, netInfo danmtypes.DanmNet, rip string) error {
+// The refreshed network object is modified in the K8s API server at the end. Conflicting updates are retried until the context is done
+func Free(ctx context.Context, danmClient danmclientset.Interface, netInfo danmtypes.DanmNet, rip string) error {
   if rip == NoneAllocType || rip == "" {
     return nil
   }
@@ -62,6 +67,9 @@ func Free(danmClient danmclientset.Interface, netInfo danmtypes.DanmNet, rip str
   tempNet := netInfo
   origSpec:= netInfo.Spec
   for {
+    if ctx.Err() != nil {
+      return errors.New("freeing IP address:" + rip + " of network:" + netInfo.ObjectMeta.Name + " was cancelled because:" + ctx.Err().Error())
+    }
     if ip.To4() != nil {
       tempNet.Spec.Options.Alloc = resetIp(tempNet.Spec.Options.Alloc, tempNet.Spec.Options.Cidr, ip)
     } else {
@@ -71,7 +79,7 @@ func Free(danmClient danmclientset.Interface, netInfo danmtypes.DanmNet, rip str
     if reflect.DeepEqual(origSpec, tempNet.Spec) {
       return nil
     }
-    retryNeeded, err, newNet := updateIpAllocation(danmClient, tempNet)
+    retryNeeded, err, newNet := updateIpAllocation(ctx, danmClient, tempNet)
     if err != nil {
       return err
     }
@@ -151,7 +159,7 @@ func allocateAddress(pool *danmtypes.IpPool, alloc, reqType, allocCidr, netCidr
       }
     }
     if !doesAnyFreeIpExist {
-      return alloc, "", errors.New("IP address cannot be dynamically allocated, all addresses are reserved!")
+      return alloc, "", cnierrors.New(cnierrors.ErrPoolExhausted, "IP address cannot be dynamically allocated, all addresses are reserved!")
     }
     allocatedIp = getIpFromIndex(allocatedIndex, allocSubnet, netSubnet)
     pool.LastIp = allocatedIp
@@ -229,8 +237,8 @@ func getIpFromIndex(index uint32, allocSubnet, netSubnet *net.IPNet) string {
   return ip.String() + "/" + strconv.Itoa(prefix)
 }
 
-func updateIpAllocation(danmClient danmclientset.Interface, netInfo danmtypes.DanmNet) (bool,error,danmtypes.DanmNet) {
-  resourceConflicted, err := netcontrol.PutNetwork(danmClient, &netInfo)
+func updateIpAllocation(ctx context.Context, danmClient danmclientset.Interface, netInfo danmtypes.DanmNet) (bool,error,danmtypes.DanmNet) {
+  resourceConflicted, err := netcontrol.PutNetwork(ctx, danmClient, &netInfo)
   if err != nil {
     return false, errors.New("DanmNet update failed with error:" + err.Error()), danmtypes.DanmNet{}
   }
@@ -256,12 +264,12 @@ func resetIp(alloc, cidr string, rip net.IP) string {
   return ba.Encode()
 }
 
-func GarbageCollectIps(danmClient danmclientset.Interface, netInfo *danmtypes.DanmNet, ip4, ip6 string) error {
-  err := Free(danmClient, *netInfo, ip4)
+func GarbageCollectIps(ctx context.Context, danmClient danmclientset.Interface, netInfo *danmtypes.DanmNet, ip4, ip6 string) error {
+  err := Free(ctx, danmClient, *netInfo, ip4)
   if err != nil {
     return err
   }
-  err = Free(danmClient, *netInfo, ip6)
+  err = Free(ctx, danmClient, *netInfo, ip6)
   return err
 }
 
@@ -0,0 +1,74 @@
+package journal
+
+import (
+  "context"
+  "errors"
+  "strings"
+  "sync"
+)
+
+// UndoFunc reverts the changes made by one step of an operation
+type UndoFunc func(ctx context.Context) error
+
+type step struct {
+  name string
+  undo UndoFunc
+}
+
+// Journal records the steps successfully executed during the creation of a network interface
+// When a later step fails, exactly the recorded steps are undone, in reverse order
+type Journal struct {
+  steps []step
+  mux sync.Mutex
+}
+
+func NewJournal() *Journal {
+  return &Journal{}
+}
+
+// Record adds a successfully executed step to the Journal, together with the function reverting it
+// Recording into a nil Journal is a no-op, so callers not interested in roll-back can simply pass nil
+func (journal *Journal) Record(name string, undo UndoFunc) {
+  if journal == nil {
+    return
+  }
+  journal.mux.Lock()
+  defer journal.mux.Unlock()
+  journal.steps = append(journal.steps, step{name: name, undo: undo})
+}
+
+// Rollback undoes all the recorded steps in reverse order, and clears the Journal
+// All steps are attempted even if some of them fail, the failures are returned as one aggregated error
+func (journal *Journal) Rollback(ctx context.Context) error {
+  if journal == nil {
+    return nil
+  }
+  journal.mux.Lock()
+  defer journal.mux.Unlock()
+  var rollbackErrors []string
+  for i := len(journal.steps)-1; i >= 0; i-- {
+    err := journal.steps[i].undo(ctx)
+    if err != nil {
+      rollbackErrors = append(rollbackErrors, "rollback of step:" + journal.steps[i].name + " failed with:" + err.Error())
+    }
+  }
+  journal.steps = nil
+  if len(rollbackErrors) > 0 {
+    return errors.New(strings.Join(rollbackErrors, "; "))
+  }
+  return nil
+}
+
+// Steps returns the names of the recorded steps in the order of their execution
+func (journal *Journal) Steps() []string {
+  if journal == nil {
+    return nil
+  }
+  journal.mux.Lock()
+  defer journal.mux.Unlock()
+  names := make([]string, 0, len(journal.steps))
+  for _, step := range journal.steps {
+    names = append(names, step.name)
+  }
+  return names
+}
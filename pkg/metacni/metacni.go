@@ -9,8 +9,11 @@ import (
   "net"
   "os"
   "runtime"
+  "sort"
   "strconv"
   "strings"
+  "sync"
+  "time"
   "encoding/json"
   "github.com/containernetworking/cni/pkg/skel"
   "github.com/containernetworking/cni/pkg/types"
@@ -19,16 +22,23 @@ import (
   "github.com/containernetworking/plugins/pkg/utils/sysctl"
   podresclient "gopkg.in/k8snetworkplumbingwg/multus-cni.v3/pkg/kubeletclient"
   multus_types "gopkg.in/k8snetworkplumbingwg/multus-cni.v3/pkg/types"
+  corev1 "k8s.io/api/core/v1"
+  apierrors "k8s.io/apimachinery/pkg/api/errors"
   meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+  "k8s.io/apimachinery/pkg/labels"
   "k8s.io/client-go/rest"
   "k8s.io/client-go/tools/clientcmd"
   "k8s.io/client-go/kubernetes"
   danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
   danmclientset "github.com/nokia/danm/crd/client/clientset/versioned"
+  danmlisters "github.com/nokia/danm/crd/client/listers/danm/v1"
   "github.com/nokia/danm/pkg/cnidel"
+  "github.com/nokia/danm/pkg/cnierrors"
   "github.com/nokia/danm/pkg/danmep"
   "github.com/nokia/danm/pkg/datastructs"
+  "github.com/nokia/danm/pkg/epstore"
   "github.com/nokia/danm/pkg/ipam"
+  "github.com/nokia/danm/pkg/journal"
   "github.com/nokia/danm/pkg/netcontrol"
   "github.com/nokia/danm/pkg/syncher"
 )
@@ -41,11 +51,15 @@ const (
   defaultNetworkName = "default"
   defaultIfName = "eth"
   DefaultCniDir = "/etc/cni/net.d"
+  rollbackShareOfAdd = 4
 )
 
 var (
   apiHost = os.Getenv("API_SERVERS")
-  DanmConfig *datastructs.NetConf
+  agentCaches *AgentCaches
+  //The periodic maintenance of the agent uses the directories of the config the last CNI operation was invoked with
+  lastNetConf *datastructs.NetConf
+  lastNetConfLock sync.RWMutex
 )
 
 // K8sArgs is the valid CNI_ARGS type used to parse K8s CNI event calls (thanks Multus)
@@ -57,49 +71,77 @@ type K8sArgs struct {
   K8S_POD_INFRA_CONTAINER_ID types.UnmarshallableString
 }
 
+// AgentCaches holds the long-living API clients, and informer backed caches of the node-local DANM agent
+// When set, CNI operations re-use them instead of re-building the clients, and re-listing all DanmEps for every invocation
+type AgentCaches struct {
+  DanmClient danmclientset.Interface
+  K8sClient  kubernetes.Interface
+  EpLister   danmlisters.DanmEpLister
+}
+
+// SetAgentCaches makes all subsequent CNI operations of the process use the provided clients and caches
+func SetAgentCaches(caches *AgentCaches) {
+  agentCaches = caches
+}
+
 func CreateInterfaces(args *skel.CmdArgs) error {
+  cniResult, err := SetupInterfaces(context.Background(), args)
+  if err != nil {
+    return err
+  }
+  return types.PrintResult(cniResult, cniVersion)
+}
+
+// SetupInterfaces creates all the network interfaces of a Pod, and returns their aggregated CNI result
+// The result is not printed, so the node-local DANM agent can also use it to serve forwarded CNI ADD requests
+// The operation, including its rollback, never outlives ctx, nor the ADD timeout of the config
+func SetupInterfaces(ctx context.Context, args *skel.CmdArgs) (*current.Result, error) {
   cniArgs,err := extractCniArgs(args)
   if err != nil {
     log.Println("ERROR: ADD: CNI args cannot be loaded with error:" + err.Error())
-    return fmt.Errorf("CNI args cannot be loaded with error: %v", err)
+    return nil, fmt.Errorf("CNI args cannot be loaded with error: %v", err)
   }
   log.Println("CNI ADD invoked with: ns:" + cniArgs.Namespace + " for Pod:" + cniArgs.PodName + " CID: " + cniArgs.ContainerId)
-  err = loadNetConf(cniArgs.StdIn)
+  err = loadNetConf(cniArgs, args)
   if err != nil {
-    return errors.New("ERROR: ADD: cannot load DANM CNI config due to error:" + err.Error())
+    return nil, errors.New("ERROR: ADD: cannot load DANM CNI config due to error:" + err.Error())
   }
   err = getPod(cniArgs)
   if err != nil {
     log.Println("ERROR: ADD: Pod manifest could not be parsed with error:" + err.Error())
-    return fmt.Errorf("Pod manifest could not be parsed with error: %v", err)
+    return nil, fmt.Errorf("Pod manifest could not be parsed with error: %v", err)
   }
   err = extractConnections(cniArgs)
   if err != nil {
     log.Println("ERROR: ADD: DANM annotation cannot be parsed:" + err.Error())
-    return fmt.Errorf("DANM annotation cannot be parsed: %v", err)
+    return nil, fmt.Errorf("DANM annotation cannot be parsed: %v", err)
   }
   if len(cniArgs.Interfaces) == 0 {
-    danmClient, err := CreateDanmClient(DanmConfig.Kubeconfig)
+    danmClient, err := getDanmClient(cniArgs.NetConf)
     if err != nil {
       log.Println("ERROR: cannot instantiate K8s client, because:" + err.Error())
-      return fmt.Errorf("ERROR: cannot instantiate K8s client: %v", err)
+      return nil, fmt.Errorf("ERROR: cannot instantiate K8s client: %v", err)
     }
     defaultNet, err := netcontrol.GetDefaultNetwork(danmClient, defaultNetworkName, cniArgs.Pod.ObjectMeta.Namespace)
     if err != nil {
       log.Println("ERROR: there are no network connections defined for Pod:" + cniArgs.Pod.ObjectMeta.Name + ", and there is no suitable default network configured in the cluster!")
-      return errors.New("there are no network connections defined, and there is no suitable default network configured in the cluster")
+      return nil, cnierrors.New(cnierrors.ErrNetworkNotFound, "there are no network connections defined, and there is no suitable default network configured in the cluster")
     }
     cniArgs.DefaultNetwork = defaultNet
   }
-  cniResult, err := setupNetworking(cniArgs)
+  addCtx, cancel := context.WithTimeout(ctx, getTimeout(cniArgs.NetConf.AddTimeout))
+  defer cancel()
+  //Interfaces are only created until the rollback reserve starts, so the deadline of the rollback is always the deadline of the whole ADD
+  addDeadline, _ := addCtx.Deadline()
+  ctx, cancelCreation := context.WithDeadline(addCtx, addDeadline.Add(-getRollbackReserve(cniArgs.NetConf)))
+  defer cancelCreation()
+  cniResult, err := setupNetworking(ctx, cniArgs)
   if err != nil {
-    //Best effort cleanup - not interested in possible errors, anyway could not do anything with them
-    os.Setenv("CNI_COMMAND","DEL")
-    DeleteInterfaces(args)
     log.Println("ERROR: ADD: CNI network could not be set up with error:" + err.Error())
-    return fmt.Errorf("CNI network could not be set up: %v", err)
+    return nil, cnierrors.Wrap(err, "CNI network could not be set up: ")
   }
-  return types.PrintResult(cniResult, cniVersion)
+  cniResult.CNIVersion = cniVersion
+  return cniResult, nil
 }
 
 func CreateDanmClient(kubeConfig string) (danmclientset.Interface,error) {
@@ -114,6 +156,13 @@ func CreateDanmClient(kubeConfig string) (danmclientset.Interface,error) {
   return client, nil
 }
 
+func getDanmClient(netConf *datastructs.NetConf) (danmclientset.Interface,error) {
+  if agentCaches != nil && agentCaches.DanmClient != nil {
+    return agentCaches.DanmClient, nil
+  }
+  return CreateDanmClient(netConf.Kubeconfig)
+}
+
 func getClientConfig(kubeConfig string) (*rest.Config, error){
   config, err := clientcmd.BuildConfigFromFlags("", kubeConfig)
   if err != nil {
@@ -122,17 +171,43 @@ func getClientConfig(kubeConfig string) (*rest.Config, error){
   return config, nil
 }
 
-func loadNetConf(bytes []byte) error {
+//Every operation gets its own config, together with the parameters of its invocation, as the agent serves different containers in parallel
+func loadNetConf(cniArgs *datastructs.CniArgs, args *skel.CmdArgs) error {
   netconf := &datastructs.NetConf{}
-  err := json.Unmarshal(bytes, netconf)
+  err := json.Unmarshal(cniArgs.StdIn, netconf)
   if err != nil {
     return errors.New("Failed to parse DANM's CNI config file:" + err.Error())
   }
-  DanmConfig = netconf
-  if DanmConfig.CniConfigDir == "" {
-    DanmConfig.CniConfigDir = DefaultCniDir
+  if netconf.CniConfigDir == "" {
+    netconf.CniConfigDir = DefaultCniDir
   }
-  return nil
+  if netconf.StoreDir == "" {
+    netconf.StoreDir = epstore.DefaultStoreDir
+  }
+  if netconf.BackendDir == "" {
+    netconf.BackendDir = cnidel.DefaultBackendDir
+  }
+  if netconf.AddTimeout < 0 || netconf.DelTimeout < 0 {
+    return errors.New("CNI operation timeouts cannot be negative")
+  }
+  netconf.CniEnv = datastructs.CniEnv {
+    ContainerId: args.ContainerID,
+    Netns:       args.Netns,
+    Args:        args.Args,
+    Path:        args.Path,
+  }
+  cniArgs.NetConf = netconf
+  lastNetConfLock.Lock()
+  lastNetConf = netconf
+  lastNetConfLock.Unlock()
+  //Backend definitions are re-read for every operation, so new backends can be added without restarting the agent
+  return cnidel.LoadBackends(netconf.BackendDir)
+}
+
+func getLastNetConf() *datastructs.NetConf {
+  lastNetConfLock.RLock()
+  defer lastNetConfLock.RUnlock()
+  return lastNetConf
 }
 
 func extractCniArgs(args *skel.CmdArgs) (*datastructs.CniArgs,error) {
@@ -151,11 +226,22 @@ func extractCniArgs(args *skel.CmdArgs) (*datastructs.CniArgs,error) {
   	Pod:            nil,
   	DefaultNetwork: nil,
   }
+  //The runtime only passes the runtimeConfig of the capabilities declared in the CNI config of DANM
+  if len(args.StdinData) > 0 {
+    var runtimeArgs struct {
+      RuntimeConfig map[string]interface{} `json:"runtimeConfig,omitempty"`
+    }
+    err = json.Unmarshal(args.StdinData, &runtimeArgs)
+    if err != nil {
+      return nil, errors.New("runtimeConfig of the CNI config could not be parsed because:" + err.Error())
+    }
+    cmdArgs.RuntimeConfig = runtimeArgs.RuntimeConfig
+  }
   return &cmdArgs, nil
 }
 
 func getPod(args *datastructs.CniArgs) error {
-  k8sClient, err := createK8sClient(DanmConfig.Kubeconfig)
+  k8sClient, err := getK8sClient(args.NetConf)
   if err != nil {
     return errors.New("cannot create K8s REST client due to error:" + err.Error())
   }
@@ -167,6 +253,13 @@ func getPod(args *datastructs.CniArgs) error {
   return nil
 }
 
+func getK8sClient(netConf *datastructs.NetConf) (kubernetes.Interface, error) {
+  if agentCaches != nil && agentCaches.K8sClient != nil {
+    return agentCaches.K8sClient, nil
+  }
+  return createK8sClient(netConf.Kubeconfig)
+}
+
 func createK8sClient(kubeconfig string) (kubernetes.Interface, error) {
   config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
   if err != nil {
@@ -176,76 +269,316 @@ func createK8sClient(kubeconfig string) (kubernetes.Interface, error) {
 }
 
 func extractConnections(args *datastructs.CniArgs) error {
+  ifaces, err := ParseInterfaces(args.Pod.Annotations)
+  if err != nil {
+    return errors.New("Can't create network interfaces for Pod: " + args.Pod.ObjectMeta.Name + " because:" + err.Error())
+  }
+  args.Interfaces = expandBondSlaves(ifaces)
+  return nil
+}
+
+// ParseInterfaces decodes, and validates the network connections requested in the DANM annotation of a Pod
+// It is used both by the CNI, and by the webhook admitting Pods
+func ParseInterfaces(annotations map[string]string) ([]datastructs.Interface, error) {
   var ifaces []datastructs.Interface
-  for key, val := range args.Pod.Annotations {
+  for key, val := range annotations {
     if strings.Contains(key, danmIfDefinitionSyntax) {
       decoder := json.NewDecoder(bytes.NewReader([]byte(val)))
       //We are using Decoder interface, because it can notify us if any unknown fields were put into the object
       decoder.DisallowUnknownFields()
       err := decoder.Decode(&ifaces)
       if err != nil {
-        return errors.New("Can't create network interfaces for Pod: " + args.Pod.ObjectMeta.Name + " due to badly formatted " + danmIfDefinitionSyntax + " definition in Pod annotation:" + err.Error())
+        return nil, errors.New("badly formatted " + danmIfDefinitionSyntax + " definition in Pod annotation:" + err.Error())
       }
       break
     }
   }
   if err := validateAnnotation(ifaces); err!=nil {
-    return errors.New("DANM annotation is invalid for Pod: " + args.Pod.ObjectMeta.Name + ", because:" + err.Error())
+    return nil, errors.New("DANM annotation is invalid, because:" + err.Error())
   }
-  args.Interfaces = ifaces
-  return nil
+  return ifaces, nil
 }
 
 func validateAnnotation(ifaces []datastructs.Interface) error {
   for ifaceId, iface := range ifaces {
-    var definedNetworks int
-    if iface.Network        != "" {definedNetworks++}
-    if iface.TenantNetwork  != "" {definedNetworks++}
-    if iface.ClusterNetwork != "" {definedNetworks++}
+    definedNetworks := countNetworkReferences(iface)
     if definedNetworks != 1 {
       return errors.New("network connection no.:" + strconv.Itoa(ifaceId)+ " contains invalid number of network references:" + strconv.Itoa(definedNetworks))
     }
+    err := cnidel.ValidateVfOptions(iface.Vf)
+    if err != nil {
+      return errors.New("VF properties of network connection no.:" + strconv.Itoa(ifaceId) + " are invalid, because:" + err.Error())
+    }
+    for _, route := range iface.ExtendedProutes {
+      err = danmep.ValidateIpRoute(route, nil)
+      if err != nil {
+        return errors.New("extended policy-based IP route of network connection no.:" + strconv.Itoa(ifaceId) + " is invalid, because:" + err.Error())
+      }
+    }
+    if iface.Bond != nil {
+      err = validateBond(ifaceId, iface.Bond)
+      if err != nil {
+        return err
+      }
+    }
   }
   return nil
 }
 
-func setupNetworking(args *datastructs.CniArgs) (*current.Result, error) {
+func countNetworkReferences(iface datastructs.Interface) int {
+  var definedNetworks int
+  if iface.Network        != "" {definedNetworks++}
+  if iface.TenantNetwork  != "" {definedNetworks++}
+  if iface.ClusterNetwork != "" {definedNetworks++}
+  return definedNetworks
+}
+
+func validateBond(ifaceId int, bond *datastructs.Bond) error {
+  bondId := strconv.Itoa(ifaceId)
+  if bond.Mode != "" && bond.Mode != danmep.BondModeActiveBackup && bond.Mode != danmep.BondMode8023ad {
+    return errors.New("bond of network connection no.:" + bondId + " has unsupported mode:" + bond.Mode)
+  }
+  if bond.Miimon < 0 {
+    return errors.New("bond of network connection no.:" + bondId + " has negative miimon:" + strconv.Itoa(bond.Miimon))
+  }
+  if len(bond.Slaves) == 0 {
+    return errors.New("bond of network connection no.:" + bondId + " does not have any slaves")
+  }
+  for slaveId, slave := range bond.Slaves {
+    definedNetworks := countNetworkReferences(slave)
+    if definedNetworks != 1 {
+      return errors.New("slave no.:" + strconv.Itoa(slaveId) + " of bond no.:" + bondId + " contains invalid number of network references:" + strconv.Itoa(definedNetworks))
+    }
+    if slave.Bond != nil {
+      return errors.New("slave no.:" + strconv.Itoa(slaveId) + " of bond no.:" + bondId + " cannot be a bond itself")
+    }
+    err := cnidel.ValidateVfOptions(slave.Vf)
+    if err != nil {
+      return errors.New("VF properties of slave no.:" + strconv.Itoa(slaveId) + " of bond no.:" + bondId + " are invalid, because:" + err.Error())
+    }
+    if (slave.Ip != "" && slave.Ip != ipam.NoneAllocType) || (slave.Ip6 != "" && slave.Ip6 != ipam.NoneAllocType) {
+      return errors.New("slave no.:" + strconv.Itoa(slaveId) + " of bond no.:" + bondId + " cannot have IPs, they are put on the bond")
+    }
+  }
+  return nil
+}
+
+//Bond slaves are connected to their networks like any other connection, so they are appended to the end of the connection list
+//Their sequence IDs are also recorded in the bond, so it can find them once they are created
+func expandBondSlaves(ifaces []datastructs.Interface) []datastructs.Interface {
+  numOfIfaces := len(ifaces)
+  for ifaceId := 0; ifaceId < numOfIfaces; ifaceId++ {
+    bond := ifaces[ifaceId].Bond
+    if bond == nil {
+      continue
+    }
+    if bond.Mode == "" {
+      bond.Mode = danmep.BondModeActiveBackup
+    }
+    if bond.Miimon == 0 {
+      bond.Miimon = danmep.DefaultBondMiimon
+    }
+    for slaveId := range bond.Slaves {
+      bond.Slaves[slaveId].SequenceId = len(ifaces)
+      ifaces = append(ifaces, bond.Slaves[slaveId])
+    }
+  }
+  return ifaces
+}
+
+//Timeouts are configured in seconds, zero means the default timeout of the syncher
+func getTimeout(seconds int) time.Duration {
+  if seconds == 0 {
+    return syncher.DefaultTimeout
+  }
+  return time.Duration(seconds) * time.Second
+}
+
+//The last quarter of the ADD timeout is reserved for rolling back the interfaces of a failed ADD, so even a timed-out ADD returns within its timeout
+func getRollbackReserve(netConf *datastructs.NetConf) time.Duration {
+  return getTimeout(netConf.AddTimeout) / rollbackShareOfAdd
+}
+
+//Rollback is not cancelled together with the creation of the interfaces, but it can only use what is left from the ADD timeout
+func getRollbackDeadline(ctx context.Context, netConf *datastructs.NetConf) time.Time {
+  deadline, hasDeadline := ctx.Deadline()
+  if !hasDeadline {
+    return time.Now().Add(getRollbackReserve(netConf))
+  }
+  return deadline.Add(getRollbackReserve(netConf))
+}
+
+func setupNetworking(ctx context.Context, args *datastructs.CniArgs) (*current.Result, error) {
   err := preparePodForIpv6(args)
   if err != nil {
     return nil, errors.New("failed to prepare Pod for IPv6 due to:" + err.Error())
   }
   allocatedDevices := make(map[string]*[]string)
-  syncher := syncher.NewSyncher(len(args.Interfaces))
-  danmClient, err := CreateDanmClient(DanmConfig.Kubeconfig)
+  //Every interface created by this ADD journals its own steps, so a failed ADD can undo exactly those, and nothing else
+  var nicSteps []*journal.Journal
+  //Bonds can only be created once all of their slaves exist, so they are not expected to report in the first round
+  bonds := getBonds(args.Interfaces)
+  syncher := syncher.NewSyncher(len(args.Interfaces)-len(bonds))
+  danmClient, err := getDanmClient(args.NetConf)
   if err != nil {
     return nil, err
   }
-  cleanOutdatedAllocations(danmClient, args)
+  args.ExistingEps, err = findEpsByCid(danmClient, args.ContainerId)
+  if err != nil {
+    return nil, errors.New("failed to look for the DanmEps of earlier ADD operations due to:" + err.Error())
+  }
+  cleanOutdatedAllocations(ctx, danmClient, args)
   if args.DefaultNetwork != nil {
-    syncher.ExpectedNumOfResults++
+    syncher.AddExpectedResults(1)
     defParam := datastructs.Interface{SequenceId: 0, Ip: "dynamic",}
-    err = createIface(args, danmClient, args.DefaultNetwork, defParam, syncher, allocatedDevices)
+    steps := journal.NewJournal()
+    nicSteps = append(nicSteps, steps)
+    err = createIface(ctx, args, danmClient, args.DefaultNetwork, defParam, syncher, allocatedDevices, steps)
     if err != nil {
       syncher.PushResult(args.DefaultNetwork.ObjectMeta.Name, err, nil, "")
     }
   }
   for nicID, nicParams := range args.Interfaces {
+    if nicParams.Bond != nil {
+      continue
+    }
     nicParams.SequenceId = nicID
     nicParams.DefaultIfaceName = defaultIfName
     netInfo, err := netcontrol.GetNetworkFromInterface(danmClient, nicParams, args.Pod.ObjectMeta.Namespace)
     if err != nil {
-      syncher.PushResult("", errors.New("failed to get network object for Pod:" + args.Pod.ObjectMeta.Name +
-                             "'s connection no.:" + strconv.Itoa(nicID) + " due to:" + err.Error()), nil, "")
+      syncher.PushResult(getNetworkName(nicParams), cnierrors.NewWithDetails(cnierrors.ErrNetworkNotFound, "failed to get network object for Pod:" + args.Pod.ObjectMeta.Name +
+                             "'s connection no.:" + strconv.Itoa(nicID), getNetworkName(nicParams), err), nil, "")
       continue
     }
-    err = createIface(args, danmClient, netInfo, nicParams, syncher, allocatedDevices)
+    steps := journal.NewJournal()
+    nicSteps = append(nicSteps, steps)
+    err = createIface(ctx, args, danmClient, netInfo, nicParams, syncher, allocatedDevices, steps)
     if err != nil {
       syncher.PushResult(netInfo.ObjectMeta.Name, err, nil, "")
       continue
     }
   }
-  err = syncher.GetAggregatedResult()
-  return syncher.MergeCniResults(), err
+  err = syncher.GetAggregatedResultWithContext(ctx)
+  if err == nil && len(bonds) > 0 {
+    for _, bondParams := range bonds {
+      steps := journal.NewJournal()
+      nicSteps = append(nicSteps, steps)
+      syncher.AddExpectedResults(1)
+      createBond(ctx, args, danmClient, bondParams, syncher, steps)
+    }
+    err = syncher.GetAggregatedResultWithContext(ctx)
+  }
+  if err == nil {
+    deleteUnclaimedEps(ctx, danmClient, args)
+    return syncher.MergeCniResults(), nil
+  }
+  if err == context.DeadlineExceeded {
+    //Timed-out interfaces roll themselves back, but they need to be waited for before the process exits
+    isEveryNicFinished := syncher.WaitForAllResults(time.Until(getRollbackDeadline(ctx, args.NetConf)))
+    if !isEveryNicFinished {
+      log.Println("WARNING: ADD: not every interface of Pod:" + args.PodName + " could be rolled back after the time-out of CNI ADD")
+    }
+    err = errors.New("CNI ADD timed-out after " + getTimeout(args.NetConf.AddTimeout).String())
+  }
+  //Events are only recorded once the interfaces stopped pushing their results, so the failures of timed-out interfaces are also reported
+  recordFailureEvents(args, syncher)
+  //Failed interfaces were already rolled back, but the successful ones of the same ADD must not be left behind either
+  rollbackErr := rollbackSteps(ctx, args.NetConf, nicSteps...)
+  if rollbackErr != nil {
+    return nil, cnierrors.Append(err, "\nrollback of the interfaces created by the failed ADD also failed with:" + rollbackErr.Error())
+  }
+  return nil, err
+}
+
+//DanmEps of earlier ADDs left unclaimed by every requested connection belong to connections the Pod does not request anymore
+//They are deleted the same way DEL would, but from the rollback reserve of the ADD, and without failing the already successful ADD
+func deleteUnclaimedEps(ctx context.Context, danmClient danmclientset.Interface, args *datastructs.CniArgs) {
+  if len(args.ExistingEps) == 0 {
+    return
+  }
+  ctx, cancel := context.WithDeadline(context.Background(), getRollbackDeadline(ctx, args.NetConf))
+  defer cancel()
+  syncher := syncher.NewSyncher(len(args.ExistingEps))
+  bondEps, otherEps := splitBondEps(args.ExistingEps)
+  for _, ep := range bondEps {
+    deleteInterface(ctx, danmClient, args, syncher, ep)
+  }
+  for _, ep := range otherEps {
+    go deleteInterface(ctx, danmClient, args, syncher, ep)
+  }
+  err := syncher.GetAggregatedResultWithContext(ctx)
+  if err != nil {
+    log.Println("WARNING: ADD: interfaces of earlier ADDs not requested by Pod:" + args.PodName + " anymore could not be fully deleted because:" + err.Error())
+  }
+  args.ExistingEps = nil
+}
+
+func getBonds(ifaces []datastructs.Interface) []datastructs.Interface {
+  var bonds []datastructs.Interface
+  for nicID, nicParams := range ifaces {
+    if nicParams.Bond == nil {
+      continue
+    }
+    nicParams.SequenceId = nicID
+    nicParams.DefaultIfaceName = defaultIfName
+    bonds = append(bonds, nicParams)
+  }
+  return bonds
+}
+
+func getNetworkName(iface datastructs.Interface) string {
+  if iface.Network != "" {
+    return iface.Network
+  }
+  if iface.TenantNetwork != "" {
+    return iface.TenantNetwork
+  }
+  return iface.ClusterNetwork
+}
+
+//Every failed network attachment is reported as a Warning Event of the Pod, so users can see why their Pod is stuck without access to the kubelet logs
+//Events are created synchronously, because the process might exit right after the ADD returns
+func recordFailureEvents(args *datastructs.CniArgs, syncher *syncher.Syncher) {
+  if args.Pod == nil {
+    return
+  }
+  k8sClient, err := getK8sClient(args.NetConf)
+  if err != nil {
+    log.Println("WARNING: ADD: Events of Pod:" + args.PodName + " cannot be recorded because K8s client could not be created:" + err.Error())
+    return
+  }
+  hostName, _ := os.Hostname()
+  for _, cniRes := range syncher.GetFailedResults() {
+    event := createFailureEvent(args.Pod, hostName, cniRes.CniName, cniRes.OpResult)
+    _, err = k8sClient.CoreV1().Events(args.Pod.ObjectMeta.Namespace).Create(context.TODO(), event, meta_v1.CreateOptions{})
+    if err != nil {
+      log.Println("WARNING: ADD: Event about the failed connection of Pod:" + args.PodName + " to network:" + cniRes.CniName + " could not be created because:" + err.Error())
+    }
+  }
+}
+
+func createFailureEvent(pod *corev1.Pod, hostName, networkName string, cause error) *corev1.Event {
+  now := meta_v1.Now()
+  return &corev1.Event{
+    ObjectMeta: meta_v1.ObjectMeta{
+      Name:      pod.ObjectMeta.Name + "." + strconv.FormatInt(now.UnixNano(), 16),
+      Namespace: pod.ObjectMeta.Namespace,
+    },
+    InvolvedObject: corev1.ObjectReference{
+      Kind:       "Pod",
+      APIVersion: "v1",
+      Name:       pod.ObjectMeta.Name,
+      Namespace:  pod.ObjectMeta.Namespace,
+      UID:        pod.ObjectMeta.UID,
+    },
+    Reason:  cnierrors.Reason(cnierrors.CodeOf(cause)),
+    Message: "connection to network:" + networkName + " failed with:" + cause.Error(),
+    Type:    corev1.EventTypeWarning,
+    Source:  corev1.EventSource{Component: "danm", Host: hostName},
+    FirstTimestamp: now,
+    LastTimestamp:  now,
+    Count: 1,
+  }
 }
 
 func preparePodForIpv6(args *datastructs.CniArgs) error {
@@ -277,28 +610,51 @@ func preparePodForIpv6(args *datastructs.CniArgs) error {
   return nil
 }
 
-func createIface(args *datastructs.CniArgs, danmClient danmclientset.Interface, netInfo *danmtypes.DanmNet, nicParams datastructs.Interface, syncher *syncher.Syncher, allocatedDevices map[string]*[]string) error {
+func createIface(ctx context.Context, args *datastructs.CniArgs, danmClient danmclientset.Interface, netInfo *danmtypes.DanmNet, nicParams datastructs.Interface, syncher *syncher.Syncher, allocatedDevices map[string]*[]string, steps *journal.Journal) error {
   if !isTenantAllowed(args, netInfo) {
-    return errors.New("Pod:" + args.PodName + "'s namespace:" + args.Namespace + " is not in the AllowedTenants whitelist of network:" + netInfo.ObjectMeta.Name)
+    return cnierrors.NewWithDetails(cnierrors.ErrTenantNotAllowed, "Pod:" + args.PodName + "'s namespace:" + args.Namespace + " is not in the AllowedTenants whitelist of network:" + netInfo.ObjectMeta.Name, netInfo.ObjectMeta.Name, nil)
   }
   var err error
-  if cnidel.IsDeviceNeeded(netInfo.Spec.NetworkType) {
-    if _, ok := allocatedDevices[netInfo.Spec.Options.DevicePool]; !ok {
-      presClient, err := podresclient.GetResourceClient("")
-      if err != nil {
-        return errors.New("failed to instantiate Kubelet Pord Resource client due to:" + err.Error())
-      }
-      allocatedDevices[netInfo.Spec.Options.DevicePool], err = getAllocatedDevices(args, presClient, netInfo.Spec.Options.DevicePool)
-      if err != nil {
-        return errors.New("failed to get allocated devices due to:" + err.Error())
+  existingEp := popExistingEp(args, netInfo)
+  if existingEp != nil {
+    if danmep.IsInterfaceInNetns(existingEp) {
+      log.Println("INFO: ADD: interface:" + existingEp.Spec.Iface.Name + " of Pod:" + args.PodName + " already exists for CID:" + args.ContainerId + ", re-using its DanmEp:" + existingEp.ObjectMeta.Name)
+      if existingEp.Spec.Iface.DeviceID != "" && cnidel.IsDevicePoolUsed(netInfo) {
+        err = loadAllocatedDevices(args, netInfo, allocatedDevices)
+        if err != nil {
+          return err
+        }
+        removeDevice(netInfo.Spec.Options.DevicePool, existingEp.Spec.Iface.DeviceID, allocatedDevices)
       }
+      syncher.PushResult(existingEp.Spec.NetworkName, nil, getPreviousResult(existingEp, args), existingEp.Spec.Iface.Name)
+      return nil
+    }
+    //A DanmEp without an interface is a leftover of an interrupted ADD, its resources are released before the interface is created again
+    log.Println("WARNING: ADD: interface:" + existingEp.Spec.Iface.Name + " of DanmEp:" + existingEp.ObjectMeta.Name + " does not exist in the network namespace of CID:" + args.ContainerId + ", it is going to be re-created")
+    danmep.DeleteDanmEp(ctx, danmClient, existingEp, netInfo)
+    cnidel.ReleaseHostDevice(args.NetConf, netInfo, existingEp)
+    epstore.NewStore(args.NetConf.StoreDir).Remove(existingEp.Spec.CID, existingEp.Spec.Iface.Name)
+  }
+  if cnidel.IsDevicePoolUsed(netInfo) {
+    err = loadAllocatedDevices(args, netInfo, allocatedDevices)
+    if err != nil {
+      return err
     }
     nicParams.Device, err = popDevice(netInfo.Spec.Options.DevicePool, allocatedDevices)
     if err != nil {
       return errors.New("failed to pop devices due to:" + err.Error())
     }
+  } else if cnidel.IsHostDeviceSelectionNeeded(netInfo) {
+    nicParams.Device, err = cnidel.ReserveHostDevice(args.NetConf, netInfo, args.ContainerId)
+    if err != nil {
+      return err
+    }
+    reservedEp := danmtypes.DanmEp{Spec: danmtypes.DanmEpSpec{CID: args.ContainerId, Iface: danmtypes.DanmEpIface{DeviceID: nicParams.Device}}}
+    steps.Record("host device reservation", func(ctx context.Context) error {
+      return cnidel.ReleaseHostDevice(args.NetConf, netInfo, &reservedEp)
+    })
   }
-  go createNic(syncher, danmClient, nicParams, netInfo, args)
+  go createNic(ctx, syncher, danmClient, nicParams, netInfo, args, steps)
   return nil
 }
 
@@ -316,6 +672,48 @@ func isTenantAllowed(args *datastructs.CniArgs, netInfo *danmtypes.DanmNet) bool
   return isTenantAllowed
 }
 
+//popExistingEp returns the DanmEp an earlier ADD operation of the same CID created for the network, if there is one
+//Every existing DanmEp can only be claimed by one connection, so Pods connecting to the same network multiple times are also handled
+func popExistingEp(args *datastructs.CniArgs, netInfo *danmtypes.DanmNet) *danmtypes.DanmEp {
+  for index, ep := range args.ExistingEps {
+    if ep.Spec.NetworkName == netInfo.ObjectMeta.Name && ep.Spec.ApiType == netInfo.TypeMeta.Kind {
+      args.ExistingEps = append(args.ExistingEps[:index], args.ExistingEps[index+1:]...)
+      return &ep
+    }
+  }
+  return nil
+}
+
+//The result of the earlier ADD is returned if it was persisted to the node-local store, otherwise it is re-constructed from the DanmEp
+func getPreviousResult(ep *danmtypes.DanmEp, args *datastructs.CniArgs) *current.Result {
+  recs, _ := epstore.NewStore(args.NetConf.StoreDir).FindByCid(ep.Spec.CID)
+  for _, rec := range recs {
+    if rec.Ep.ObjectMeta.Name == ep.ObjectMeta.Name && rec.Result != nil {
+      return rec.Result
+    }
+  }
+  prevResult := &current.Result{}
+  AddIfaceToResult(ep.Spec.Iface.Name, args.ContainerId, prevResult)
+  AddIpToResult(ep.Spec.Iface.Address,"4",prevResult)
+  AddIpToResult(ep.Spec.Iface.AddressIPv6,"6",prevResult)
+  return prevResult
+}
+
+func loadAllocatedDevices(args *datastructs.CniArgs, netInfo *danmtypes.DanmNet, allocatedDevices map[string]*[]string) error {
+  if _, ok := allocatedDevices[netInfo.Spec.Options.DevicePool]; ok {
+    return nil
+  }
+  presClient, err := podresclient.GetResourceClient("")
+  if err != nil {
+    return errors.New("failed to instantiate Kubelet Pord Resource client due to:" + err.Error())
+  }
+  allocatedDevices[netInfo.Spec.Options.DevicePool], err = getAllocatedDevices(args, presClient, netInfo.Spec.Options.DevicePool)
+  if err != nil {
+    return errors.New("failed to get allocated devices due to:" + err.Error())
+  }
+  return nil
+}
+
 func getAllocatedDevices(args *datastructs.CniArgs, presClient multus_types.ResourceClient, devicePool string)(*[]string, error){
   resourceMap, err := presClient.GetPodResourceMap(args.Pod)
   if err != nil {
@@ -339,51 +737,182 @@ func popDevice(devicePool string, allocatedDevices map[string]*[]string)(string,
   return device, nil
 }
 
-func createNic(syncher *syncher.Syncher, danmClient danmclientset.Interface, iface datastructs.Interface, netInfo *danmtypes.DanmNet, args *datastructs.CniArgs) {
-  isIpReservationNeeded := cnidel.IsDanmIpamNeededForDelegation(iface, netInfo) || netInfo.Spec.NetworkType == "ipvlan"
-  ep, netInfo, err := danmep.CreateDanmEp(danmClient, DanmConfig.NamingScheme, isIpReservationNeeded, netInfo, iface, args)
-  if err != nil {
-    if ep != nil {
-      danmep.DeleteDanmEp(danmClient, ep, netInfo)
+//Devices already used by re-used interfaces must not be handed out to the new ones
+func removeDevice(devicePool, deviceID string, allocatedDevices map[string]*[]string) {
+  if allocatedDevices[devicePool] == nil {
+    return
+  }
+  devices := (*allocatedDevices[devicePool])
+  for index, device := range devices {
+    if device == deviceID {
+      devices = append(devices[:index], devices[index+1:]...)
+      allocatedDevices[devicePool] = &devices
+      return
     }
-    syncher.PushResult(netInfo.ObjectMeta.Name, err, nil, "")
+  }
+}
+
+func createNic(ctx context.Context, syncher *syncher.Syncher, danmClient danmclientset.Interface, iface datastructs.Interface, netInfo *danmtypes.DanmNet, args *datastructs.CniArgs, steps *journal.Journal) {
+  networkName := netInfo.ObjectMeta.Name
+  isIpReservationNeeded := cnidel.IsDanmIpamNeededForDelegation(iface, netInfo) || !cnidel.IsDelegationRequired(netInfo)
+  ep, netInfo, err := danmep.CreateDanmEp(ctx, steps, danmClient, args.NetConf.NamingScheme, isIpReservationNeeded, netInfo, iface, args)
+  if err != nil {
+    pushFailedNic(ctx, args.NetConf, syncher, steps, networkName, err)
     return
   }
   var cniResult *current.Result
   if cnidel.IsDelegationRequired(netInfo) {
-    cniResult, err = createDelegatedInterface(danmClient, isIpReservationNeeded, ep, netInfo, args)
+    cniResult, err = createDelegatedInterface(ctx, steps, danmClient, isIpReservationNeeded, ep, netInfo, args)
   } else {
-    cniResult, err = createDanmInterface(danmClient, ep, netInfo, args)
+    cniResult, err = createDanmInterface(steps, danmClient, ep, netInfo, args)
+  }
+  if err != nil {
+    pushFailedNic(ctx, args.NetConf, syncher, steps, networkName, err)
+    return
+  }
+  finishNic(ctx, syncher, steps, ep, netInfo, cniResult, args)
+}
+
+//finishNic post-processes a freshly created interface, invokes the chained plugins of its network, and persists it to the node-local store
+func finishNic(ctx context.Context, syncher *syncher.Syncher, steps *journal.Journal, ep *danmtypes.DanmEp, netInfo *danmtypes.DanmNet, cniResult *current.Result, args *datastructs.CniArgs) {
+  networkName := netInfo.ObjectMeta.Name
+  err := danmep.PostProcessInterface(ep, netInfo)
+  if err != nil {
+    pushFailedNic(ctx, args.NetConf, syncher, steps, networkName, errors.New("Post-processing failed for interface:" + ep.Spec.Iface.Name + " because:" + err.Error()))
+    return
+  }
+  if len(netInfo.Spec.Options.ChainedPlugins) > 0 {
+    cniResult, err = cnidel.ExecChainedPlugins(ctx, args.NetConf, netInfo, ep, cniResult, args.RuntimeConfig)
+    if err != nil {
+      pushFailedNic(ctx, args.NetConf, syncher, steps, networkName, errors.New("chained plugins failed for interface:" + ep.Spec.Iface.Name + " because:" + err.Error()))
+      return
+    }
+    steps.Record("chained plugins", func(ctx context.Context) error {
+      return cnidel.DeleteChainedPlugins(ctx, args.NetConf, netInfo, ep)
+    })
+  }
+  //The runtime already considers an ADD failed after its deadline, so nobody would ever delete an interface finished only after it
+  if ctx.Err() != nil {
+    pushFailedNic(ctx, args.NetConf, syncher, steps, networkName, errors.New("creation of interface:" + ep.Spec.Iface.Name + " timed-out"))
+    return
+  }
+  //Failing to store the interface only degrades CNI DEL during API outages, so it shall not fail the whole Pod
+  store := epstore.NewStore(args.NetConf.StoreDir)
+  err = store.Save(&epstore.Record{Ep: *ep, Network: *netInfo, Result: cniResult})
+  if err != nil {
+    log.Println("WARNING: ADD: interface:" + ep.Spec.Iface.Name + " of Pod:" + ep.Spec.Pod + " could not be saved to the node-local store because:" + err.Error())
+  } else {
+    steps.Record("node-local store record", func(ctx context.Context) error {
+      return store.Remove(ep.Spec.CID, ep.Spec.Iface.Name)
+    })
+  }
+  syncher.PushResult(networkName, nil, cniResult, ep.Spec.Iface.Name)
+}
+
+//Bonds are created one-by-one after all the other interfaces, as their slaves need to exist in the Pod already
+func createBond(ctx context.Context, args *datastructs.CniArgs, danmClient danmclientset.Interface, nicParams datastructs.Interface, syncher *syncher.Syncher, steps *journal.Journal) {
+  netInfo, err := netcontrol.GetNetworkFromInterface(danmClient, nicParams, args.Pod.ObjectMeta.Namespace)
+  if err != nil {
+    syncher.PushResult(getNetworkName(nicParams), cnierrors.NewWithDetails(cnierrors.ErrNetworkNotFound, "failed to get network object for Pod:" + args.Pod.ObjectMeta.Name +
+                       "'s bond connection no.:" + strconv.Itoa(nicParams.SequenceId), getNetworkName(nicParams), err), nil, "")
+    return
+  }
+  networkName := netInfo.ObjectMeta.Name
+  if !isTenantAllowed(args, netInfo) {
+    syncher.PushResult(networkName, cnierrors.NewWithDetails(cnierrors.ErrTenantNotAllowed, "Pod:" + args.PodName + "'s namespace:" + args.Namespace + " is not in the AllowedTenants whitelist of network:" + networkName, networkName, nil), nil, "")
+    return
+  }
+  existingEp := popExistingEp(args, netInfo)
+  if existingEp != nil {
+    if danmep.IsInterfaceInNetns(existingEp) {
+      log.Println("INFO: ADD: bond:" + existingEp.Spec.Iface.Name + " of Pod:" + args.PodName + " already exists for CID:" + args.ContainerId + ", re-using its DanmEp:" + existingEp.ObjectMeta.Name)
+      syncher.PushResult(networkName, nil, getPreviousResult(existingEp, args), existingEp.Spec.Iface.Name)
+      return
+    }
+    danmep.DeleteDanmEp(ctx, danmClient, existingEp, netInfo)
+    epstore.NewStore(args.NetConf.StoreDir).Remove(existingEp.Spec.CID, existingEp.Spec.Iface.Name)
+  }
+  nicParams.Bond.SlaveNames, err = getBondSlaveNames(danmClient, args, nicParams.Bond)
+  if err != nil {
+    syncher.PushResult(networkName, err, nil, "")
+    return
   }
+  ep, netInfo, err := danmep.CreateDanmEp(ctx, steps, danmClient, args.NetConf.NamingScheme, true, netInfo, nicParams, args)
   if err != nil {
-    danmep.DeleteDanmEp(danmClient, ep, netInfo)
-    syncher.PushResult(ep.Spec.NetworkName, err, cniResult, "")
+    pushFailedNic(ctx, args.NetConf, syncher, steps, networkName, err)
     return
   }
-  err = danmep.PostProcessInterface(ep, netInfo)
+  err = danmep.CreateBondInterface(ep)
   if err != nil {
-    danmep.DeleteDanmEp(danmClient, ep, netInfo)
-    syncher.PushResult(ep.Spec.NetworkName, errors.New("Post-processing failed for interface:" + ep.Spec.Iface.Name + " because:" + err.Error()), nil, "")
+    pushFailedNic(ctx, args.NetConf, syncher, steps, networkName, errors.New("bond interface could not be created due to error:" + err.Error()))
     return
   }
-  syncher.PushResult(ep.Spec.NetworkName, nil, cniResult, ep.Spec.Iface.Name)
+  steps.Record("bond link", func(ctx context.Context) error {
+    return danmep.DeleteBondInterface(ep)
+  })
+  cniResult := &current.Result{}
+  AddIfaceToResult(ep.Spec.Iface.Name, args.ContainerId, cniResult)
+  AddIpToResult(ep.Spec.Iface.Address,"4",cniResult)
+  AddIpToResult(ep.Spec.Iface.AddressIPv6,"6",cniResult)
+  finishNic(ctx, syncher, steps, ep, netInfo, cniResult, args)
+}
+
+//The slaves were named exactly the same way when they were connected to their own networks
+func getBondSlaveNames(danmClient danmclientset.Interface, args *datastructs.CniArgs, bond *datastructs.Bond) ([]string, error) {
+  var slaveNames []string
+  for _, slave := range bond.Slaves {
+    slaveNet, err := netcontrol.GetNetworkFromInterface(danmClient, slave, args.Pod.ObjectMeta.Namespace)
+    if err != nil {
+      return nil, cnierrors.NewWithDetails(cnierrors.ErrNetworkNotFound, "failed to get network object of bond slave:" + getNetworkName(slave), getNetworkName(slave), err)
+    }
+    slaveNames = append(slaveNames, danmep.CalculateIfaceName(args.NetConf.NamingScheme, slaveNet.Spec.Options.Prefix, defaultIfName, slave.SequenceId))
+  }
+  return slaveNames, nil
+}
+
+//A failed interface immediately undoes every step it has already executed, failures of the roll-back are reported together with the original error
+func pushFailedNic(ctx context.Context, netConf *datastructs.NetConf, syncher *syncher.Syncher, steps *journal.Journal, networkName string, err error) {
+  rollbackErr := rollbackSteps(ctx, netConf, steps)
+  if rollbackErr != nil {
+    err = cnierrors.Append(err, ", and its rollback also failed:" + rollbackErr.Error())
+  }
+  syncher.PushResult(networkName, err, nil, "")
 }
 
-func createDelegatedInterface(danmClient danmclientset.Interface, wasIpReservedByDanmIpam bool, ep *danmtypes.DanmEp, netInfo *danmtypes.DanmNet, args *datastructs.CniArgs) (*current.Result,error) {
+func rollbackSteps(ctx context.Context, netConf *datastructs.NetConf, steps ...*journal.Journal) error {
+  ctx, cancel := context.WithDeadline(context.Background(), getRollbackDeadline(ctx, netConf))
+  defer cancel()
+  var rollbackErrors []string
+  for i := len(steps)-1; i >= 0; i-- {
+    err := steps[i].Rollback(ctx)
+    if err != nil {
+      rollbackErrors = append(rollbackErrors, err.Error())
+    }
+  }
+  if len(rollbackErrors) > 0 {
+    return errors.New(strings.Join(rollbackErrors, "; "))
+  }
+  return nil
+}
+
+func createDelegatedInterface(ctx context.Context, steps *journal.Journal, danmClient danmclientset.Interface, wasIpReservedByDanmIpam bool, ep *danmtypes.DanmEp, netInfo *danmtypes.DanmNet, args *datastructs.CniArgs) (*current.Result,error) {
   origV4Address := ep.Spec.Iface.Address
   origV6Address := ep.Spec.Iface.AddressIPv6
-  delegatedResult,err := cnidel.DelegateInterfaceSetup(DanmConfig, wasIpReservedByDanmIpam, netInfo, ep)
+  delegatedResult,err := cnidel.DelegateInterfaceSetup(ctx, args.NetConf, wasIpReservedByDanmIpam, netInfo, ep, args.RuntimeConfig)
   if err != nil {
-    //TODO: is this -basically only host-ipam related- stuff really needed, or is just legacy residue?
-    cnidel.FreeDelegatedIps(netInfo, ep.Spec.Iface.Address, ep.Spec.Iface.AddressIPv6)
-    return delegatedResult, errors.New("CNI delegation failed due to error:" + err.Error())
+    //Delegates using host-local IPAM might leave their reservation behind when they fail midway
+    cnidel.FreeDelegatedIps(args.NetConf, ep)
+    return delegatedResult, cnierrors.NewWithDetails(cnierrors.ErrDelegateFailed, "CNI delegation failed", netInfo.ObjectMeta.Name, err)
   }
+  steps.Record("delegate ADD", func(ctx context.Context) error {
+    return cnidel.DelegateInterfaceDelete(ctx, args.NetConf, netInfo, ep)
+  })
   if (origV4Address != ep.Spec.Iface.Address     && origV4Address != ipam.NoneAllocType) ||
      (origV6Address != ep.Spec.Iface.AddressIPv6 && origV6Address != ipam.NoneAllocType) {
-    err = danmep.UpdateDanmEp(danmClient, ep)
+    err = danmep.UpdateDanmEp(ctx, danmClient, ep)
     if err != nil {
-      //TODO: is this -basically only host-ipam related- stuff really needed, or is just legacy residue?
-      cnidel.FreeDelegatedIps(netInfo, ep.Spec.Iface.Address, ep.Spec.Iface.AddressIPv6)
+      //Delegates using host-local IPAM might leave their reservation behind when they fail midway
+      cnidel.FreeDelegatedIps(args.NetConf, ep)
       return delegatedResult, errors.New("could not update DanmEp:" + ep.ObjectMeta.Name + " in namespace:" + ep.ObjectMeta.Namespace +
                                          " with the result returned by CNI plugin:" + netInfo.Spec.NetworkType + " because:" + err.Error())
     }
@@ -391,11 +920,14 @@ func createDelegatedInterface(danmClient danmclientset.Interface, wasIpReservedB
   return delegatedResult, nil
 }
 
-func createDanmInterface(danmClient danmclientset.Interface, ep *danmtypes.DanmEp, netInfo *danmtypes.DanmNet, args *datastructs.CniArgs) (*current.Result,error) {
-  err := danmep.AddIpvlanInterface(netInfo, ep)
+func createDanmInterface(steps *journal.Journal, danmClient danmclientset.Interface, ep *danmtypes.DanmEp, netInfo *danmtypes.DanmNet, args *datastructs.CniArgs) (*current.Result,error) {
+  err := danmep.AddNativeInterface(netInfo, ep)
   if err != nil {
-    return nil, errors.New("IPVLAN interface could not be created due to error:" + err.Error())
+    return nil, errors.New(netInfo.Spec.NetworkType + " interface could not be created due to error:" + err.Error())
   }
+  steps.Record("native link", func(ctx context.Context) error {
+    return danmep.DeleteNativeInterface(netInfo, ep)
+  })
   danmResult := &current.Result{}
   AddIfaceToResult(ep.Spec.Iface.Name, args.ContainerId, danmResult)
   AddIpToResult(ep.Spec.Iface.Address,"4",danmResult)
@@ -422,41 +954,221 @@ func AddIpToResult(ip string, version string, cniResult *current.Result) {
   }
 }
 
-func DeleteInterfaces(args *skel.CmdArgs) error {
+// DeleteInterfaces implements CNI DEL, it never outlives ctx, nor the DEL timeout of the config
+func DeleteInterfaces(ctx context.Context, args *skel.CmdArgs) error {
   cniArgs,err := extractCniArgs(args)
-  log.Println("CNI DEL invoked with: ns:" + cniArgs.Namespace + " for Pod:" + cniArgs.PodName + " CID: " + cniArgs.ContainerId)
   if err != nil {
     log.Println("INFO: DEL: CNI args could not be loaded because" + err.Error())
     return nil
   }
-  err = loadNetConf(cniArgs.StdIn)
+  log.Println("CNI DEL invoked with: ns:" + cniArgs.Namespace + " for Pod:" + cniArgs.PodName + " CID: " + cniArgs.ContainerId)
+  err = loadNetConf(cniArgs, args)
   if err != nil {
     log.Println("INFO: DEL: cannot load DANM CNI config due to error:" + err.Error())
     return nil
   }
-  danmClient, err := CreateDanmClient(DanmConfig.Kubeconfig)
+  ctx, cancel := context.WithTimeout(ctx, getTimeout(cniArgs.NetConf.DelTimeout))
+  defer cancel()
+  danmClient, err := getDanmClient(cniArgs.NetConf)
   if err != nil {
     log.Println("INFO: DEL: DanmEp REST client could not be created because" + err.Error())
+    deleteInterfacesOffline(ctx, cniArgs)
     return nil
   }
+  //A DanmEp missing from the informer cache of the agent would leak its interface and IPs, so DEL always asks the API server
   eplist, err := danmep.FindByCid(danmClient, cniArgs.ContainerId)
   if err != nil {
     log.Println("INFO: DEL: Could not interrogate DanmEps from K8s API server because" + err.Error())
+    deleteInterfacesOffline(ctx, cniArgs)
     return nil
   }
   syncher := syncher.NewSyncher(len(eplist))
+  bondEps, otherEps := splitBondEps(eplist)
+  //Bonds are deleted first, so their slaves are already released when they are deleted
+  for _, ep := range bondEps {
+    deleteInterface(ctx, danmClient, cniArgs, syncher, ep)
+  }
   //Note to self: NEVER change this to pass-by-pointer. It totally breaks CNI DEL for all but one interface
-  for _, ep := range eplist {
-    go deleteInterface(danmClient, cniArgs, syncher, ep)
+  for _, ep := range otherEps {
+    go deleteInterface(ctx, danmClient, cniArgs, syncher, ep)
   }
-  deleteErrors := syncher.GetAggregatedResult()
+  deleteErrors := syncher.GetAggregatedResultWithContext(ctx)
   if deleteErrors != nil {
     log.Println("INFO: DEL: Following errors happened during interface deletion:" + deleteErrors.Error())
   }
+  //The API server is obviously reachable again, so it is a good time to finish what earlier, offline DELs could not
+  ReplayPendingReleases(danmClient, cniArgs.NetConf)
   return nil
 }
 
-func deleteInterface(danmClient danmclientset.Interface, args *datastructs.CniArgs, syncher *syncher.Syncher, ep danmtypes.DanmEp) {
+func splitBondEps(eps []danmtypes.DanmEp) ([]danmtypes.DanmEp, []danmtypes.DanmEp) {
+  var bondEps, otherEps []danmtypes.DanmEp
+  for _, ep := range eps {
+    if ep.Spec.Iface.Bond != nil {
+      bondEps = append(bondEps, ep)
+    } else {
+      otherEps = append(otherEps, ep)
+    }
+  }
+  return bondEps, otherEps
+}
+
+func sortBondRecsFirst(recs []epstore.Record) {
+  sort.SliceStable(recs, func(i, j int) bool {
+    return recs[i].Ep.Spec.Iface.Bond != nil && recs[j].Ep.Spec.Iface.Bond == nil
+  })
+}
+
+//When the API server cannot be reached the interfaces are torn down based on the node-local store
+//Their DanmEps, and IPs are queued to be released once the API server becomes available again
+func deleteInterfacesOffline(ctx context.Context, args *datastructs.CniArgs) {
+  store := epstore.NewStore(args.NetConf.StoreDir)
+  recs, err := store.FindByCid(args.ContainerId)
+  if err != nil {
+    log.Println("ERROR: DEL: interfaces of CID:" + args.ContainerId + " could not be read from the node-local store because:" + err.Error())
+    return
+  }
+  sortBondRecsFirst(recs)
+  for _, rec := range recs {
+    err = deleteNic(ctx, args.NetConf, &rec.Network, &rec.Ep)
+    if err != nil {
+      log.Println("INFO: DEL: offline deletion of interface:" + rec.Ep.Spec.Iface.Name + " failed with error:" + err.Error())
+    }
+    err = store.QueueRelease(&rec)
+    if err != nil {
+      log.Println("ERROR: DEL: release of DanmEp:" + rec.Ep.ObjectMeta.Name + " could not be queued, its IPs will leak because:" + err.Error())
+      continue
+    }
+    store.Remove(rec.Ep.Spec.CID, rec.Ep.Spec.Iface.Name)
+    log.Println("INFO: DEL: interface:" + rec.Ep.Spec.Iface.Name + " of CID:" + args.ContainerId + " was deleted offline, release of DanmEp:" + rec.Ep.ObjectMeta.Name + " is queued")
+  }
+}
+
+// ReplayPendingReleases deletes the DanmEps, and frees the IPs queued by earlier offline CNI DEL operations
+// Only one process replays the queue at a time. DanmEps already removed from the API are considered released, as their IPs were freed together with them
+// Without a config the one of the last CNI operation is used, or the defaults if there was none yet
+func ReplayPendingReleases(danmClient danmclientset.Interface, netConf *datastructs.NetConf) {
+  if netConf == nil {
+    netConf = getLastNetConf()
+  }
+  storeDir := epstore.DefaultStoreDir
+  delTimeout := syncher.DefaultTimeout
+  if netConf != nil {
+    storeDir = netConf.StoreDir
+    delTimeout = getTimeout(netConf.DelTimeout)
+  }
+  store := epstore.NewStore(storeDir)
+  unlock, isLocked, err := store.LockReplay()
+  if err != nil || !isLocked {
+    return
+  }
+  defer unlock()
+  recs, err := store.PendingReleases()
+  if err != nil {
+    log.Println("ERROR: queued DanmEp releases could not be read because:" + err.Error())
+    return
+  }
+  for _, rec := range recs {
+    _, err = danmClient.DanmV1().DanmEps(rec.Ep.ObjectMeta.Namespace).Get(context.TODO(), rec.Ep.ObjectMeta.Name, meta_v1.GetOptions{})
+    if apierrors.IsNotFound(err) {
+      store.RemovePendingRelease(rec.Ep.ObjectMeta.Name)
+      continue
+    }
+    if err != nil {
+      log.Println("INFO: queued release of DanmEp:" + rec.Ep.ObjectMeta.Name + " is postponed because:" + err.Error())
+      return
+    }
+    dnet, err := netcontrol.GetNetworkFromEp(danmClient, &rec.Ep)
+    if err != nil {
+      dnet = &rec.Network
+    }
+    ctx, cancel := context.WithTimeout(context.Background(), delTimeout)
+    err = danmep.DeleteDanmEp(ctx, danmClient, &rec.Ep, dnet)
+    cancel()
+    if err != nil {
+      log.Println("INFO: queued release of DanmEp:" + rec.Ep.ObjectMeta.Name + " failed, will be retried later because:" + err.Error())
+      continue
+    }
+    store.RemovePendingRelease(rec.Ep.ObjectMeta.Name)
+    log.Println("INFO: queued release of DanmEp:" + rec.Ep.ObjectMeta.Name + " was successfully executed")
+  }
+}
+
+// CleanupStaleReservations removes the host-local IPAM reservations of delegated plugins, and the host device reservations whose containers do not exist on the host anymore
+// A container is considered alive while a DanmEp of the host refers to it, and the Pod of that DanmEp still exists. Nothing is removed when either of these is unknown
+func CleanupStaleReservations(host string) {
+  if agentCaches == nil || agentCaches.EpLister == nil || agentCaches.K8sClient == nil {
+    return
+  }
+  netConf := getLastNetConf()
+  storeDir := epstore.DefaultStoreDir
+  if netConf != nil {
+    storeDir = netConf.StoreDir
+  }
+  store := epstore.NewStore(storeDir)
+  eps, err := agentCaches.EpLister.List(labels.Everything())
+  if err != nil {
+    log.Println("WARNING: stale reservations are not cleaned, because DanmEps could not be listed:" + err.Error())
+    return
+  }
+  liveCids, err := getLiveCids(agentCaches.K8sClient, host, eps)
+  if err != nil {
+    log.Println("WARNING: stale reservations are not cleaned, because the Pods of the host could not be checked:" + err.Error())
+    return
+  }
+  removed, err := cnidel.CleanupStaleHostLocalReservations(netConf, liveCids)
+  if err != nil {
+    log.Println("WARNING: stale host-local reservations could not be cleaned because:" + err.Error())
+  } else if len(removed) > 0 {
+    log.Println("INFO: " + strconv.Itoa(len(removed)) + " stale host-local reservations were removed")
+  }
+  //Devices are reserved before the interface is stored, so the same grace period protects the reservations of interfaces under creation
+  released, err := store.ReleaseStaleDevices(liveCids, cnidel.StaleReservationGracePeriod)
+  if err != nil {
+    log.Println("WARNING: stale host device reservations could not be cleaned because:" + err.Error())
+  }
+  if len(released) > 0 {
+    log.Println("INFO: reservations of host devices:" + strings.Join(released, ",") + " of deleted containers were released")
+  }
+}
+
+//The DanmEps of leaked interfaces outlive their Pods, so a DanmEp only keeps its container alive while its Pod still exists
+//A Pod re-created with the same name is a different Pod, so its UID must also match
+func getLiveCids(k8sClient kubernetes.Interface, host string, eps []*danmtypes.DanmEp) (map[string]bool, error) {
+  liveCids := map[string]bool{}
+  livePods := map[string]bool{}
+  for _, ep := range eps {
+    if ep.Spec.Host != host || ep.Spec.CID == "" {
+      continue
+    }
+    podKey := ep.ObjectMeta.Namespace + "/" + ep.Spec.Pod + "/" + string(ep.Spec.PodUID)
+    if _, isChecked := livePods[podKey]; !isChecked {
+      pod, err := k8sClient.CoreV1().Pods(ep.ObjectMeta.Namespace).Get(context.TODO(), ep.Spec.Pod, meta_v1.GetOptions{})
+      if err != nil && !apierrors.IsNotFound(err) {
+        return nil, errors.New("Pod:" + ep.Spec.Pod + " of DanmEp:" + ep.ObjectMeta.Name + " could not be read because:" + err.Error())
+      }
+      livePods[podKey] = err == nil && (ep.Spec.PodUID == "" || pod.ObjectMeta.UID == ep.Spec.PodUID)
+    }
+    if livePods[podKey] {
+      liveCids[ep.Spec.CID] = true
+    }
+  }
+  return liveCids, nil
+}
+
+//The informer cache of the agent might not know about freshly created DanmEps yet, so an empty cache hit is always double-checked with the API server
+//Only ADD relies on the cache, DEL always asks the API server
+func findEpsByCid(danmClient danmclientset.Interface, cid string) ([]danmtypes.DanmEp, error) {
+  if agentCaches != nil && agentCaches.EpLister != nil {
+    eplist, err := danmep.FindByCidInCache(agentCaches.EpLister, cid)
+    if err == nil && len(eplist) > 0 {
+      return eplist, nil
+    }
+  }
+  return danmep.FindByCid(danmClient, cid)
+}
+
+func deleteInterface(ctx context.Context, danmClient danmclientset.Interface, args *datastructs.CniArgs, syncher *syncher.Syncher, ep danmtypes.DanmEp) {
   //During delete we are not that interested in errors, but we also can't just return yet.
   //We need to try and clean-up as many remaining resources as possible
   var aggregatedError string
@@ -465,15 +1177,17 @@ func deleteInterface(danmClient danmclientset.Interface, args *datastructs.CniAr
     aggregatedError += "failed to get network:"+ err.Error() + "; "
   }
   if netInfo != nil {
-    err = deleteNic(netInfo, &ep)
+    err = deleteNic(ctx, args.NetConf, netInfo, &ep)
     if err != nil {
       aggregatedError += "failed to delete container NIC:" + err.Error() + "; "
     }
   }
-  err = danmep.DeleteDanmEp(danmClient, &ep, netInfo)
+  err = danmep.DeleteDanmEp(ctx, danmClient, &ep, netInfo)
   if err != nil {
     aggregatedError += "failed to delete DanmEp:" + err.Error() + "; "
+    queueRelease(args.NetConf, &ep, netInfo)
   }
+  epstore.NewStore(args.NetConf.StoreDir).Remove(ep.Spec.CID, ep.Spec.Iface.Name)
   if aggregatedError != "" {
     syncher.PushResult(ep.Spec.NetworkName, errors.New(aggregatedError), nil, "")
   } else {
@@ -481,29 +1195,82 @@ func deleteInterface(danmClient danmclientset.Interface, args *datastructs.CniAr
   }
 }
 
-func deleteNic(netInfo *danmtypes.DanmNet, ep *danmtypes.DanmEp) error {
+//If the interface is already gone but the API server is unable to free its resources, the release is queued with the last known state of its network
+func queueRelease(netConf *datastructs.NetConf, ep *danmtypes.DanmEp, netInfo *danmtypes.DanmNet) {
+  store := epstore.NewStore(netConf.StoreDir)
+  if netInfo == nil {
+    recs, _ := store.FindByCid(ep.Spec.CID)
+    for _, rec := range recs {
+      if rec.Ep.Spec.Iface.Name == ep.Spec.Iface.Name {
+        netInfo = &rec.Network
+      }
+    }
+  }
+  if netInfo == nil {
+    return
+  }
+  err := store.QueueRelease(&epstore.Record{Ep: *ep, Network: *netInfo})
+  if err != nil {
+    log.Println("ERROR: DEL: release of DanmEp:" + ep.ObjectMeta.Name + " could not be queued because:" + err.Error())
+  }
+}
+
+//Chained plugins are torn down first, as they were the last ones touching the interface
+func deleteNic(ctx context.Context, netConf *datastructs.NetConf, netInfo *danmtypes.DanmNet, ep *danmtypes.DanmEp) error {
   var err error
-  if ep.Spec.NetworkType != "ipvlan" {
-    err = cnidel.DelegateInterfaceDelete(DanmConfig, netInfo, ep)
+  chainErr := cnidel.DeleteChainedPlugins(ctx, netConf, netInfo, ep)
+  ruleErr := danmep.DeletePolicyRules(ep)
+  if ruleErr != nil {
+    log.Println("WARNING: DEL: policy-based routing rules of interface:" + ep.Spec.Iface.Name + " could not be deleted because:" + ruleErr.Error())
+  }
+  if ep.Spec.Iface.Bond != nil {
+    err = danmep.DeleteBondInterface(ep)
+  } else if !cnidel.IsDanmNativeType(ep.Spec.NetworkType) {
+    err = cnidel.DelegateInterfaceDelete(ctx, netConf, netInfo, ep)
   } else {
-    err = danmep.DeleteIpvlanInterface(ep)
+    err = danmep.DeleteNativeInterface(netInfo, ep)
+  }
+  if chainErr != nil {
+    if err != nil {
+      return errors.New(err.Error() + ", and " + chainErr.Error())
+    }
+    return chainErr
   }
   return err
 }
 
-func GetInterfaces(args *skel.CmdArgs) error {
+// GetInterfaces implements CNI CHECK by replaying CHECK towards the delegated plugins with the configs, and results cached during ADD
+func GetInterfaces(ctx context.Context, args *skel.CmdArgs) error {
+  cniArgs,err := extractCniArgs(args)
+  if err != nil {
+    log.Println("ERROR: CHECK: CNI args could not be loaded because" + err.Error())
+    return err
+  }
+  err = loadNetConf(cniArgs, args)
+  if err != nil {
+    log.Println("ERROR: CHECK: cannot load DANM CNI config due to error:" + err.Error())
+    return err
+  }
+  ctx, cancel := context.WithTimeout(ctx, getTimeout(cniArgs.NetConf.DelTimeout))
+  defer cancel()
+  err = cnidel.CheckDelegatedInterfaces(ctx, cniArgs.NetConf, cniArgs.ContainerId)
+  if err != nil {
+    log.Println("ERROR: CHECK: interfaces of CID:" + cniArgs.ContainerId + " failed the check with error:" + err.Error())
+    return errors.New("CNI CHECK failed: " + err.Error())
+  }
   return nil
 }
 
 // I'm tired of cleaning up after Kubelet, but what can we do? :)
 // After a full cluster restart Kubelet invokes a CNI_ADD for the same Pod, with the same UID.
 // We need to take care of clearing old, invalid allocations for the same UID ourselves during ADD.
-func cleanOutdatedAllocations(danmClient danmclientset.Interface, args *datastructs.CniArgs){
+func cleanOutdatedAllocations(ctx context.Context, danmClient danmclientset.Interface, args *datastructs.CniArgs){
   deps, _ := danmep.FindByPodName(danmClient, args.Pod.ObjectMeta.Name, args.Pod.ObjectMeta.Namespace)
   for _, dep := range deps {
-    if dep.Spec.PodUID == args.Pod.ObjectMeta.UID {
+    //DanmEps of the same CID belong to a repeated ADD, they are handled by the interface re-use logic instead
+    if dep.Spec.PodUID == args.Pod.ObjectMeta.UID && dep.Spec.CID != args.ContainerId {
       dnet, _ := netcontrol.GetNetworkFromEp(danmClient, &dep)
-      danmep.DeleteDanmEp(danmClient, &dep, dnet)
+      danmep.DeleteDanmEp(ctx, danmClient, &dep, dnet)
       log.Println("WARNING: DANM needed to reconcile inconsistent cluster state during CNI ADD, as DanmEps already existed for Pod:" + args.Pod.ObjectMeta.Name + " in namespace:" + args.Pod.ObjectMeta.Namespace)
     }
   }
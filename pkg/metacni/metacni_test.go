@@ -1,7 +1,20 @@
 package metacni
 
 import (
+  "context"
+  "strings"
   "testing"
+  "github.com/containernetworking/cni/pkg/skel"
+  corev1 "k8s.io/api/core/v1"
+  meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+  "k8s.io/apimachinery/pkg/types"
+  k8sfake "k8s.io/client-go/kubernetes/fake"
+  danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
+  "github.com/nokia/danm/crd/client/clientset/versioned/fake"
+  "github.com/nokia/danm/pkg/cnierrors"
+  "github.com/nokia/danm/pkg/danmep"
+  "github.com/nokia/danm/pkg/datastructs"
+  "github.com/nokia/danm/pkg/epstore"
 )
 
 var devicePool0 = "pool0"
@@ -31,3 +44,195 @@ func TestPopDevice(t *testing.T) {
     t.Errorf("Empty pool should expect error.")
   }
 }
+
+func TestRemoveDevice(t *testing.T) {
+  allocatedDevices := make(map[string]*[]string)
+  removeDevice(devicePool0, "device0", allocatedDevices)
+  allocatedDevices[devicePool1] = &[]string{"device1", "device2", "device3"}
+  removeDevice(devicePool1, "device2", allocatedDevices)
+  removeDevice(devicePool1, "device4", allocatedDevices)
+  if len(*allocatedDevices[devicePool1]) != 2 {
+    t.Errorf("Number of remaining devices:%d does not match with expectation.", len(*allocatedDevices[devicePool1]))
+  }
+  device,err := popDevice(devicePool1, allocatedDevices)
+  if device != "device3"  || err != nil {
+    t.Errorf("Received device or error does not match with expectation.")
+  }
+  device,err = popDevice(devicePool1, allocatedDevices)
+  if device != "device1"  || err != nil {
+    t.Errorf("Received device or error does not match with expectation.")
+  }
+}
+
+func TestPopExistingEp(t *testing.T) {
+  args := &datastructs.CniArgs {
+    ExistingEps: []danmtypes.DanmEp {
+      {ObjectMeta: meta_v1.ObjectMeta{Name: "ep1"}, Spec: danmtypes.DanmEpSpec{NetworkName: "net1", ApiType: "DanmNet"}},
+      {ObjectMeta: meta_v1.ObjectMeta{Name: "ep2"}, Spec: danmtypes.DanmEpSpec{NetworkName: "net1", ApiType: "ClusterNetwork"}},
+      {ObjectMeta: meta_v1.ObjectMeta{Name: "ep3"}, Spec: danmtypes.DanmEpSpec{NetworkName: "net1", ApiType: "DanmNet"}},
+    },
+  }
+  dnet := &danmtypes.DanmNet{TypeMeta: meta_v1.TypeMeta{Kind: "DanmNet"}, ObjectMeta: meta_v1.ObjectMeta{Name: "net1"}}
+  cnet := &danmtypes.DanmNet{TypeMeta: meta_v1.TypeMeta{Kind: "ClusterNetwork"}, ObjectMeta: meta_v1.ObjectMeta{Name: "net1"}}
+  tnet := &danmtypes.DanmNet{TypeMeta: meta_v1.TypeMeta{Kind: "TenantNetwork"}, ObjectMeta: meta_v1.ObjectMeta{Name: "net1"}}
+  expectedEps := []struct {
+    netInfo *danmtypes.DanmNet
+    epName string
+  } {
+    {tnet, ""}, {dnet, "ep1"}, {cnet, "ep2"}, {dnet, "ep3"}, {dnet, ""}, {cnet, ""},
+  }
+  for _, expected := range expectedEps {
+    ep := popExistingEp(args, expected.netInfo)
+    if (ep == nil && expected.epName != "") || (ep != nil && ep.ObjectMeta.Name != expected.epName) {
+      t.Errorf("Received DanmEp:%v does not match with the expected:%s", ep, expected.epName)
+    }
+  }
+}
+
+func TestCreateFailureEvent(t *testing.T) {
+  pod := &corev1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: "pod", Namespace: "ns", UID: "uid"}}
+  event := createFailureEvent(pod, "node", "dnet", cnierrors.New(cnierrors.ErrTenantNotAllowed, "namespace is not allowed"))
+  if event.InvolvedObject.Kind != "Pod" || event.InvolvedObject.Name != "pod" || event.InvolvedObject.UID != "uid" || event.ObjectMeta.Namespace != "ns" {
+    t.Errorf("Event does not refer to the failed Pod:%v", event.InvolvedObject)
+  }
+  if event.Type != corev1.EventTypeWarning || event.Reason != "TenantNotAllowed" {
+    t.Errorf("Event type:%s, or reason:%s does not match with the expected Warning, TenantNotAllowed", event.Type, event.Reason)
+  }
+  if !strings.Contains(event.Message, "dnet") || !strings.Contains(event.Message, "namespace is not allowed") {
+    t.Errorf("Event message:%s does not name the network and the cause of the failure", event.Message)
+  }
+}
+
+var validateBondTcs = []struct {
+  tcName string
+  bond datastructs.Bond
+  isErrorExpected bool
+}{
+  {"validBond", datastructs.Bond{Mode: "802.3ad", Slaves: []datastructs.Interface{{Network: "net1"}, {ClusterNetwork: "net2", Ip: "none"}}}, false},
+  {"unsupportedMode", datastructs.Bond{Mode: "balance-rr", Slaves: []datastructs.Interface{{Network: "net1"}}}, true},
+  {"negativeMiimon", datastructs.Bond{Miimon: -1, Slaves: []datastructs.Interface{{Network: "net1"}}}, true},
+  {"noSlaves", datastructs.Bond{}, true},
+  {"slaveWithoutNetwork", datastructs.Bond{Slaves: []datastructs.Interface{{}}}, true},
+  {"slaveWithTwoNetworks", datastructs.Bond{Slaves: []datastructs.Interface{{Network: "net1", TenantNetwork: "net2"}}}, true},
+  {"nestedBond", datastructs.Bond{Slaves: []datastructs.Interface{{Network: "net1", Bond: &datastructs.Bond{}}}}, true},
+  {"slaveWithIp", datastructs.Bond{Slaves: []datastructs.Interface{{Network: "net1", Ip: "dynamic"}}}, true},
+  {"slaveWithIp6", datastructs.Bond{Slaves: []datastructs.Interface{{Network: "net1", Ip6: "dynamic"}}}, true},
+}
+
+func TestValidateBond(t *testing.T) {
+  for _, tc := range validateBondTcs {
+    t.Run(tc.tcName, func(t *testing.T) {
+      err := validateBond(0, &tc.bond)
+      if (err != nil && !tc.isErrorExpected) || (err == nil && tc.isErrorExpected) {
+        t.Errorf("Received error:%v does not match with expectation", err)
+      }
+    })
+  }
+}
+
+func TestExpandBondSlaves(t *testing.T) {
+  ifaces := []datastructs.Interface {
+    {Network: "net0"},
+    {Network: "bondnet1", Bond: &datastructs.Bond{Slaves: []datastructs.Interface{{Network: "slave1"}, {Network: "slave2"}}}},
+    {Network: "bondnet2", Bond: &datastructs.Bond{Mode: "802.3ad", Miimon: 50, Slaves: []datastructs.Interface{{Network: "slave3"}}}},
+  }
+  ifaces = expandBondSlaves(ifaces)
+  if len(ifaces) != 6 {
+    t.Fatalf("Number of connections after expanding the bond slaves:%d does not match with the expected 6", len(ifaces))
+  }
+  for slaveId, expectedSeqId := range []int{3, 4} {
+    if ifaces[expectedSeqId].Network != ifaces[1].Bond.Slaves[slaveId].Network || ifaces[1].Bond.Slaves[slaveId].SequenceId != expectedSeqId {
+      t.Errorf("Slave no.:%d of the first bond was not appended as connection no.:%d", slaveId, expectedSeqId)
+    }
+  }
+  if ifaces[5].Network != "slave3" || ifaces[2].Bond.Slaves[0].SequenceId != 5 {
+    t.Errorf("Slave of the second bond was not appended as connection no.:5")
+  }
+  if ifaces[1].Bond.Mode != danmep.BondModeActiveBackup || ifaces[1].Bond.Miimon != danmep.DefaultBondMiimon {
+    t.Errorf("Defaults of the bond:%v were not set", ifaces[1].Bond)
+  }
+  if ifaces[2].Bond.Mode != "802.3ad" || ifaces[2].Bond.Miimon != 50 {
+    t.Errorf("Explicitly set mode, and miimon of the bond:%v were overwritten", ifaces[2].Bond)
+  }
+  bonds := getBonds(ifaces)
+  if len(bonds) != 2 || bonds[0].SequenceId != 1 || bonds[1].SequenceId != 2 {
+    t.Errorf("Bonds:%v were not collected with their sequence IDs", bonds)
+  }
+}
+
+func TestBondsAreDeletedFirst(t *testing.T) {
+  bond := &danmtypes.DanmEpBond{}
+  eps := []danmtypes.DanmEp {
+    {ObjectMeta: meta_v1.ObjectMeta{Name: "slave1"}},
+    {ObjectMeta: meta_v1.ObjectMeta{Name: "bond1"}, Spec: danmtypes.DanmEpSpec{Iface: danmtypes.DanmEpIface{Bond: bond}}},
+    {ObjectMeta: meta_v1.ObjectMeta{Name: "slave2"}},
+    {ObjectMeta: meta_v1.ObjectMeta{Name: "bond2"}, Spec: danmtypes.DanmEpSpec{Iface: danmtypes.DanmEpIface{Bond: bond}}},
+  }
+  bondEps, otherEps := splitBondEps(eps)
+  if len(bondEps) != 2 || bondEps[0].ObjectMeta.Name != "bond1" || bondEps[1].ObjectMeta.Name != "bond2" {
+    t.Errorf("Bond DanmEps:%v were not separated from the rest", bondEps)
+  }
+  if len(otherEps) != 2 || otherEps[0].ObjectMeta.Name != "slave1" || otherEps[1].ObjectMeta.Name != "slave2" {
+    t.Errorf("Slave DanmEps:%v were not separated from the bonds", otherEps)
+  }
+  var recs []epstore.Record
+  for _, ep := range eps {
+    recs = append(recs, epstore.Record{Ep: ep})
+  }
+  sortBondRecsFirst(recs)
+  for recId, expectedName := range []string{"bond1", "bond2", "slave1", "slave2"} {
+    if recs[recId].Ep.ObjectMeta.Name != expectedName {
+      t.Errorf("Record no.:%d of the offline deletion is:%s instead of the expected:%s", recId, recs[recId].Ep.ObjectMeta.Name, expectedName)
+    }
+  }
+}
+
+func TestDeleteUnclaimedEps(t *testing.T) {
+  unclaimedEp := &danmtypes.DanmEp{ObjectMeta: meta_v1.ObjectMeta{Name: "unclaimed", Namespace: "ns"}, Spec: danmtypes.DanmEpSpec{CID: "cid", NetworkName: "removed", ApiType: "DanmNet", Iface: danmtypes.DanmEpIface{Name: "eth1"}}}
+  claimedEp := &danmtypes.DanmEp{ObjectMeta: meta_v1.ObjectMeta{Name: "claimed", Namespace: "ns"}, Spec: danmtypes.DanmEpSpec{CID: "cid", NetworkName: "kept", ApiType: "DanmNet", Iface: danmtypes.DanmEpIface{Name: "eth0"}}}
+  client := fake.NewSimpleClientset(unclaimedEp, claimedEp)
+  args := &datastructs.CniArgs{PodName: "pod", ExistingEps: []danmtypes.DanmEp{*unclaimedEp}, NetConf: &datastructs.NetConf{StoreDir: t.TempDir()}}
+  deleteUnclaimedEps(context.TODO(), client, args)
+  if len(args.ExistingEps) != 0 {
+    t.Errorf("Unclaimed DanmEps:%v were left in the arguments of the ADD", args.ExistingEps)
+  }
+  eps, _ := client.DanmV1().DanmEps("ns").List(context.TODO(), meta_v1.ListOptions{})
+  if len(eps.Items) != 1 || eps.Items[0].ObjectMeta.Name != "claimed" {
+    t.Errorf("Remaining DanmEps:%v do not match with the claimed one", eps.Items)
+  }
+}
+
+func TestDeleteWithInvalidRuntimeConfig(t *testing.T) {
+  args := &skel.CmdArgs{ContainerID: "cid", StdinData: []byte(`{"cniVersion":"0.3.1","name":"danm","type":"danm","runtimeConfig":"invalid"}`)}
+  err := DeleteInterfaces(context.TODO(), args)
+  if err != nil {
+    t.Errorf("DEL with an invalid runtimeConfig was expected to succeed, but failed with:%v", err)
+  }
+}
+
+func TestGetLiveCids(t *testing.T) {
+  livePod := &corev1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: "live", Namespace: "ns", UID: "liveuid"}}
+  recreatedPod := &corev1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: "recreated", Namespace: "ns", UID: "newuid"}}
+  k8sClient := k8sfake.NewSimpleClientset(livePod, recreatedPod)
+  eps := []*danmtypes.DanmEp {
+    newCleanupTestEp("livecid", "host1", "live", "liveuid"),
+    newCleanupTestEp("livecid", "host1", "live", "liveuid"),
+    newCleanupTestEp("otherhostcid", "host2", "live", "liveuid"),
+    newCleanupTestEp("deletedcid", "host1", "deleted", "deleteduid"),
+    newCleanupTestEp("recreatedcid", "host1", "recreated", "olduid"),
+  }
+  liveCids, err := getLiveCids(k8sClient, "host1", eps)
+  if err != nil {
+    t.Fatalf("live containers could not be determined because:%v", err)
+  }
+  if len(liveCids) != 1 || !liveCids["livecid"] {
+    t.Errorf("Live containers:%v do not match with the expected:[livecid]", liveCids)
+  }
+}
+
+func newCleanupTestEp(cid, host, podName, podUid string) *danmtypes.DanmEp {
+  return &danmtypes.DanmEp {
+    ObjectMeta: meta_v1.ObjectMeta{Name: cid + "-" + podName, Namespace: "ns"},
+    Spec: danmtypes.DanmEpSpec{CID: cid, Host: host, Pod: podName, PodUID: types.UID(podUid)},
+  }
+}
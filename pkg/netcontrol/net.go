@@ -3,7 +3,10 @@ package netcontrol
 import (
   "errors"
   "net"
+  "os"
+  "os/exec"
   "strconv"
+  "strings"
   "syscall"
   "github.com/apparentlymart/go-cidr/cidr"
   "github.com/vishvananda/netlink"
@@ -15,6 +18,11 @@ const (
   ip6MulticastCidr = "ff02::0/16"
   maxVlanId = 4094
   maxVxlanId = 16777214
+  bridgeNetworkType = "bridge"
+  ovsNetworkType = "ovs"
+  vlanNetworkType = "vlan"
+  defaultOvsVsctl = "ovs-vsctl"
+  vxlanOverhead = 50
 )
 
 // LinkInfo is an absract struct to represent a host NIC of a special type: either VLAN, or VxLAN
@@ -25,15 +33,21 @@ type LinkInfo struct {
 }
 
 func deleteNetworks(dnet *danmtypes.DanmNet) error {
-  if dnet.Spec.Options.Device == "" {
+  //OVS bridges can be shared by multiple networks, so they are left intact
+  if dnet.Spec.Options.Device == "" || isOvsNetwork(dnet) || isVlanNetwork(dnet) {
     return nil
   }
   var combinedErrorMessage string
+  //The bridge goes first, so the VLAN, or VxLAN interface is not enslaved anymore when it gets deleted
+  tempErr := deleteBridge(dnet)
+  if tempErr != nil {
+    combinedErrorMessage = tempErr.Error() + "\n"
+  }
   vxlanId := dnet.Spec.Options.Vxlan
   netId := dnet.Spec.NetworkID
-  tempErr := deleteHostInterface(vxlanId, "vx_" + netId)
+  tempErr = deleteHostInterface(vxlanId, "vx_" + netId)
   if tempErr != nil {
-    combinedErrorMessage = tempErr.Error() + "\n"
+    combinedErrorMessage += tempErr.Error() + "\n"
   }
   vlanId := dnet.Spec.Options.Vlan
   tempErr = deleteHostInterface(vlanId, determineVlanHdev(vlanId, netId, dnet.Spec.Options.Device))
@@ -65,19 +79,117 @@ func setupHost(dnet *danmtypes.DanmNet) error {
   if dnet.Spec.Options.Device == "" {
     return nil
   }
+  //The VLAN of OVS networks is a port tag, so the bridge is the only host resource needed
+  if isOvsNetwork(dnet) {
+    return setupOvsBridge(dnet)
+  }
+  //VLAN sub-interfaces of vlan networks are created directly inside the Pods
+  if isVlanNetwork(dnet) {
+    return nil
+  }
   netId := dnet.Spec.NetworkID
   hdev := dnet.Spec.Options.Device
   vxlanId := dnet.Spec.Options.Vxlan
   vlanId := dnet.Spec.Options.Vlan
   // Nothing to do here
   if vxlanId == 0 && vlanId == 0 {
-    return nil
+    return setupBridge(dnet)
   }
   err := setupVlan(vlanId, netId, hdev)
   if err != nil {
     return err
   }
-  return setupVxlan(vxlanId, netId, hdev)
+  err = setupVxlan(vxlanId, netId, hdev)
+  if err != nil {
+    return err
+  }
+  err = setupHostDeviceMtu(dnet)
+  if err != nil {
+    return err
+  }
+  return setupBridge(dnet)
+}
+
+//Dynamic bridge networks get their own host bridge, with the host device, or the VLAN, VxLAN interface of the network enslaved to it
+func setupBridge(dnet *danmtypes.DanmNet) error {
+  if !strings.EqualFold(dnet.Spec.NetworkType, bridgeNetworkType) {
+    return nil
+  }
+  brName := DetermineBridgeName(dnet)
+  hdevName := DetermineHostDeviceName(dnet)
+  hdev, err := netlink.LinkByName(hdevName)
+  if err != nil {
+    return errors.New("cannot set-up host bridge:" + brName + ", because its host device:" + hdevName + " is not present in the system")
+  }
+  bridge, err := netlink.LinkByName(brName)
+  if err != nil {
+    bridge = &netlink.Bridge {
+      LinkAttrs: netlink.LinkAttrs {
+        Name: brName,
+      },
+    }
+    err = addLink(bridge)
+    if err != nil {
+      return errors.New("cannot add bridge:" + brName + " to the host due to:" + err.Error())
+    }
+  }
+  err = netlink.LinkSetMaster(hdev, bridge)
+  if err != nil {
+    return errors.New("cannot enslave host device:" + hdevName + " to bridge:" + brName + " due to:" + err.Error())
+  }
+  //The MTU of a bridge cannot be larger than the MTU of its ports, so it can only be set after the host device was enslaved
+  if dnet.Spec.Options.MTU != 0 {
+    err = netlink.LinkSetMTU(bridge, dnet.Spec.Options.MTU)
+    if err != nil {
+      return errors.New("cannot set the MTU of bridge:" + brName + " due to:" + err.Error())
+    }
+  }
+  if dnet.Spec.Options.PromiscMode {
+    err = netlink.SetPromiscOn(bridge)
+    if err != nil {
+      return errors.New("cannot put bridge:" + brName + " into promiscuous mode due to:" + err.Error())
+    }
+  }
+  return nil
+}
+
+func deleteBridge(dnet *danmtypes.DanmNet) error {
+  if !strings.EqualFold(dnet.Spec.NetworkType, bridgeNetworkType) {
+    return nil
+  }
+  brName := DetermineBridgeName(dnet)
+  bridge, err := netlink.LinkByName(brName)
+  if err != nil {
+    return nil
+  }
+  err = netlink.LinkDel(bridge)
+  if err != nil {
+    return errors.New("Deletion of bridge:" + brName + " failed with error:"+err.Error())
+  }
+  return nil
+}
+
+func isOvsNetwork(dnet *danmtypes.DanmNet) bool {
+  return strings.EqualFold(dnet.Spec.NetworkType, ovsNetworkType)
+}
+
+func isVlanNetwork(dnet *danmtypes.DanmNet) bool {
+  return strings.EqualFold(dnet.Spec.NetworkType, vlanNetworkType)
+}
+
+//The host_device of OVS networks is the OVS bridge itself, which is added via ovs-vsctl unless it already exists
+//The ovs-vsctl binary can be overwritten via the OVS_VSCTL_PATH environment variable
+func setupOvsBridge(dnet *danmtypes.DanmNet) error {
+  ovsVsctl := os.Getenv("OVS_VSCTL_PATH")
+  if ovsVsctl == "" {
+    ovsVsctl = defaultOvsVsctl
+  }
+  brName := dnet.Spec.Options.Device
+  output, err := exec.Command(ovsVsctl, "--may-exist", "add-br", brName).CombinedOutput()
+  if err != nil {
+    return errors.New("cannot add OVS bridge:" + brName + " to the host due to:" + err.Error() + ", output:" + strings.TrimSpace(string(output)))
+  }
+  return nil
 }
 
 func setupVlan(vlanId int, netId, hdev string) error {
@@ -102,6 +214,39 @@ func setupVlan(vlanId int, netId, hdev string) error {
   return nil
 }
 
+// setupHostDeviceMtu makes sure the VLAN, or VxLAN host interface of the network can hold the MTU requested for its Pod interfaces
+// The MTU of the VLAN, or VxLAN is only ever increased, as the interface might be shared between multiple networks
+func setupHostDeviceMtu(dnet *danmtypes.DanmNet) error {
+  mtu := dnet.Spec.Options.MTU
+  if mtu == 0 {
+    return nil
+  }
+  ifName := DetermineHostDeviceName(dnet)
+  link, err := netlink.LinkByName(ifName)
+  if err != nil {
+    return errors.New("cannot set the MTU of host interface:" + ifName + " because it does not exist")
+  }
+  if link.Attrs().MTU >= mtu {
+    return nil
+  }
+  hdev, err := netlink.LinkByName(dnet.Spec.Options.Device)
+  if err != nil {
+    return errors.New("host device:" + dnet.Spec.Options.Device + " is not present in the system")
+  }
+  requiredHdevMtu := mtu
+  if dnet.Spec.Options.Vxlan != 0 {
+    requiredHdevMtu += vxlanOverhead
+  }
+  if hdev.Attrs().MTU < requiredHdevMtu {
+    return errors.New("MTU:" + strconv.Itoa(mtu) + " cannot be set on host interface:" + ifName + " because the MTU of host device:" + dnet.Spec.Options.Device + " is only:" + strconv.Itoa(hdev.Attrs().MTU))
+  }
+  err = netlink.LinkSetMTU(link, mtu)
+  if err != nil {
+    return errors.New("cannot set the MTU of host interface:" + ifName + " due to:" + err.Error())
+  }
+  return nil
+}
+
 func shouldInterfaceBeCreated(ifId int, ifName string, hostDevice string) (bool, LinkInfo, error) {
   hostLink := LinkInfo{}
   if ifId == 0 {
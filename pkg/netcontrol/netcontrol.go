@@ -396,7 +396,7 @@ func (netWatcher *NetWatcher) AddNad(obj interface{}) {
   //TODO: on one hand this would make much more sense to be done in an admission controller, on the other one it makes sense for netwatcher to be self-containing
   //      Let's see if this causes issues in production. A random initial Pod restart here and there when the network and a Pod using it are created the same time we can live with IMO
   if dnet.Spec.Options.Vlan != 0 || dnet.Spec.Options.Vxlan != 0 {
-    nad.Spec.Config = string(PatchCniConf([]byte(nad.Spec.Config), "master", DetermineHostDeviceName(dnet)))
+    nad.Spec.Config = string(patchNadConf([]byte(nad.Spec.Config), "master", DetermineHostDeviceName(dnet)))
     _, err = netWatcher.NadClient.K8sCniCncfIoV1().NetworkAttachmentDefinitions(nad.ObjectMeta.Namespace).Update(context.TODO(), nad, meta_v1.UpdateOptions{})
     if err != nil {
       log.Println("INFO: Could not update NetworkAttachmentDefinition:" + nad.ObjectMeta.Name + " with the new parent interface name because:" + err.Error())
@@ -436,7 +436,7 @@ func (netWatcher *NetWatcher) UpdateNad(oldObj, newObj interface{}) {
     log.Println("INFO: Creating host interfaces for modified NetworkAttachmentDefinition:" + newNad.ObjectMeta.Name + " after update failed with error:" + err.Error())
   }
   if parentUpdateNeeded {
-    newNad.Spec.Config = string(PatchCniConf([]byte(newNad.Spec.Config), "master", DetermineHostDeviceName(newdDn)))
+    newNad.Spec.Config = string(patchNadConf([]byte(newNad.Spec.Config), "master", DetermineHostDeviceName(newdDn)))
     _, err = netWatcher.NadClient.K8sCniCncfIoV1().NetworkAttachmentDefinitions(newNad.ObjectMeta.Namespace).Update(context.TODO(), newNad, meta_v1.UpdateOptions{})
     if err != nil {
       log.Println("INFO: Could not update NetworkAttachmentDefinition:" + newNad.ObjectMeta.Name + " with the new parent interface name because:" + err.Error())
@@ -502,12 +502,19 @@ func convertNadToDnet(nad *nadtypes.NetworkAttachmentDefinition) (*danmtypes.Dan
   if err != nil {
     return &dnet, errors.New("could not parse CNI config from Nad.Spec.Config into delegate type because:" + err.Error())
   }
-  //TODO: support conflist type
-  if delegateConf.Conf.Type == "" {
+  rawPluginConf := []byte(nad.Spec.Config)
+  if delegateConf.ConfListPlugin {
+    pluginConfs, err := GetPluginConfsFromConfList(rawPluginConf)
+    if err != nil {
+      return &dnet, errors.New("could not split CNI config list of Nad.Spec.Config into plugin configs because:" + err.Error())
+    }
+    //Host interfaces are created for the first plugin of the chain, the ones after it are expected to only fine-tune what it created
+    rawPluginConf = pluginConfs[0]
+  } else if delegateConf.Conf.Type == "" {
     return &dnet, nil
   }
   var netConf datastructs.NetConf
-  err = json.Unmarshal([]byte(nad.Spec.Config), &netConf)
+  err = json.Unmarshal(rawPluginConf, &netConf)
   if err != nil {
     return &dnet, errors.New("could not parse CNI config from Nad.Spec.Config into netconf type because:" + err.Error())
   }
@@ -540,17 +547,17 @@ func ConvertDnetToCnet(dnet *danmtypes.DanmNet) *danmtypes.ClusterNetwork {
   }
 }
 
-func PutNetwork(danmClient danmclientset.Interface, dnet *danmtypes.DanmNet) (bool,error) {
+func PutNetwork(ctx context.Context, danmClient danmclientset.Interface, dnet *danmtypes.DanmNet) (bool,error) {
   var err error
   var wasResourceAlreadyUpdated bool
   if dnet.TypeMeta.Kind == DanmNetKind || dnet.TypeMeta.Kind == "" {
-    _, err = danmClient.DanmV1().DanmNets(dnet.ObjectMeta.Namespace).Update(context.TODO(), dnet, meta_v1.UpdateOptions{})
+    _, err = danmClient.DanmV1().DanmNets(dnet.ObjectMeta.Namespace).Update(ctx, dnet, meta_v1.UpdateOptions{})
   } else if dnet.TypeMeta.Kind == TenantNetworkKind {
     tn := ConvertDnetToTnet(dnet)
-    _, err = danmClient.DanmV1().TenantNetworks(dnet.ObjectMeta.Namespace).Update(context.TODO(), tn, meta_v1.UpdateOptions{})
+    _, err = danmClient.DanmV1().TenantNetworks(dnet.ObjectMeta.Namespace).Update(ctx, tn, meta_v1.UpdateOptions{})
   } else if dnet.TypeMeta.Kind == ClusterNetworkKind {
     cn := ConvertDnetToCnet(dnet)
-    _, err = danmClient.DanmV1().ClusterNetworks().Update(context.TODO(), cn, meta_v1.UpdateOptions{})
+    _, err = danmClient.DanmV1().ClusterNetworks().Update(ctx, cn, meta_v1.UpdateOptions{})
   } else {
     return wasResourceAlreadyUpdated, errors.New("can't refresh network object because it has an invalid type:" + dnet.TypeMeta.Kind)
   }
@@ -643,6 +650,12 @@ func DetermineHostDeviceName(dnet *danmtypes.DanmNet) string {
   return device
 }
 
+// DetermineBridgeName returns the name of the host bridge DANM creates for dynamic bridge networks
+// Every network gets its own bridge, as the host device, VLAN, or VxLAN interface of the network can only be enslaved to one
+func DetermineBridgeName(dnet *danmtypes.DanmNet) string {
+  return "br_" + dnet.Spec.NetworkID
+}
+
 func PatchCniConf(rawConf []byte, patchKey string, patchValue interface{}) []byte {
   transparentCniConf := map[string]interface{}{}
   json.Unmarshal(rawConf, &transparentCniConf)
@@ -651,6 +664,54 @@ func PatchCniConf(rawConf []byte, patchKey string, patchValue interface{}) []byt
   return moddedCniConf
 }
 
+// GetPluginConfsFromConfList splits a CNI network configuration list into the configs of its plugins, in the order of their invocation
+// The name, and cniVersion of the list is injected into every plugin config, the same way libcni does it before invoking a plugin of a chain
+func GetPluginConfsFromConfList(rawConfList []byte) ([][]byte, error) {
+  var confList struct {
+    Name       string                   `json:"name"`
+    CNIVersion string                   `json:"cniVersion"`
+    Plugins    []map[string]interface{} `json:"plugins"`
+  }
+  err := json.Unmarshal(rawConfList, &confList)
+  if err != nil {
+    return nil, errors.New("CNI config list could not be parsed because:" + err.Error())
+  }
+  if len(confList.Plugins) == 0 {
+    return nil, errors.New("CNI config list:" + confList.Name + " does not contain any plugins")
+  }
+  pluginConfs := make([][]byte, 0, len(confList.Plugins))
+  for index, plugin := range confList.Plugins {
+    if pluginType, _ := plugin["type"].(string); pluginType == "" {
+      return nil, errors.New("plugin no.:" + strconv.Itoa(index) + " of CNI config list:" + confList.Name + " does not have a type")
+    }
+    plugin["name"] = confList.Name
+    plugin["cniVersion"] = confList.CNIVersion
+    pluginConf, err := json.Marshal(plugin)
+    if err != nil {
+      return nil, errors.New("config of plugin no.:" + strconv.Itoa(index) + " of CNI config list:" + confList.Name + " could not be marshalled because:" + err.Error())
+    }
+    pluginConfs = append(pluginConfs, pluginConf)
+  }
+  return pluginConfs, nil
+}
+
+//In case of config lists it is the first plugin of the chain which connects to the parent interface, so that is the one getting patched
+func patchNadConf(rawConf []byte, patchKey string, patchValue interface{}) []byte {
+  transparentConfList := map[string]interface{}{}
+  json.Unmarshal(rawConf, &transparentConfList)
+  plugins, isConfList := transparentConfList["plugins"].([]interface{})
+  if !isConfList || len(plugins) == 0 {
+    return PatchCniConf(rawConf, patchKey, patchValue)
+  }
+  firstPlugin, isPluginValid := plugins[0].(map[string]interface{})
+  if !isPluginValid {
+    return rawConf
+  }
+  firstPlugin[patchKey] = patchValue
+  moddedConfList,_ := json.Marshal(transparentConfList)
+  return moddedConfList
+}
+
 //Little trickery: if there was no change in the VNI+host_device combo during the update we set it to 0 in the manifests.
 //Thus we avoid unnecessarily recreating host interfaces.
 func zeroVnis(oldDn, newDn *danmtypes.DanmNet) {
@@ -419,7 +419,7 @@ func (c *Controller) updateDanmep(old, new interface{}) {
 	glog.V(5).Infof("updateDanmep is called: %s %s", new.(*danmv1.DanmEp).GetName(), new.(*danmv1.DanmEp).GetNamespace())
 	oldDanmEp := old.(*danmv1.DanmEp)
 	newDanmEp := new.(*danmv1.DanmEp)
-	if oldDanmEp.ResourceVersion == newDanmEp.ResourceVersion {
+	if oldDanmEp.ResourceVersion == newDanmEp.ResourceVersion || !DanmEpChanged(oldDanmEp, newDanmEp) {
 		return
 	}
 	c.delDanmep(old)
@@ -130,6 +130,16 @@ func PodLabelChanged(oldPod, newPod *corev1.Pod) bool {
 	return true
 }
 
+// DanmEpChanged tells whether an update of a DanmEp is relevant for Endpoints
+// Status updates written periodically by the DANM agent only change the resourceVersion, but not the Spec, or the labels
+func DanmEpChanged(oldDe, newDe *danmv1.DanmEp) bool {
+	if reflect.DeepEqual(oldDe.Spec, newDe.Spec) && reflect.DeepEqual(oldDe.GetLabels(), newDe.GetLabels()) {
+		// no change
+		return false
+	}
+	return true
+}
+
 func MatchExistingSvc(de *danmv1.DanmEp, servicesList []*corev1.Service) []*corev1.Service {
 	deNs := de.Namespace
 	var svcList []*corev1.Service
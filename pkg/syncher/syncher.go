@@ -1,19 +1,22 @@
 package syncher
 
 import (
+  "context"
   "errors"
-  "fmt"
   "strconv"
   "strings"
   "sync"
   "time"
+  "github.com/containernetworking/cni/pkg/types"
   "github.com/containernetworking/cni/pkg/types/current"
+  "github.com/nokia/danm/pkg/cnierrors"
 )
 
 const (
   MaximumAllowedTime = 3000  // Timeout = MaximumAllowedTime * RetryInterval[ms] = 30s
   RetryInterval = 10         // [ms]
   DefaultIfName = "eth0"
+  DefaultTimeout = MaximumAllowedTime * RetryInterval * time.Millisecond
 )
 
 type cniOpResult struct {
@@ -35,6 +38,13 @@ func NewSyncher(numOfResults int) *Syncher {
   return &syncher
 }
 
+// AddExpectedResults increases the number of results to wait for, e.g. when interfaces are created in multiple rounds
+func (synch *Syncher) AddExpectedResults(numOfResults int) {
+  synch.mux.Lock()
+  defer synch.mux.Unlock()
+  synch.ExpectedNumOfResults += numOfResults
+}
+
 func (synch *Syncher) PushResult(cniName string, opRes error, cniRes *current.Result, ifName string) {
   synch.mux.Lock()
   defer synch.mux.Unlock()
@@ -49,17 +59,48 @@ func (synch *Syncher) PushResult(cniName string, opRes error, cniRes *current.Re
 
 func (synch *Syncher) GetAggregatedResult() error {
   //Time-out Pod creation if plugins did not provide results within the configured timeframe
-  for i := 0; i < MaximumAllowedTime; i++ {
-    if synch.ExpectedNumOfResults > len(synch.CniResults) {
-      time.Sleep(RetryInterval * time.Millisecond)
-      continue
-    }
-    if synch.wasAnyOperationErroneous() {
-      return synch.mergeErrorMessages()
+  ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+  defer cancel()
+  err := synch.GetAggregatedResultWithContext(ctx)
+  if err == context.DeadlineExceeded {
+    return errors.New("CNI operation timed-out after " + strconv.Itoa(MaximumAllowedTime * RetryInterval / 1000) + " seconds")
+  }
+  return err
+}
+
+// GetAggregatedResultWithContext waits until all the expected results arrive, or the context is done
+// In the latter case the error of the context is returned, and the operations still running are expected to be cancelled via the same context
+func (synch *Syncher) GetAggregatedResultWithContext(ctx context.Context) error {
+  ticker := time.NewTicker(RetryInterval * time.Millisecond)
+  defer ticker.Stop()
+  for !synch.areAllResultsReceived() {
+    select {
+    case <-ctx.Done():
+      return ctx.Err()
+    case <-ticker.C:
     }
-    return nil
   }
-  return errors.New("CNI operation timed-out after " + strconv.Itoa(MaximumAllowedTime * RetryInterval / 1000) + " seconds")
+  synch.mux.Lock()
+  defer synch.mux.Unlock()
+  if synch.wasAnyOperationErroneous() {
+    return synch.mergeErrorMessages()
+  }
+  return nil
+}
+
+// WaitForAllResults blocks until all the expected results arrive, but at most for the provided duration
+// It is used after a time-out, so cancelled operations get the chance to roll back their changes before the process exits
+func (synch *Syncher) WaitForAllResults(timeout time.Duration) bool {
+  ctx, cancel := context.WithTimeout(context.Background(), timeout)
+  defer cancel()
+  err := synch.GetAggregatedResultWithContext(ctx)
+  return err != context.DeadlineExceeded
+}
+
+func (synch *Syncher) areAllResultsReceived() bool {
+  synch.mux.Lock()
+  defer synch.mux.Unlock()
+  return synch.ExpectedNumOfResults <= len(synch.CniResults)
 }
 
 func (synch *Syncher) wasAnyOperationErroneous() bool {
@@ -71,14 +112,42 @@ func (synch *Syncher) wasAnyOperationErroneous() bool {
   return false
 }
 
+//The merged error is a typed CNI error: it inherits the code of the failed operations if they all failed for the same reason
+//Its details list the failed networks together with the underlying causes of their failures
 func (synch *Syncher) mergeErrorMessages() error {
-  var aggregatedErrors []string
+  var aggregatedErrors, aggregatedDetails []string
+  var errorCodes = map[uint]bool{}
+  var aggregatedCode uint
+  for _, cniRes := range synch.CniResults {
+    if cniRes.OpResult != nil {
+      aggregatedErrors = append(aggregatedErrors, "CNI operation for network:" + cniRes.CniName + " failed with:" + cnierrors.MessageOf(cniRes.OpResult))
+      details := cnierrors.DetailsOf(cniRes.OpResult)
+      if details == "" {
+        details = cnierrors.Details(cniRes.CniName, nil)
+      }
+      aggregatedDetails = append(aggregatedDetails, details)
+      aggregatedCode = cnierrors.CodeOf(cniRes.OpResult)
+      errorCodes[aggregatedCode] = true
+    }
+  }
+  if len(errorCodes) > 1 {
+    aggregatedCode = cnierrors.ErrMultipleFailures
+  }
+  return types.NewError(aggregatedCode, strings.Join(aggregatedErrors, "\n"), strings.Join(aggregatedDetails, "\n"))
+}
+
+// GetFailedResults returns a copy of the results of the failed operations received so far
+// Operations can still push their results concurrently, so the results must not be read directly
+func (synch *Syncher) GetFailedResults() []cniOpResult {
+  synch.mux.Lock()
+  defer synch.mux.Unlock()
+  var failedResults []cniOpResult
   for _, cniRes := range synch.CniResults {
     if cniRes.OpResult != nil {
-      aggregatedErrors = append(aggregatedErrors, "CNI operation for network:" + cniRes.CniName + " failed with:" + cniRes.OpResult.Error())
+      failedResults = append(failedResults, cniRes)
     }
   }
-  return fmt.Errorf(strings.Join(aggregatedErrors, "\n"))
+  return failedResults
 }
 
 func (synch *Syncher) MergeCniResults() *current.Result {
@@ -109,6 +109,7 @@ mkdir -p /config-out/resources
 # need to link in a few externals
 ln -sf /integration/manifests/cni_plugins/cni_plugins_ds.yaml.tmpl /etc/confd/templates/cni_plugins_ds.yaml.tmpl
 ln -sf /integration/manifests/netwatcher/netwatcher_ds.yaml.tmpl /etc/confd/templates/netwatcher_ds.yaml.tmpl
+ln -sf /integration/manifests/agent/agent_ds.yaml.tmpl /etc/confd/templates/agent_ds.yaml.tmpl
 ln -sf /integration/manifests/webhook/webhook.yaml.tmpl /etc/confd/templates/webhook.yaml.tmpl
 ln -sf /integration/manifests/svcwatcher/svcwatcher_ds.yaml.tmpl /etc/confd/templates/svcwatcher_ds.yaml.tmpl
 
@@ -123,6 +124,9 @@ kubectl apply -f /integration/manifests/cni_plugins
 echo ; echo "Creating NetWatcher DaemonSet..."
 kubectl apply -f /integration/manifests/netwatcher
 
+echo ; echo "Creating DANM agent DaemonSet..."
+kubectl apply -f /integration/manifests/agent
+
 echo ; echo "Creating Webhook..."
 kubectl apply -f /integration/manifests/webhook
 
@@ -26,6 +26,10 @@ func (epClient EpClientStub) Update(ctx context.Context, obj *danmtypes.DanmEp,
   return nil, nil
 }
 
+func (epClient EpClientStub) UpdateStatus(ctx context.Context, obj *danmtypes.DanmEp, options meta_v1.UpdateOptions) (*danmtypes.DanmEp, error) {
+  return nil, nil
+}
+
 func (epClient EpClientStub) Delete(ctx context.Context, name string, options meta_v1.DeleteOptions) error {
   return nil
 }
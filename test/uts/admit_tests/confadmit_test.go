@@ -135,6 +135,18 @@ var (
         "flannel": "flannel",
        },
     },
+    danmtypes.TenantConfig {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "invalid-rtables"},TypeMeta: meta_v1.TypeMeta {Kind: "TenantConfig"},
+      RoutingTables: "100-1a0",
+    },
+    danmtypes.TenantConfig {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "reserved-rtables"},TypeMeta: meta_v1.TypeMeta {Kind: "TenantConfig"},
+      RoutingTables: "100-199,254",
+    },
+    danmtypes.TenantConfig {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "valid-rtables"},TypeMeta: meta_v1.TypeMeta {Kind: "TenantConfig"},
+      RoutingTables: "100-199,250",
+    },
   }
 )
 
@@ -166,6 +178,9 @@ var validateTconfTcs = []struct {
   {"longNidWithDynamicNeType", "", "longnid-sriov", "", true, nil},
   {"okayNids", "", "shortnid", "", false, nil},
   {"noChangeInIfaces", "old-iface", "new-iface", v1beta1.Update, false, nil},
+  {"invalidRoutingTables", "", "invalid-rtables", "", true, nil},
+  {"reservedRoutingTables", "", "reserved-rtables", "", true, nil},
+  {"onlyRoutingTables", "", "valid-rtables", "", false, nil},
 }
 
 var (
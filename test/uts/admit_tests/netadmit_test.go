@@ -6,12 +6,14 @@ import (
   "testing"
   "encoding/json"
   danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
+  "github.com/nokia/danm/crd/client/clientset/versioned/fake"
   "github.com/nokia/danm/pkg/admit"
   stubs "github.com/nokia/danm/test/stubs/danm"
   httpstub "github.com/nokia/danm/test/stubs/http"
   "github.com/nokia/danm/test/utils"
   "k8s.io/api/admission/v1beta1"
   meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+  "k8s.io/apimachinery/pkg/runtime"
 )
 
 const (
@@ -150,6 +152,72 @@ var validateNetworkTcs = []struct {
   {"Pool6CidrBiggerThanNet6", "", "pool6-cidr-outside-net6", DnetType, "", nil, nil, true, nil, 0},
   {"InvalidPool6StartAddress", "", "invalid-pool6-start", DnetType, "", nil, nil, true, nil, 0},
   {"Pool6StartAddressMatchesEnd", "", "pool6-end-equals-start", DnetType, "", nil, nil, true, nil, 0},
+  {"ChainedPluginWithPathDNet", "", "chained-path", DnetType, "", nil, nil, true, nil, 0},
+  {"ChainedPluginWithPathTNet", "", "chained-path", TnetType, "", nil, nil, true, nil, 0},
+  {"ChainedPluginWithPathCNet", "", "chained-path", CnetType, "", nil, nil, true, nil, 0},
+  {"ChainedPluginWithoutType", "", "chained-no-type", CnetType, "", nil, nil, true, nil, 0},
+  {"ChainedPluginArgsNotObject", "", "chained-args-list", CnetType, "", nil, nil, true, nil, 0},
+  {"ChainedPluginArgsReserved", "", "chained-args-reserved", CnetType, "", nil, nil, true, nil, 0},
+  {"ChainedPluginsSuccess", "", "chained-valid", CnetType, v1beta1.Create, nil, nil, false, nil, 0},
+  {"TooLongNidWithDynamicBridgeDNet", "", "long-nid-dynamic-bridge", DnetType, "", nil, nil, true, nil, 0},
+  {"TooLongNidWithDynamicBridgeCNet", "", "long-nid-dynamic-bridge", CnetType, "", nil, nil, true, nil, 0},
+  {"LongNidWithStaticBridge", "", "long-nid-static-bridge", CnetType, v1beta1.Create, nil, nil, false, nil, 0},
+  {"HostDevicesWithOtherNeType", "", "host-devices-ipvlan", CnetType, "", nil, nil, true, nil, 0},
+  {"HostDevicesWithDevicePool", "", "host-devices-with-dp", DnetType, "", nil, nil, true, nil, 0},
+  {"HostDevicesDuplicated", "", "host-devices-duplicated", CnetType, "", nil, nil, true, nil, 0},
+  {"HostDevicesInvalidName", "", "host-devices-invalid", CnetType, "", nil, nil, true, nil, 0},
+  {"HostDevicesSuccess", "", "host-devices-valid", CnetType, v1beta1.Create, nil, nil, false, nil, 0},
+  {"HostDevicesInTenantNet", "", "host-devices-valid", TnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"TrunkWithOtherNeType", "", "trunk-macvlan", CnetType, "", nil, nil, true, nil, 0},
+  {"OvsWithVxlan", "", "ovs-vxlan", DnetType, "", nil, nil, true, nil, 0},
+  {"OvsWithVlanAndTrunk", "", "ovs-vlan-trunk", CnetType, "", nil, nil, true, nil, 0},
+  {"OvsTrunkIdAndRange", "", "ovs-trunk-id-range", CnetType, "", nil, nil, true, nil, 0},
+  {"OvsTrunkInvalidRange", "", "ovs-trunk-invalid-range", CnetType, "", nil, nil, true, nil, 0},
+  {"OvsTrunkInvalidId", "", "ovs-trunk-invalid-id", DnetType, "", nil, nil, true, nil, 0},
+  {"OvsTrunkInTenantNet", "", "ovs-trunk-valid", TnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"OvsTrunkSuccess", "", "ovs-trunk-valid", CnetType, v1beta1.Create, nil, nil, false, nil, 0},
+  {"OvsLongNidWithVlan", "", "ovs-long-nid-vlan", DnetType, v1beta1.Create, nil, nil, false, nil, 0},
+  {"MacvlanModeWithOtherNeType", "", "macvlan-mode-ipvlan", DnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"MacvlanModeInvalid", "", "macvlan-mode-invalid", CnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"MacvlanModeSourceWithoutMacs", "", "macvlan-mode-source", DnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"MacvlanSourceMacsInvalid", "", "macvlan-source-invalid-mac", CnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"MacvlanSourceMacsWithOtherMode", "", "macvlan-source-macs-bridge", DnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"MacvlanModeSourceSuccess", "", "macvlan-source-valid", CnetType, v1beta1.Create, nil, nil, false, nil, 0},
+  {"MacvlanModeSuccess", "", "macvlan-mode-valid", CnetType, v1beta1.Create, nil, nil, false, nil, 0},
+  {"IpvlanModeWithOtherNeType", "", "ipvlan-mode-macvlan", DnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"IpvlanModeInvalid", "", "ipvlan-mode-invalid", CnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"IpvlanFlagInvalid", "", "ipvlan-flag-invalid", DnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"IpvlanModeSuccess", "", "ipvlan-mode-valid", CnetType, v1beta1.Create, nil, nil, false, nil, 0},
+  {"OkayToModifyIpvlanModeNoConnectionsDNet", "vniOld", "ipvlanModeNew", DnetType, v1beta1.Update, nil, noMatchDnet, false, nil, 0},
+  {"NotOkayToModifyIpvlanModeDNet", "vniOld", "ipvlanModeNew", DnetType, v1beta1.Update, nil, matchDnet, true, nil, 0},
+  {"VlanNetworkWithoutVlan", "", "vlan-without-id", DnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"VlanNetworkWithDevicePool", "", "vlan-with-dp", CnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"VlanNetworkWithVxlan", "", "vlan-with-vxlan", DnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"VlanNetworkSuccess", "", "vlan-valid", CnetType, v1beta1.Create, nil, nil, false, nil, 0},
+  {"HostVrfWithOtherNeType", "", "host-vrf-ipvlan", DnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"VethNetworkWithDevice", "", "veth-with-device", CnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"VethNetworkWithVrfAndTable", "", "veth-vrf-and-table", DnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"VethNetworkSuccess", "", "veth-valid", CnetType, v1beta1.Create, nil, nil, false, nil, 0},
+  {"NeighAnnounceTooManyGarps", "", "announce-many-garps", DnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"NeighAnnounceNegativeNaInterval", "", "announce-negative-interval", CnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"NeighAnnounceSuccess", "", "announce-valid", DnetType, v1beta1.Create, nil, nil, false, nil, 0},
+  {"VrfWithoutRtTables", "", "vrf-without-table", DnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"VrfWithMainTable", "", "vrf-main-table", CnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"VrfWithInvalidName", "", "vrf-invalid-name", DnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"VrfSuccess", "", "vrf-valid", CnetType, v1beta1.Create, nil, nil, false, nil, 0},
+  {"ExtendedRouteInvalidDst", "", "ext-route-invalid-dst", DnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"ExtendedRouteGwOutsideCidr", "", "ext-route-gw-outside", CnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"ExtendedRouteOnL2Network", "", "ext-route-l2", DnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"ExtendedRouteInvalidScope", "", "ext-route-invalid-scope", CnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"ExtendedRouteTooBigWeight", "", "ext-route-big-weight", DnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"ExtendedRouteEcmpSuccess", "", "ext-route-ecmp", DnetType, v1beta1.Create, nil, nil, false, v6Allocs, 0},
+  {"MtuTooSmall", "", "mtu-too-small", DnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"MtuTooLarge", "", "mtu-too-large", CnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"VfWithOtherNeType", "", "vf-macvlan", DnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"VfQosWithoutVlan", "", "vf-qos-without-vlan", CnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"VfInvalidSpoofChk", "", "vf-invalid-spoofchk", DnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"VfInvalidTxRates", "", "vf-invalid-rates", CnetType, v1beta1.Create, nil, nil, true, nil, 0},
+  {"VfSuccess", "", "vf-valid", DnetType, v1beta1.Create, nil, nil, false, nil, 0},
 }
 
 var (
@@ -373,6 +441,246 @@ var (
       ObjectMeta: meta_v1.ObjectMeta {Name: "pool6-end-equals-start"},
       Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "nanomsg", Options: danmtypes.DanmNetOption{Net6: "2001:db8:85a3::8a2e:370:7334/108", Pool6: danmtypes.IpPoolV6{Cidr: "2001:db8:85a3::8a2e:370:7334/109", IpPool: danmtypes.IpPool{Start: "2001:db8:85a3::8a2e:370:7340", End: "2001:db8:85a3::8a2e:370:7340"}}}},
     },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "chained-path"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "nanomsg", Options: danmtypes.DanmNetOption{Device: "ens3", ChainedPlugins: []danmtypes.ChainedPlugin{{Type: "../../bin/sh"}}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "chained-no-type"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "nanomsg", Options: danmtypes.DanmNetOption{Device: "ens3", ChainedPlugins: []danmtypes.ChainedPlugin{{Type: "tuning"},{}}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "chained-args-list"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "nanomsg", Options: danmtypes.DanmNetOption{Device: "ens3", ChainedPlugins: []danmtypes.ChainedPlugin{{Type: "tuning", Args: runtime.RawExtension{Raw: []byte(`["mtu"]`)}}}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "chained-args-reserved"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "nanomsg", Options: danmtypes.DanmNetOption{Device: "ens3", ChainedPlugins: []danmtypes.ChainedPlugin{{Type: "tuning", Args: runtime.RawExtension{Raw: []byte(`{"type":"bandwidth"}`)}}}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "chained-valid"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "nanomsg", Options: danmtypes.DanmNetOption{Device: "ens3", ChainedPlugins: []danmtypes.ChainedPlugin{{Type: "tuning", Args: runtime.RawExtension{Raw: []byte(`{"mtu":1400}`)}},{Type: "portmap"}}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "long-nid-dynamic-bridge"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "bridge", NetworkID: "abcdeftgasdf", Options: danmtypes.DanmNetOption{Device: "ens3"}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "long-nid-static-bridge"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "bridge", NetworkID: "abcdeftgasdf"},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "host-devices-ipvlan"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "nanomsg", Options: danmtypes.DanmNetOption{HostDevices: []string{"ens3"}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "host-devices-with-dp"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "host-device", NetworkID: "nanomsg", Options: danmtypes.DanmNetOption{DevicePool: "nokia.k8s.io/nics", HostDevices: []string{"ens3"}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "host-devices-duplicated"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "host-device", NetworkID: "nanomsg", Options: danmtypes.DanmNetOption{HostDevices: []string{"ens3","ens4","ens3"}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "host-devices-invalid"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "host-device", NetworkID: "nanomsg", Options: danmtypes.DanmNetOption{HostDevices: []string{"ens3","averyveryverylongname"}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "trunk-macvlan"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "macvlan", NetworkID: "nanomsg", Options: danmtypes.DanmNetOption{Device: "ens3", Trunk: []danmtypes.VlanTrunk{{ID: 42}}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "ovs-vxlan"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ovs", NetworkID: "nanomsg", Options: danmtypes.DanmNetOption{Device: "br-data", Vxlan: 50}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "ovs-vlan-trunk"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ovs", NetworkID: "nanomsg", Options: danmtypes.DanmNetOption{Device: "br-data", Vlan: 50, Trunk: []danmtypes.VlanTrunk{{ID: 42}}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "ovs-trunk-id-range"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ovs", NetworkID: "nanomsg", Options: danmtypes.DanmNetOption{Device: "br-data", Trunk: []danmtypes.VlanTrunk{{ID: 42, MinID: 100, MaxID: 200}}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "ovs-trunk-invalid-range"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ovs", NetworkID: "nanomsg", Options: danmtypes.DanmNetOption{Device: "br-data", Trunk: []danmtypes.VlanTrunk{{MinID: 200, MaxID: 100}}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "ovs-trunk-invalid-id"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ovs", NetworkID: "nanomsg", Options: danmtypes.DanmNetOption{Device: "br-data", Trunk: []danmtypes.VlanTrunk{{ID: 42},{ID: 4095}}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "ovs-trunk-valid"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ovs", NetworkID: "nanomsg", Options: danmtypes.DanmNetOption{Device: "br-data", Trunk: []danmtypes.VlanTrunk{{ID: 42},{MinID: 100, MaxID: 200}}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "ovs-long-nid-vlan"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ovs", NetworkID: "averyveryverylongnid", Options: danmtypes.DanmNetOption{Device: "br-data", Vlan: 50}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "macvlan-mode-ipvlan"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "macvlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", MacvlanMode: "private"}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "macvlan-mode-invalid"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "macvlan", NetworkID: "macvlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", MacvlanMode: "brigde"}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "macvlan-mode-source"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "macvlan", NetworkID: "macvlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", MacvlanMode: "source"}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "macvlan-source-invalid-mac"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "macvlan", NetworkID: "macvlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", MacvlanMode: "source", MacvlanSourceMacs: []string{"fa:16:3e:00:00:01", "fa:16:3e:hu:lu"}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "macvlan-source-macs-bridge"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "macvlan", NetworkID: "macvlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", MacvlanMode: "bridge", MacvlanSourceMacs: []string{"fa:16:3e:00:00:01"}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "macvlan-source-valid"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "macvlan", NetworkID: "macvlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", MacvlanMode: "source", MacvlanSourceMacs: []string{"fa:16:3e:00:00:01", "fa:16:3e:00:00:02"}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "macvlan-mode-valid"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "macvlan", NetworkID: "macvlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", MacvlanMode: "vepa", MTU: 9000}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "ipvlan-mode-macvlan"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "macvlan", NetworkID: "ipvlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", IpvlanMode: "l3"}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "ipvlan-mode-invalid"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "ipvlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", IpvlanMode: "l4"}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "ipvlan-flag-invalid"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "ipvlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", IpvlanFlag: "passthru"}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "ipvlan-mode-valid"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "ipvlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", IpvlanMode: "l3s", IpvlanFlag: "private"}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "ipvlanModeNew", Namespace: "vni-test"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "nanomsg", Options: danmtypes.DanmNetOption{Device: "ens4", Vlan: 50, IpvlanMode: "l3"}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "vlan-without-id"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "vlan", NetworkID: "vlan", Options: danmtypes.DanmNetOption{Device: "ens1f0"}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "vlan-with-dp"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "vlan", NetworkID: "vlan", Options: danmtypes.DanmNetOption{DevicePool: "nokia.k8s.io/sriov_ens1f0", Vlan: 500}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "vlan-with-vxlan"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "vlan", NetworkID: "vlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", Vlan: 500, Vxlan: 600}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "vlan-valid"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "vlan", NetworkID: "vlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", Vlan: 500, MTU: 1500}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "host-vrf-ipvlan"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "ipvlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", HostVrf: "vrf-blue"}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "veth-with-device"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "veth", NetworkID: "veth", Options: danmtypes.DanmNetOption{Device: "ens1f0", Cidr: "192.168.1.64/26"}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "veth-vrf-and-table"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "veth", NetworkID: "veth", Options: danmtypes.DanmNetOption{Cidr: "192.168.1.64/26", HostVrf: "vrf-blue", HostRTable: 100}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "veth-valid"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "veth", NetworkID: "veth", Options: danmtypes.DanmNetOption{HostVrf: "vrf-blue", MTU: 1500}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "announce-many-garps"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "ipvlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", NeighAnnounce: &danmtypes.NeighAnnounce{GarpCount: 11}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "announce-negative-interval"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "ipvlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", NeighAnnounce: &danmtypes.NeighAnnounce{NaCount: 3, NaInterval: -100}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "announce-valid"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "ipvlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", NeighAnnounce: &danmtypes.NeighAnnounce{GarpCount: 3, GarpInterval: 200, NaCount: 3, NaInterval: 200}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "vrf-without-table"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "ipvlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", Vrf: "vrf-red"}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "vrf-main-table"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "ipvlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", Vrf: "vrf-red", RTables: 254}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "vrf-invalid-name"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "ipvlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", Vrf: "averyveryverylongvrf", RTables: 100}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "vrf-valid"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "ipvlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", Vrf: "vrf-red", RTables: 100}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "ext-route-invalid-dst"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "ipvlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", Cidr: "10.20.1.0/24", ExtendedRoutes: []danmtypes.IpRoute{{Dst: "10.20.20.0/33", Nexthops: []danmtypes.Nexthop{{Gw: "10.20.1.1"}}}}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "ext-route-gw-outside"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "ipvlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", Cidr: "10.20.1.0/24", ExtendedRoutes: []danmtypes.IpRoute{{Dst: "10.20.20.0/24", Nexthops: []danmtypes.Nexthop{{Gw: "10.20.1.1"},{Gw: "10.20.0.1"}}}}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "ext-route-l2"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "ipvlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", ExtendedRoutes: []danmtypes.IpRoute{{Dst: "10.20.20.0/24", Nexthops: []danmtypes.Nexthop{{Gw: "10.20.1.1"}}}}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "ext-route-invalid-scope"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "ipvlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", Cidr: "10.20.1.0/24", ExtendedRoutes: []danmtypes.IpRoute{{Dst: "10.20.20.0/24", Scope: "galaxy", Nexthops: []danmtypes.Nexthop{{Gw: "10.20.1.1"}}}}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "ext-route-big-weight"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "ipvlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", Cidr: "10.20.1.0/24", ExtendedRoutes: []danmtypes.IpRoute{{Dst: "10.20.20.0/24", Nexthops: []danmtypes.Nexthop{{Gw: "10.20.1.1", Weight: 300},{Gw: "10.20.1.2"}}}}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "ext-route-ecmp"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "ipvlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", Net6: "2a00:8a00:a000:1193::/64", ExtendedRoutes: []danmtypes.IpRoute{{Dst: "2a00:8a00:a000:2000::/64", Metric: 100, Scope: "universe", Nexthops: []danmtypes.Nexthop{{Gw: "2a00:8a00:a000:1193::1", Weight: 2},{Gw: "2a00:8a00:a000:1000::1", Onlink: true}}}}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "mtu-too-small"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "macvlan", NetworkID: "macvlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", MTU: 67}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "mtu-too-large"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "macvlan", NetworkID: "macvlan", Options: danmtypes.DanmNetOption{Device: "ens1f0", MTU: 65536}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "vf-macvlan"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "macvlan", NetworkID: "sriov", Options: danmtypes.DanmNetOption{Device: "ens1f0", Vf: &danmtypes.VfOptions{Trust: "on"}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "vf-qos-without-vlan"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "sriov", NetworkID: "sriov", Options: danmtypes.DanmNetOption{DevicePool: "nokia.k8s.io/sriov_ens1f0", Vf: &danmtypes.VfOptions{VlanQoS: 3}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "vf-invalid-spoofchk"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "sriov", NetworkID: "sriov", Options: danmtypes.DanmNetOption{DevicePool: "nokia.k8s.io/sriov_ens1f0", Vf: &danmtypes.VfOptions{SpoofChk: "true"}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "vf-invalid-rates"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "sriov", NetworkID: "sriov", Options: danmtypes.DanmNetOption{DevicePool: "nokia.k8s.io/sriov_ens1f0", Vf: &danmtypes.VfOptions{MinTxRate: 500, MaxTxRate: 100}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "vf-valid"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "sriov", NetworkID: "sriov", Options: danmtypes.DanmNetOption{DevicePool: "nokia.k8s.io/sriov_ens1f0", Vlan: 500, Vf: &danmtypes.VfOptions{Trust: "on", SpoofChk: "off", MaxTxRate: 1000, VlanQoS: 3, VlanProto: "802.1q"}}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "host-devices-valid"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "host-device", NetworkID: "nanomsg", Options: danmtypes.DanmNetOption{HostDevices: []string{"ens3","ens4"}}},
+    },
   }
 )
 
@@ -564,6 +872,93 @@ func TestValidateNetwork(t *testing.T) {
   }
 }
 
+var (
+  existingBridgeNets = []runtime.Object {
+    &danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "bridge-ens3", Namespace: "default"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "bridge", NetworkID: "br1", Options: danmtypes.DanmNetOption{Device: "ens3"}},
+    },
+    &danmtypes.TenantNetwork {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "bridge-vlan", Namespace: "tenant"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "bridge", NetworkID: "br2", Options: danmtypes.DanmNetOption{Device: "ens4", Vlan: 50}},
+    },
+    &danmtypes.ClusterNetwork {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "static-bridge"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "bridge", NetworkID: "br3"},
+    },
+  }
+  bridgeNets = []danmtypes.DanmNet {
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "bridge-ens3", Namespace: "default"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "bridge", NetworkID: "br1", Options: danmtypes.DanmNetOption{Device: "ens3", MTU: 9000}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "same-nid", Namespace: "other"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "bridge", NetworkID: "br1", Options: danmtypes.DanmNetOption{Device: "ens5"}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "same-nid-as-tnet"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "bridge", NetworkID: "br2", Options: danmtypes.DanmNetOption{Device: "ens5"}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "same-device"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "bridge", NetworkID: "br4", Options: danmtypes.DanmNetOption{Device: "ens3"}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "same-device-other-vlan"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "bridge", NetworkID: "br4", Options: danmtypes.DanmNetOption{Device: "ens3", Vlan: 60}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "same-nid-as-static"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "bridge", NetworkID: "br3", Options: danmtypes.DanmNetOption{Device: "ens6"}},
+    },
+    danmtypes.DanmNet {
+      ObjectMeta: meta_v1.ObjectMeta {Name: "ipvlan-same-nid"},
+      Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "br1", Options: danmtypes.DanmNetOption{Device: "ens3"}},
+    },
+  }
+)
+
+var validateHostBridgeTcs = []struct {
+  tcName string
+  netName string
+  neType string
+  opType v1beta1.Operation
+  isErrorExpected bool
+}{
+  {"UpdateOfOwnBridge", "bridge-ens3", DnetType, v1beta1.Update, false},
+  {"SameNidInOtherNamespace", "same-nid", DnetType, v1beta1.Create, true},
+  {"SameNidAsTenantNetwork", "same-nid-as-tnet", DnetType, v1beta1.Create, true},
+  {"SameBareHostDevice", "same-device", CnetType, v1beta1.Create, true},
+  {"SameHostDeviceWithVlan", "same-device-other-vlan", CnetType, v1beta1.Create, false},
+  {"SameNidAsStaticBridge", "same-nid-as-static", CnetType, v1beta1.Create, false},
+  {"SameNidAsNonBridgeNetwork", "ipvlan-same-nid", DnetType, v1beta1.Create, false},
+}
+
+func TestValidateHostBridge(t *testing.T) {
+  validator := admit.Validator{Client: fake.NewSimpleClientset(existingBridgeNets...)}
+  for _, tc := range validateHostBridgeTcs {
+    t.Run(tc.tcName, func(t *testing.T) {
+      writerStub := httpstub.NewWriterStub()
+      var oldNet []byte
+      if tc.opType == v1beta1.Update {
+        oldNet, _, _ = getNetForValidate(tc.netName, bridgeNets, tc.neType)
+      }
+      newNet, _, _ := getNetForValidate(tc.netName, bridgeNets, tc.neType)
+      request,err := utils.CreateHttpRequest(oldNet, newNet, false, false, tc.opType)
+      if err != nil {
+        t.Errorf("Could not create test HTTP Request object, because:%v", err)
+        return
+      }
+      validator.ValidateNetwork(writerStub, request)
+      err = utils.ValidateHttpResponse(writerStub, tc.isErrorExpected, nil)
+      if err != nil {
+        t.Errorf("Received HTTP Response did not match expectation, because:%v", err)
+      }
+    })
+  }
+}
+
 func getNetForValidate(name string, nets []danmtypes.DanmNet, neType string) ([]byte, *danmtypes.DanmNet, bool) {
   dnet := utils.GetTestNet(name, nets)
   if dnet == nil {
@@ -0,0 +1,57 @@
+package admit_tests
+
+import (
+  "testing"
+  "encoding/json"
+  "github.com/nokia/danm/pkg/admit"
+  httpstub "github.com/nokia/danm/test/stubs/http"
+  "github.com/nokia/danm/test/utils"
+  "k8s.io/api/admission/v1beta1"
+  corev1 "k8s.io/api/core/v1"
+  meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var validatePodTcs = []struct {
+  tcName string
+  annotation string
+  isErrorExpected bool
+}{
+  {"noAnnotation", "", false},
+  {"malformedAnnotation", `[{"network":"sriov"`, true},
+  {"unknownField", `[{"network":"sriov","blurp":true}]`, true},
+  {"invalidNumberOfNetworks", `[{"network":"sriov","clusterNetwork":"sriov"}]`, true},
+  {"invalidTrust", `[{"network":"sriov","vf":{"trust":"yes"}}]`, true},
+  {"invalidLinkState", `[{"network":"sriov","vf":{"link_state":"up"}}]`, true},
+  {"invalidTxRates", `[{"network":"sriov","vf":{"min_tx_rate":1000,"max_tx_rate":100}}]`, true},
+  {"invalidVlanQos", `[{"network":"sriov","vf":{"vlan_qos":8}}]`, true},
+  {"invalidVlanProto", `[{"network":"sriov","vf":{"vlan_proto":"802.1x"}}]`, true},
+  {"invalidVfOfBondSlave", `[{"network":"bond","bond":{"slaves":[{"network":"sriov","vf":{"spoofchk":"no"}}]}}]`, true},
+  {"invalidExtendedProute", `[{"network":"sriov","extended_proutes":[{"dst":"10.0.0.0/33"}]}]`, true},
+  {"invalidExtendedProuteWeight", `[{"network":"sriov","extended_proutes":[{"dst":"10.0.0.0/24","nexthops":[{"gw":"10.0.1.1","weight":-1},{"gw":"10.0.1.2"}]}]}]`, true},
+  {"validExtendedProute", `[{"network":"sriov","extended_proutes":[{"dst":"10.0.0.0/24","metric":10,"nexthops":[{"gw":"10.0.1.1","weight":3},{"gw":"10.0.2.1","onlink":true}]}]}]`, false},
+  {"validVf", `[{"network":"sriov","vf":{"spoofchk":"off","trust":"on","link_state":"enable","min_tx_rate":100,"max_tx_rate":1000,"vlan_qos":5,"vlan_proto":"802.1ad"}}]`, false},
+}
+
+func TestValidatePod(t *testing.T) {
+  validator := admit.Validator{}
+  for _, tc := range validatePodTcs {
+    t.Run(tc.tcName, func(t *testing.T) {
+      writerStub := httpstub.NewWriterStub()
+      pod := corev1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: "test-pod"}}
+      if tc.annotation != "" {
+        pod.ObjectMeta.Annotations = map[string]string{"danm.io/interfaces": tc.annotation}
+      }
+      podBinary,_ := json.Marshal(pod)
+      request, err := utils.CreateHttpRequest(nil, podBinary, false, false, v1beta1.Create)
+      if err != nil {
+        t.Errorf("Could not create test HTTP Request object, because:%v", err)
+        return
+      }
+      validator.ValidatePod(writerStub, request)
+      err = utils.ValidateHttpResponse(writerStub, tc.isErrorExpected, nil)
+      if err != nil {
+        t.Errorf("Received HTTP Response did not match expectation, because:%v", err)
+      }
+    })
+  }
+}
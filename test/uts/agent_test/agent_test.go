@@ -0,0 +1,191 @@
+package agent_test
+
+import (
+  "context"
+  "net"
+  "os"
+  "path/filepath"
+  "testing"
+  "time"
+  "encoding/json"
+  "io/ioutil"
+  "github.com/containernetworking/cni/pkg/skel"
+  "github.com/containernetworking/cni/pkg/types"
+  danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
+  "github.com/nokia/danm/crd/client/clientset/versioned/fake"
+  danmlisters "github.com/nokia/danm/crd/client/listers/danm/v1"
+  "github.com/nokia/danm/pkg/agent"
+  meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+  "k8s.io/client-go/tools/cache"
+)
+
+const (
+  testHost = "test-host"
+)
+
+type forwardTest struct {
+  tcName string
+  cniOp string
+  agentResponse *agent.CniResponse
+  isAgentRunning bool
+  expectedResult string
+  isErrorExpected bool
+  expectedError error
+}
+
+var forwardTcs = []forwardTest {
+  {"noAgent", agent.CniAddOp, nil, false, "", true, agent.ErrAgentUnavailable},
+  {"successfulAdd", agent.CniAddOp, &agent.CniResponse{Result: json.RawMessage(`{"cniVersion":"0.3.1"}`)}, true, `{"cniVersion":"0.3.1"}`, false, nil},
+  {"failedDel", agent.CniDelOp, &agent.CniResponse{Error: types.NewError(types.ErrInternal, "it did not go well", "")}, true, "", true, nil},
+  {"successfulCheck", agent.CniCheckOp, &agent.CniResponse{}, true, "", false, nil},
+}
+
+var (
+  deletionTime = meta_v1.NewTime(time.Now())
+  liveStatus = danmtypes.DanmEpStatus{OperState: "up", Carrier: true, MTU: 1500}
+)
+
+var refreshTcs = []struct {
+  tcName string
+  ep *danmtypes.DanmEp
+  isUpdateExpected bool
+}{
+  {"epOfHost", newTestEp("ep-of-host", testHost, nil, danmtypes.DanmEpStatus{}), true},
+  {"epOfOtherHost", newTestEp("ep-of-other-host", "other-host", nil, danmtypes.DanmEpStatus{}), false},
+  {"epUnderDeletion", newTestEp("ep-under-deletion", testHost, &deletionTime, danmtypes.DanmEpStatus{}), false},
+  {"epWithUnchangedStatus", newTestEp("ep-with-unchanged-status", testHost, nil, liveStatus), false},
+}
+
+func TestRefreshEpStatusesOfHost(t *testing.T) {
+  for _, tc := range refreshTcs {
+    t.Run(tc.tcName, func(t *testing.T) {
+      client := fake.NewSimpleClientset(tc.ep)
+      indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+      indexer.Add(tc.ep)
+      danmAgent := &agent.Agent {
+        DanmClient: client,
+        StatsInterval: agent.StatsInterval,
+        StatusGetter: func(ep *danmtypes.DanmEp) (*danmtypes.DanmEpStatus, error) {
+          status := liveStatus
+          status.LastUpdated = meta_v1.NewTime(time.Now())
+          return &status, nil
+        },
+      }
+      danmAgent.RefreshEpStatusesOfHost(testHost, danmlisters.NewDanmEpLister(indexer))
+      isUpdated := false
+      for _, action := range client.Actions() {
+        if action.GetVerb() == "update" && action.GetSubresource() == "status" {
+          isUpdated = true
+        }
+      }
+      if isUpdated != tc.isUpdateExpected {
+        t.Fatalf("status update of DanmEp was expected to be:%t", tc.isUpdateExpected)
+      }
+      if !isUpdated {
+        return
+      }
+      ep, err := client.DanmV1().DanmEps(tc.ep.ObjectMeta.Namespace).Get(context.TODO(), tc.ep.ObjectMeta.Name, meta_v1.GetOptions{})
+      if err != nil {
+        t.Fatalf("DanmEp could not be read from the API server because:%v", err)
+      }
+      if ep.Status.OperState != liveStatus.OperState || ep.Status.MTU != liveStatus.MTU {
+        t.Errorf("status of DanmEp:%v does not match with the live state of the interface", ep.Status)
+      }
+    })
+  }
+}
+
+func newTestEp(name, host string, deletionTimestamp *meta_v1.Time, status danmtypes.DanmEpStatus) *danmtypes.DanmEp {
+  return &danmtypes.DanmEp {
+    ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: "default", DeletionTimestamp: deletionTimestamp},
+    Spec: danmtypes.DanmEpSpec{Host: host, Iface: danmtypes.DanmEpIface{Name: "eth1"}},
+    Status: status,
+  }
+}
+
+func TestForwardRequest(t *testing.T) {
+  for _, tc := range forwardTcs {
+    t.Run(tc.tcName, func(t *testing.T) {
+      testDir, err := ioutil.TempDir("", "danmagent")
+      if err != nil {
+        t.Fatalf("temporary directory could not be created because:%v", err)
+      }
+      defer os.RemoveAll(testDir)
+      socketPath := filepath.Join(testDir, "agent.sock")
+      args := &skel.CmdArgs{ContainerID: "cid", Netns: "/var/run/netns/test", IfName: "eth0", StdinData: []byte(`{"agentSocket":"` + socketPath + `"}`)}
+      receivedRequests := make(chan agent.CniRequest, 1)
+      if tc.isAgentRunning {
+        listener, err := net.Listen("unix", socketPath)
+        if err != nil {
+          t.Fatalf("fake agent could not be started because:%v", err)
+        }
+        defer listener.Close()
+        go fakeAgent(listener, tc.agentResponse, receivedRequests)
+      }
+      rawResult, err := agent.ForwardRequest(tc.cniOp, args)
+      if (err != nil && !tc.isErrorExpected) || (err == nil && tc.isErrorExpected) {
+        t.Fatalf("received error:%v does not match with expectation", err)
+      }
+      if tc.expectedError != nil && err != tc.expectedError {
+        t.Fatalf("received error:%v is not the expected:%v", err, tc.expectedError)
+      }
+      if string(rawResult) != tc.expectedResult {
+        t.Errorf("received result:%s does not match with the expected:%s", string(rawResult), tc.expectedResult)
+      }
+      if !tc.isAgentRunning {
+        return
+      }
+      request := <-receivedRequests
+      if request.Env["CNI_COMMAND"] != tc.cniOp || request.Env["CNI_CONTAINERID"] != args.ContainerID || request.Env["CNI_IFNAME"] != args.IfName || string(request.Config) != string(args.StdinData) {
+        t.Errorf("CNI request received by the agent:%v does not match with the forwarded CNI args", request)
+      }
+      if !request.Deadline.After(time.Now()) {
+        t.Errorf("deadline of the CNI request:%v is not in the future, the agent would drop it", request.Deadline)
+      }
+    })
+  }
+}
+
+func TestForwardRequestToHungAgent(t *testing.T) {
+  testDir, err := ioutil.TempDir("", "danmagent")
+  if err != nil {
+    t.Fatalf("temporary directory could not be created because:%v", err)
+  }
+  defer os.RemoveAll(testDir)
+  socketPath := filepath.Join(testDir, "agent.sock")
+  listener, err := net.Listen("unix", socketPath)
+  if err != nil {
+    t.Fatalf("fake agent could not be started because:%v", err)
+  }
+  defer listener.Close()
+  go func() {
+    conn, err := listener.Accept()
+    if err != nil {
+      return
+    }
+    //The agent reads the request, but never answers
+    defer conn.Close()
+    ioutil.ReadAll(conn)
+  }()
+  args := &skel.CmdArgs{ContainerID: "cid", StdinData: []byte(`{"agentSocket":"` + socketPath + `","delTimeout":1}`)}
+  start := time.Now()
+  _, err = agent.ForwardRequest(agent.CniDelOp, args)
+  if err == nil || err == agent.ErrAgentUnavailable {
+    t.Fatalf("forwarding to a hung agent returned error:%v instead of a time-out", err)
+  }
+  if time.Since(start) > 30*time.Second {
+    t.Errorf("forwarding to a hung agent was not bounded by the DEL timeout, it took:%v", time.Since(start))
+  }
+}
+
+func fakeAgent(listener net.Listener, response *agent.CniResponse, receivedRequests chan agent.CniRequest) {
+  conn, err := listener.Accept()
+  if err != nil {
+    return
+  }
+  defer conn.Close()
+  var request agent.CniRequest
+  json.NewDecoder(conn).Decode(&request)
+  receivedRequests <- request
+  json.NewEncoder(conn).Encode(response)
+}
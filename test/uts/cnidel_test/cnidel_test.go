@@ -1,31 +1,73 @@
 package cnidel_test
 
 import (
+  "context"
+  "errors"
+  "encoding/json"
   "os"
+  "os/exec"
   "strings"
   "testing"
+  "time"
   "io/ioutil"
   "path/filepath"
+  "reflect"
+  "github.com/containernetworking/cni/pkg/types"
+  "github.com/containernetworking/cni/pkg/types/current"
   sriov_utils "github.com/intel/sriov-cni/pkg/utils"
   danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
   "github.com/nokia/danm/pkg/cnidel"
+  "github.com/nokia/danm/pkg/cnierrors"
   "github.com/nokia/danm/pkg/datastructs"
+  "github.com/nokia/danm/pkg/epstore"
   "github.com/nokia/danm/test/utils"
   meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+  "k8s.io/apimachinery/pkg/runtime"
 )
 
 const (
   cniTestConfigDir = "/etc/cni/net.d"
   cniTestConfigFile = "cnitest.conf"
+  cniTestTraceFile = "cnitest.trace"
+  cniTestStoreDir = "/tmp/danm-cnidel-store"
+  cniTestBackendDir = "/tmp/danm-cnidel-backends"
+  cniTestHostLocalDir = "/tmp/danm-cnidel-hostlocal"
 )
 
 var (
   cniTesterDir = cniTestConfigDir
-  defaultDataDir = "/var/lib/cni/networks"
+  testSourceDir, _ = os.Getwd()
   flannelBridge = "cbr0"
-  cniConf = datastructs.NetConf{CniConfigDir: "/etc/cni/net.d"}
+  cniConf = datastructs.NetConf{CniConfigDir: "/etc/cni/net.d", StoreDir: cniTestStoreDir, HostLocalDataDir: cniTestHostLocalDir}
 )
 
+var testBackendDefinitions = []CniConf {
+  {"templated.json", []byte(`{"type":"templated","ipamNeeded":true,"template":"{\"cniVersion\":\"{{.CNIVersion}}\",\"name\":\"{{.Name}}\",\"type\":\"{{.Type}}\",\"master\":\"{{.HostDevice}}\",\"mtu\":{{.Options.MTU}},\"ipam\":{{json .Ipam}}}"}`)},
+  {"templated-device.json", []byte(`{"type":"templated-device","cniVersion":"0.3.1","deviceNeeded":true,"templateFile":"templated-device.tmpl"}`)},
+  {"templated-device.tmpl", []byte(`{"cniVersion":"{{.CNIVersion}}","name":"{{.Name}}","type":"templated-device","deviceID":"{{.DeviceID}}"}`)},
+  {"templated-missing.json", []byte(`{"type":"templated-missing","template":"{\"name\":\"{{.Options.Nope}}\"}"}`)},
+  {"templated-invalid.json", []byte(`{"type":"templated-invalid","template":"{\"name\":{{.Name}}}"}`)},
+  {"sriov.json", []byte(`{"type":"sriov","template":"{}"}`)},
+  {"broken-template.json", []byte(`{"type":"broken-template","template":"{{.Name"}`)},
+  {"unknown-field.json", []byte(`{"type":"unknown-field","template":"{}","blurp":true}`)},
+  {"no-template.json", []byte(`{"type":"no-template"}`)},
+}
+
+var backendLookupTcs = []struct {
+  neType string
+  isFoundExpected bool
+  isDeviceNeededExpected bool
+}{
+  {"templated", true, false},
+  {"Templated-Device", true, true},
+  {"templated-missing", true, false},
+  {"sriov", true, true},
+  {"broken-template", false, false},
+  {"unknown-field", false, false},
+  {"no-template", false, false},
+  {"templated-device.tmpl", false, false},
+}
+
 type CniConf struct {
   ConfName string
   Conftent []byte
@@ -58,7 +100,7 @@ var testNets = []danmtypes.DanmNet {
   },
   danmtypes.DanmNet {
     ObjectMeta: meta_v1.ObjectMeta {Name: "ipamNeeded"},
-    Spec: danmtypes.DanmNetSpec{NetworkType: "macvlan", NetworkID: "cidr",},
+    Spec: danmtypes.DanmNetSpec{NetworkType: "bridge", NetworkID: "cidr",},
   },
   danmtypes.DanmNet {
     ObjectMeta: meta_v1.ObjectMeta {Name: "flannel-test"},
@@ -73,24 +115,40 @@ var testNets = []danmtypes.DanmNet {
     Spec: danmtypes.DanmNetSpec{NetworkType: "flanel", NetworkID: "flannel_conf",},
   },
   danmtypes.DanmNet {
-    ObjectMeta: meta_v1.ObjectMeta {Name: "macvlan-v4"},
+    ObjectMeta: meta_v1.ObjectMeta {Name: "macvlan"},
     Spec: danmtypes.DanmNetSpec{NetworkType: "macvlan", NetworkID: "macvlan-v4", Options: danmtypes.DanmNetOption{Cidr: "192.168.1.64/26", Device: "ens1f0"}},
   },
   danmtypes.DanmNet {
-    ObjectMeta: meta_v1.ObjectMeta {Name: "macvlan-v6"},
-    Spec: danmtypes.DanmNetSpec{NetworkType: "macvlan", NetworkID: "macvlan-v6", Options: danmtypes.DanmNetOption{Net6: "2a00:8a00:a000:1193::/64", Device: "ens1f1"}},
+    ObjectMeta: meta_v1.ObjectMeta {Name: "veth"},
+    Spec: danmtypes.DanmNetSpec{NetworkType: "veth", NetworkID: "veth", Options: danmtypes.DanmNetOption{Cidr: "192.168.1.64/26"}},
   },
   danmtypes.DanmNet {
-    ObjectMeta: meta_v1.ObjectMeta {Name: "macvlan-ds"},
-    Spec: danmtypes.DanmNetSpec{NetworkType: "macvlan", NetworkID: "macvlan-ds", Options: danmtypes.DanmNetOption{Cidr: "192.168.1.64/26", Net6: "2a00:8a00:a000:1193::/64", Device: "ens1f1"}},
+    ObjectMeta: meta_v1.ObjectMeta {Name: "vlan"},
+    Spec: danmtypes.DanmNetSpec{NetworkType: "vlan", NetworkID: "vlan", Options: danmtypes.DanmNetOption{Cidr: "192.168.1.64/26", Device: "ens1f0", Vlan: 500}},
   },
   danmtypes.DanmNet {
     ObjectMeta: meta_v1.ObjectMeta {Name: "sriov-test"},
     Spec: danmtypes.DanmNetSpec{NetworkType: "sriov", NetworkID: "sriov-test", Options: danmtypes.DanmNetOption{Cidr: "192.168.1.64/26", Vlan: 500}},
   },
   danmtypes.DanmNet {
-    ObjectMeta: meta_v1.ObjectMeta {Name: "full-macvlan"},
-    Spec: danmtypes.DanmNetSpec{NetworkType: "macvlan", NetworkID: "full", Options: danmtypes.DanmNetOption{Cidr: "192.168.1.64/26", Device: "ens1f0"}},
+    ObjectMeta: meta_v1.ObjectMeta {Name: "sriov-vf"},
+    Spec: danmtypes.DanmNetSpec{NetworkType: "sriov", NetworkID: "sriov-vf", Options: danmtypes.DanmNetOption{Vlan: 500, Vf: &danmtypes.VfOptions{SpoofChk: "off", Trust: "on", MaxTxRate: 1000, VlanQoS: 3}}},
+  },
+  danmtypes.DanmNet {
+    ObjectMeta: meta_v1.ObjectMeta {Name: "templated"},
+    Spec: danmtypes.DanmNetSpec{NetworkType: "templated", NetworkID: "templated", Options: danmtypes.DanmNetOption{Cidr: "192.168.1.64/26", Device: "ens1f0", Vlan: 200, MTU: 9000}},
+  },
+  danmtypes.DanmNet {
+    ObjectMeta: meta_v1.ObjectMeta {Name: "templated-device"},
+    Spec: danmtypes.DanmNetSpec{NetworkType: "templated-device", NetworkID: "templated-device"},
+  },
+  danmtypes.DanmNet {
+    ObjectMeta: meta_v1.ObjectMeta {Name: "templated-missing"},
+    Spec: danmtypes.DanmNetSpec{NetworkType: "templated-missing", NetworkID: "templated-missing"},
+  },
+  danmtypes.DanmNet {
+    ObjectMeta: meta_v1.ObjectMeta {Name: "templated-invalid"},
+    Spec: danmtypes.DanmNetSpec{NetworkType: "templated-invalid", NetworkID: "templated-invalid"},
   },
   danmtypes.DanmNet {
     ObjectMeta: meta_v1.ObjectMeta {Name: "bridge-ipam-ipv4"},
@@ -124,20 +182,59 @@ var testNets = []danmtypes.DanmNet {
     ObjectMeta: meta_v1.ObjectMeta {Name: "full-bridge"},
     Spec: danmtypes.DanmNetSpec{NetworkType: "bridge", NetworkID: "bridge_l2", Options: danmtypes.DanmNetOption{Cidr: "192.168.1.64/26"}},
   },
+  danmtypes.DanmNet {
+    ObjectMeta: meta_v1.ObjectMeta {Name: "chain"},
+    Spec: danmtypes.DanmNetSpec{NetworkType: "bridge", NetworkID: "chain", Options: danmtypes.DanmNetOption{Cidr: "192.168.1.64/26"}},
+  },
+  danmtypes.DanmNet {
+    ObjectMeta: meta_v1.ObjectMeta {Name: "chained-ipvlan"},
+    Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: "chained", Options: danmtypes.DanmNetOption{ChainedPlugins: []danmtypes.ChainedPlugin{{Type: "tuning", Args: runtime.RawExtension{Raw: []byte(`{"mtu":1400}`)}},{Type: "portmap", Args: runtime.RawExtension{Raw: []byte(`{"capabilities":{"portMappings":true}}`)}}}}},
+  },
+  danmtypes.DanmNet {
+    ObjectMeta: meta_v1.ObjectMeta {Name: "bridge-dynamic"},
+    Spec: danmtypes.DanmNetSpec{NetworkType: "bridge", NetworkID: "dbridge", Options: danmtypes.DanmNetOption{Device: "ens1f0", Vlan: 500, Cidr: "192.168.1.64/26", MTU: 9000, HairpinMode: true}},
+  },
+  danmtypes.DanmNet {
+    ObjectMeta: meta_v1.ObjectMeta {Name: "ovs-vlan"},
+    Spec: danmtypes.DanmNetSpec{NetworkType: "ovs", NetworkID: "ovsvlan", Options: danmtypes.DanmNetOption{Device: "br-data", Vlan: 500, Cidr: "192.168.1.64/26", MTU: 9000}},
+  },
+  danmtypes.DanmNet {
+    ObjectMeta: meta_v1.ObjectMeta {Name: "ovs-trunk"},
+    Spec: danmtypes.DanmNetSpec{NetworkType: "ovs", NetworkID: "ovstrunk", Options: danmtypes.DanmNetOption{Device: "br-data", Trunk: []danmtypes.VlanTrunk{{ID: 42},{MinID: 1000, MaxID: 1010}}}},
+  },
+  danmtypes.DanmNet {
+    ObjectMeta: meta_v1.ObjectMeta {Name: "host-device"},
+    Spec: danmtypes.DanmNetSpec{NetworkType: "host-device", NetworkID: "hdev", Options: danmtypes.DanmNetOption{HostDevices: []string{"nosuchdevice", "lo"}, Cidr: "192.168.1.64/26"}},
+  },
+  danmtypes.DanmNet {
+    ObjectMeta: meta_v1.ObjectMeta {Name: "host-device-pool"},
+    Spec: danmtypes.DanmNetSpec{NetworkType: "host-device", NetworkID: "hdev", Options: danmtypes.DanmNetOption{DevicePool: "nokia.k8s.io/nics", Cidr: "192.168.1.64/26"}},
+  },
+  danmtypes.DanmNet {
+    ObjectMeta: meta_v1.ObjectMeta {Name: "chain-broken"},
+    Spec: danmtypes.DanmNetSpec{NetworkType: "bridge", NetworkID: "chain_broken", Options: danmtypes.DanmNetOption{Cidr: "192.168.1.64/26"}},
+  },
+  danmtypes.DanmNet {
+    ObjectMeta: meta_v1.ObjectMeta {Name: "sriov-vf-qinq"},
+    Spec: danmtypes.DanmNetSpec{NetworkType: "sriov", NetworkID: "sriov-vf-qinq", Options: danmtypes.DanmNetOption{Vlan: 500, Vf: &danmtypes.VfOptions{LinkState: "auto", VlanQoS: 5, VlanProto: "802.1ad"}}},
+  },
+  danmtypes.DanmNet {
+    ObjectMeta: meta_v1.ObjectMeta {Name: "bridge-promisc"},
+    Spec: danmtypes.DanmNetSpec{NetworkType: "bridge", NetworkID: "pbridge", Options: danmtypes.DanmNetOption{Device: "ens1f0", Cidr: "192.168.1.64/26", PromiscMode: true}},
+  },
 }
 
 var expectedCniConfigs = []CniConf {
   {"flannel", []byte(`{"cniexp":{"cnitype":"flannel"},"cniconf":{"cniVersion":"0.3.1","name":"cbr0","type":"flannel","delegate":{"hairpinMode":true,"isDefaultGateway":true}}}`)},
   {"flannel-ip", []byte(`{"cniexp":{"cnitype":"flannel","ip":"10.244.10.30/24","env":{"CNI_COMMAND":"ADD","CNI_IFNAME":"eth0"}},"cniconf":{"cniVersion":"0.3.1","name":"cbr0","type":"flannel","delegate":{"hairpinMode":true,"isDefaultGateway":true}}}`)},
-  {"macvlan-ip4", []byte(`{"cniexp":{"cnitype":"macvlan","ip":"192.168.1.65/26","env":{"CNI_COMMAND":"ADD","CNI_IFNAME":"ens1f0"}},"cniconf":{"cniVersion":"0.3.1","name":"macvlan-v4","master":"ens1f0","mode":"bridge","mtu":1500,"ipam":{"type":"fakeipam","ips":[{"ipcidr":"192.168.1.65/26","version":4}]}}}`)},
-  {"macvlan-ip6", []byte(`{"cniexp":{"cnitype":"macvlan","ip6":"2a00:8a00:a000:1193::/64","env":{"CNI_COMMAND":"ADD","CNI_IFNAME":"ens1f1"}},"cniconf":{"cniVersion":"0.3.1","name":"macvlan-v6","master":"ens1f1","mode":"bridge","mtu":1500,"ipam":{"type":"fakeipam"}}}`)},
-  {"macvlan-dual-stack", []byte(`{"cniexp":{"cnitype":"macvlan","ip":"192.168.1.65/26","ip6":"2a00:8a00:a000:1193::/64","env":{"CNI_COMMAND":"ADD","CNI_IFNAME":"ens1f1"}},"cniconf":{"cniVersion":"0.3.1","name":"macvlan-ds","master":"ens1f1","mode":"bridge","mtu":1500,"ipam":{"type":"fakeipam","ips":[{"ipcidr":"192.168.1.65/26","version":4}]}}}`)},
-  {"macvlan-ip4-type020", []byte(`{"cniexp":{"cnitype":"macvlan","ip":"192.168.1.65/26","env":{"CNI_COMMAND":"ADD","CNI_IFNAME":"ens1f0"},"return":"020"},"cniconf":{"cniVersion":"0.3.1","name":"macvlan-v4","master":"ens1f0","mode":"bridge","mtu":1500,"ipam":{"type":"fakeipam","ips":[{"ipcidr":"192.168.1.65/26","version":4}]}}}`)},
-  {"macvlan-ip6-type020", []byte(`{"cniexp":{"cnitype":"macvlan","ip6":"2a00:8a00:a000:1193::/64","env":{"CNI_COMMAND":"ADD","CNI_IFNAME":"ens1f1"},"return":"020"},"cniconf":{"cniVersion":"0.3.1","name":"macvlan-v6","master":"ens1f1","mode":"bridge","mtu":1500,"ipam":{"type":"fakeipam"}}}`)},
   {"sriov-l3", []byte(`{"cniexp":{"cnitype":"sriov","ip":"192.168.1.65/26","env":{"CNI_COMMAND":"ADD","CNI_IFNAME":"eth0"}},"cniconf":{"cniVersion":"0.3.1","name":"sriov-test","type":"sriov","master":"enp175s0f1","vlan":500,"deviceID":"0000:af:06.0","ipam":{"type":"fakeipam","ips":[{"ipcidr":"192.168.1.65/26","version":4}]}}}`)},
+  {"sriov-vf", []byte(`{"cniexp":{"cnitype":"sriov","env":{"CNI_COMMAND":"ADD","CNI_IFNAME":"eth0"}},"cniconf":{"cniVersion":"0.3.1","name":"sriov-vf","type":"sriov","master":"enp175s0f1","vlan":500,"deviceID":"0000:af:06.0","spoofchk":"off","trust":"on","max_tx_rate":1000,"vlanQoS":3}}`)},
+  {"sriov-vf-override", []byte(`{"cniexp":{"cnitype":"sriov","env":{"CNI_COMMAND":"ADD","CNI_IFNAME":"eth0"}},"cniconf":{"cniVersion":"0.3.1","name":"sriov-vf","type":"sriov","master":"enp175s0f1","vlan":500,"deviceID":"0000:af:06.0","spoofchk":"off","trust":"off","link_state":"enable","min_tx_rate":100,"max_tx_rate":1000,"vlanQoS":3}}`)},
+  {"sriov-vf-qinq", []byte(`{"cniexp":{"cnitype":"sriov","env":{"CNI_COMMAND":"ADD","CNI_IFNAME":"eth0"}},"cniconf":{"cniVersion":"0.3.1","name":"sriov-vf-qinq","type":"sriov","master":"enp175s0f1","vlan":500,"deviceID":"0000:af:06.0","link_state":"auto","vlanQoS":5,"vlanProto":"802.1ad"}}`)},
+  {"templated", []byte(`{"cniexp":{"cnitype":"generic","ip":"192.168.1.65/26","env":{"CNI_COMMAND":"ADD","CNI_IFNAME":"ens1f0"}},"cniconf":{"cniVersion":"0.3.1","name":"templated","type":"templated","master":"templated.200","mtu":9000,"ipam":{"type":"fakeipam","ips":[{"ipcidr":"192.168.1.65/26","version":4}]}}}`)},
+  {"templated-device", []byte(`{"cniexp":{"cnitype":"generic","env":{"CNI_COMMAND":"ADD","CNI_IFNAME":"eth0"}},"cniconf":{"cniVersion":"0.3.1","name":"templated-device","type":"templated-device","deviceID":"0000:af:06.0"}}`)},
   {"sriov-l2", []byte(`{"cniexp":{"cnitype":"sriov","env":{"CNI_COMMAND":"ADD","CNI_IFNAME":"eth0"}},"cniconf":{"cniVersion":"0.3.1","name":"sriov-test","type":"sriov","master":"enp175s0f1","vlan":500,"deviceID":"0000:af:06.0"}}`)},
   {"deleteflannel", []byte(`{"cniexp":{"cnitype":"flannel","env":{"CNI_COMMAND":"DEL","CNI_IFNAME":"eth0"}},"cniconf":{"cniVersion":"0.3.1","name":"cbr0","type":"flannel","delegate":{"hairpinMode":true,"isDefaultGateway":true}}}`)},
-  {"deletemacvlan", []byte(`{"cniexp":{"cnitype":"macvlan","env":{"CNI_COMMAND":"DEL","CNI_IFNAME":"ens1f0"}},"cniconf":{"cniVersion":"0.3.1","name":"full","master":"ens1f0","mode":"bridge","mtu":1500,"ipam": {"type": "fakeipam","ips":[{"ipcidr":"192.168.1.65/26","version":4}]}}}`)},
   {"bridge-l3-ip4", []byte(`{"cniexp":{"cnitype":"macvlan","ip":"192.168.1.65/26","env":{"CNI_COMMAND":"ADD","CNI_IFNAME":"eth0"}},"cniconf":{"cniVersion":"0.3.1","name": "mynet","type": "bridge","bridge": "mynet0","isDefaultGateway": true,"forceAddress": false,"ipMasq": true,"hairpinMode": true,"ipam": {"type": "fakeipam","ips":[{"ipcidr":"192.168.1.65/26","version":4}]}}}`)},
   {"bridge-l2-ip4", []byte(`{"cniexp":{"cnitype":"macvlan","ip":"192.168.1.65/26","env":{"CNI_COMMAND":"ADD","CNI_IFNAME":"eth0"}},"cniconf":{"cniVersion":"0.3.1","name": "mynet","type": "bridge","bridge": "mynet0","ipam": {"type": "fakeipam","ips":[{"ipcidr":"192.168.1.65/26","version":4}]}}}`)},
   {"bridge-l3-orig", []byte(`{"cniexp":{"cnitype":"macvlan","ip":"10.10.0.1/16","env":{"CNI_COMMAND":"ADD","CNI_IFNAME":"eth0"}},"cniconf":{"cniVersion":"0.3.1","name": "mynet","type": "bridge","bridge": "mynet0","isDefaultGateway": true,"forceAddress": false,"ipMasq": true,"hairpinMode": true,"ipam": {"type": "host-local","subnet": "10.10.0.0/16"}}}`)},
@@ -145,6 +242,14 @@ var expectedCniConfigs = []CniConf {
   {"bridge-l3-ip6", []byte(`{"cniexp":{"cnitype":"macvlan","ip6":"2a00:8a00:a000:1193::/64","env":{"CNI_COMMAND":"ADD","CNI_IFNAME":"eth0"}},"cniconf":{"cniVersion":"0.3.1","name": "mynet","type": "bridge","bridge": "mynet0","isDefaultGateway": true,"forceAddress": false,"ipMasq": true,"hairpinMode": true,"ipam": {"type": "fakeipam"}}}`)},
   {"bridge-l3-ds", []byte(`{"cniexp":{"cnitype":"macvlan","ip":"192.168.1.65/26","ip6":"2a00:8a00:a000:1193::/64","env":{"CNI_COMMAND":"ADD","CNI_IFNAME":"eth0"}},"cniconf":{"cniVersion":"0.3.1","name": "mynet","type": "bridge","bridge": "mynet0","isDefaultGateway": true,"forceAddress": false,"ipMasq": true,"hairpinMode": true,"ipam": {"type": "fakeipam","ips":[{"ipcidr":"192.168.1.65/26","version":4}]}}}`)},
   {"deletebridge", []byte(`{"cniexp":{"cnitype":"macvlan","env":{"CNI_COMMAND":"DEL","CNI_IFNAME":"eth0"}},"cniconf":{"cniVersion":"0.3.1","name": "mynet","type": "bridge","bridge": "mynet0","ipam": {"type": "fakeipam","ips":[{"ipcidr":"192.168.1.65/26","version":4}]}}}`)},
+  {"chain", []byte(`{"cniexp":{"cnitype":"chain","ip":"192.168.1.65/26","chain":["bridge","tuning","portmap"]}}`)},
+  {"bridge-promisc", []byte(`{"cniexp":{"cnitype":"bridge","ip":"192.168.1.65/26","env":{"CNI_COMMAND":"ADD","CNI_IFNAME":"eth0"}},"cniconf":{"cniVersion":"0.3.1","name":"pbridge","type":"bridge","bridge":"br_pbridge","promiscMode":true,"ipam":{"type":"fakeipam","ips":[{"ipcidr":"192.168.1.65/26","version":4}]}}}`)},
+  {"ovs-vlan", []byte(`{"cniexp":{"cnitype":"ovs","ip":"192.168.1.65/26","env":{"CNI_COMMAND":"ADD","CNI_IFNAME":"eth0"}},"cniconf":{"cniVersion":"0.3.1","name":"ovsvlan","type":"ovs","bridge":"br-data","vlan":500,"mtu":9000,"ipam":{"type":"fakeipam","ips":[{"ipcidr":"192.168.1.65/26","version":4}]}}}`)},
+  {"ovs-trunk", []byte(`{"cniexp":{"cnitype":"ovs","env":{"CNI_COMMAND":"ADD","CNI_IFNAME":"eth0"}},"cniconf":{"cniVersion":"0.3.1","name":"ovstrunk","type":"ovs","bridge":"br-data","trunk":[{"id":42},{"minID":1000,"maxID":1010}]}}`)},
+  {"bridge-dynamic", []byte(`{"cniexp":{"cnitype":"bridge","ip":"192.168.1.65/26","env":{"CNI_COMMAND":"ADD","CNI_IFNAME":"eth0"}},"cniconf":{"cniVersion":"0.3.1","name":"dbridge","type":"bridge","bridge":"br_dbridge","hairpinMode":true,"mtu":9000,"ipam":{"type":"fakeipam","ips":[{"ipcidr":"192.168.1.65/26","version":4}]}}}`)},
+  {"host-device", []byte(`{"cniexp":{"cnitype":"host-device","ip":"192.168.1.65/26","env":{"CNI_COMMAND":"ADD","CNI_IFNAME":"eth0"}},"cniconf":{"cniVersion":"0.3.1","name":"hdev","type":"host-device","device":"lo","ipam":{"type":"fakeipam","ips":[{"ipcidr":"192.168.1.65/26","version":4}]}}}`)},
+  {"host-device-pool", []byte(`{"cniexp":{"cnitype":"host-device","ip":"192.168.1.65/26","env":{"CNI_COMMAND":"ADD","CNI_IFNAME":"eth0"}},"cniconf":{"cniVersion":"0.3.1","name":"hdev","type":"host-device","pciBusID":"0000:af:06.0","ipam":{"type":"fakeipam","ips":[{"ipcidr":"192.168.1.65/26","version":4}]}}}`)},
+  {"chained-plugins", []byte(`{"cniexp":{"cnitype":"chain","chain":["ipvlan","tuning","portmap"]}}`)},
   {"deletebridge-wo-ipam", []byte(`{"cniexp":{"cnitype":"macvlan","env":{"CNI_COMMAND":"DEL","CNI_IFNAME":"eth0"}},"cniconf":{"cniVersion":"0.3.1","name": "mynet","type": "bridge","bridge": "mynet0"}}`)},
 }
 
@@ -153,6 +258,8 @@ var testCniConfFiles = []CniConf {
   {"bridge_l3.conf", []byte(`{"cniVersion":"0.3.1","name": "mynet","type": "bridge","bridge": "mynet0","isDefaultGateway": true,"forceAddress": false,"ipMasq": true,"hairpinMode": true,"ipam": {"type": "host-local","subnet": "10.10.0.0/16"}}`)},
   {"bridge_l2.conf", []byte(`{"cniVersion":"0.3.1","name": "mynet","type": "bridge","bridge": "mynet0"}`)},
   {"bridge_invalid.conf", []byte(`{"cniVersion":"0.3.1","name": "mynet","type": "bridge","bridge": "myne`)},
+  {"chain.conflist", []byte(`{"cniVersion":"0.3.1","name": "chain","plugins":[{"type": "bridge","bridge": "mynet0"},{"type": "tuning","sysctl":{"net.core.somaxconn": "500"}},{"type": "portmap","capabilities":{"portMappings": true}}]}`)},
+  {"chain_broken.conflist", []byte(`{"cniVersion":"0.3.1","name": "chain","plugins":[{"type": "bridge","bridge": "mynet0"},{"type": "tuning"},{"type": "nosuchplugin"}]}`)},
 }
 
 var testEps = []danmtypes.DanmEp {
@@ -175,6 +282,18 @@ var testEps = []danmtypes.DanmEp {
     ObjectMeta: meta_v1.ObjectMeta {Name: "dynamicIpv4WithDeviceId"},
     Spec: danmtypes.DanmEpSpec {Iface: danmtypes.DanmEpIface{Name:"eth0", Address: "192.168.1.65/26", DeviceID: "0000:af:06.0"},},
   },
+  danmtypes.DanmEp{
+    ObjectMeta: meta_v1.ObjectMeta {Name: "dynamicIpv4WithHostDevice"},
+    Spec: danmtypes.DanmEpSpec {Iface: danmtypes.DanmEpIface{Name:"eth0", Address: "192.168.1.65/26", DeviceID: "lo"},},
+  },
+  danmtypes.DanmEp{
+    ObjectMeta: meta_v1.ObjectMeta {Name: "vfOverride"},
+    Spec: danmtypes.DanmEpSpec {Iface: danmtypes.DanmEpIface{Name:"eth0", Address: "none", DeviceID: "0000:af:06.0", Vf: &danmtypes.VfOptions{Trust: "off", MinTxRate: 100, LinkState: "enable"}},},
+  },
+  danmtypes.DanmEp{
+    ObjectMeta: meta_v1.ObjectMeta {Name: "vfInvalidOverride"},
+    Spec: danmtypes.DanmEpSpec {Iface: danmtypes.DanmEpIface{Name:"eth0", Address: "none", DeviceID: "0000:af:06.0", Vf: &danmtypes.VfOptions{MinTxRate: 2000}},},
+  },
   danmtypes.DanmEp{
     ObjectMeta: meta_v1.ObjectMeta {Name: "noneWithDeviceId"},
     Spec: danmtypes.DanmEpSpec {Iface: danmtypes.DanmEpIface{Name:"eth0", Address: "none", DeviceID: "0000:af:06.0"},},
@@ -216,6 +335,9 @@ var delegationRequiredTcs = []struct {
   {"empty", false},
   {"ipvlan", false},
   {"IPVLAN-UPPER", false},
+  {"macvlan", false},
+  {"vlan", false},
+  {"veth", false},
   {"sriov", true},
   {"flannel", true},
   {"hululululu", true},
@@ -230,6 +352,17 @@ var isDeviceNeededTcs = []struct {
   {"neverhas", false},
 }
 
+var nativeBackendTcs = []struct {
+  netName string
+  isNativeExpected bool
+}{
+  {"sriov-test", true},
+  {"flannel", false},
+  {"full-bridge", false},
+  {"bridge-dynamic", true},
+  {"ovs-vlan", true},
+}
+
 var delSetupTcs = []struct {
   tcName string
   netName string
@@ -246,14 +379,17 @@ var delSetupTcs = []struct {
   {"staticCniNoConfig", "no-conf", "noIps", "", "", "", true, false},
   {"staticCniNoBinary", "no-binary", "noIps", "flannel", "", "", true, false},
   {"staticCniWithIp", "flannel-test", "noIps", "flannel-ip", "10.244.10.30", "", false, false},
-  {"dynamicMacvlanIpv4", "macvlan-v4", "dynamicIpv4", "macvlan-ip4", "192.168.1.65", "", false, true},
-  {"dynamicMacvlanIpv6", "macvlan-v6", "dynamicIpv6", "macvlan-ip6", "", "2a00:8a00:a000:1193", false, true},
-  {"dynamicMacvlanDualStack", "macvlan-ds", "dynamicDual", "macvlan-dual-stack", "192.168.1.65", "2a00:8a00:a000:1193", false, true},
-  {"dynamicMacvlanIpv4Type020Result", "macvlan-v4", "dynamicIpv4", "macvlan-ip4-type020", "192.168.1.65", "", false, true},
-  {"dynamicMacvlanIpv6Type020Result", "macvlan-v6", "dynamicIpv6", "macvlan-ip6-type020", "", "2a00:8a00:a000:1193", false, true},
   {"dynamicSriovNoDeviceId", "sriov-test", "dynamicIpv4", "", "", "", true, true},
   {"dynamicSriovL3", "sriov-test", "dynamicIpv4WithDeviceId", "sriov-l3", "", "", false, true},
   {"dynamicSriovL2", "sriov-test", "noneWithDeviceId", "sriov-l2", "", "", false, true},
+  {"pluggableBackendInlineTemplate", "templated", "dynamicIpv4", "templated", "192.168.1.65", "", false, true},
+  {"pluggableBackendTemplateFile", "templated-device", "noneWithDeviceId", "templated-device", "", "", false, true},
+  {"pluggableBackendMissingKey", "templated-missing", "noneWithDeviceId", "", "", "", true, true},
+  {"pluggableBackendInvalidJson", "templated-invalid", "noneWithDeviceId", "", "", "", true, true},
+  {"dynamicSriovVfFromNetwork", "sriov-vf", "noneWithDeviceId", "sriov-vf", "", "", false, true},
+  {"dynamicSriovVfOverridden", "sriov-vf", "vfOverride", "sriov-vf-override", "", "", false, true},
+  {"dynamicSriovVfInvalidOverride", "sriov-vf", "vfInvalidOverride", "", "", "", true, true},
+  {"dynamicSriovVfWithVlanProto", "sriov-vf-qinq", "noneWithDeviceId", "sriov-vf-qinq", "", "", false, true},
   {"bridgeWithV4Overwrite", "bridge-ipam-ipv4", "simpleIpv4", "bridge-l3-ip4", "", "", false, true},
   {"bridgeWithV4Add", "bridge-ipam-l2", "simpleIpv4", "bridge-l2-ip4", "", "", false, true},
   {"bridgeWithInvalidAdd", "bridge-invalid", "simpleIpv4", "", "", "", true, false},
@@ -262,6 +398,30 @@ var delSetupTcs = []struct {
   {"bridgeL2OriginalNoCidr", "bridge-noipam-l2", "simpleIpv4", "bridge-l2-orig", "", "", false, false},
   {"bridgeWithV6Overwrite", "bridge-ipam-ipv6", "simpleIpv6", "bridge-l3-ip6", "", "", false, true},
   {"bridgeWithDsOverwrite", "bridge-ipam-ds", "simpleDs", "bridge-l3-ds", "", "", false, true},
+  {"dynamicBridgeIpv4", "bridge-dynamic", "simpleIpv4", "bridge-dynamic", "192.168.1.65", "", false, true},
+  {"dynamicBridgePromisc", "bridge-promisc", "simpleIpv4", "bridge-promisc", "192.168.1.65", "", false, true},
+  {"ovsWithVlanTag", "ovs-vlan", "simpleIpv4", "ovs-vlan", "192.168.1.65", "", false, true},
+  {"ovsWithTrunk", "ovs-trunk", "noIps", "ovs-trunk", "", "", false, false},
+  {"hostDeviceNoDevice", "host-device", "simpleIpv4", "", "", "", true, true},
+  {"hostDeviceByName", "host-device", "dynamicIpv4WithHostDevice", "host-device", "192.168.1.65", "", false, true},
+  {"hostDeviceFromPool", "host-device-pool", "dynamicIpv4WithDeviceId", "host-device-pool", "192.168.1.65", "", false, true},
+}
+
+var vfOptionsTcs = []struct {
+  tcName string
+  vf *danmtypes.VfOptions
+  isErrorExpected bool
+}{
+  {"noVfOptions", nil, false},
+  {"validVfOptions", &danmtypes.VfOptions{SpoofChk: "on", Trust: "off", LinkState: "disable", MinTxRate: 100, MaxTxRate: 1000, VlanQoS: 7, VlanProto: "802.1q"}, false},
+  {"onlyMinTxRate", &danmtypes.VfOptions{MinTxRate: 100}, false},
+  {"invalidSpoofChk", &danmtypes.VfOptions{SpoofChk: "yes"}, true},
+  {"invalidTrust", &danmtypes.VfOptions{Trust: "true"}, true},
+  {"invalidLinkState", &danmtypes.VfOptions{LinkState: "up"}, true},
+  {"negativeTxRate", &danmtypes.VfOptions{MaxTxRate: -1}, true},
+  {"minTxRateAboveMax", &danmtypes.VfOptions{MinTxRate: 2000, MaxTxRate: 1000}, true},
+  {"vlanQosOutOfRange", &danmtypes.VfOptions{VlanQoS: 8}, true},
+  {"invalidVlanProto", &danmtypes.VfOptions{VlanProto: "802.1x"}, true},
 }
 
 var delDeleteTcs = []struct {
@@ -273,7 +433,6 @@ var delDeleteTcs = []struct {
   timesUpdateShouldBeCalled int
 }{
   {"flannel", "flannel-test", "deleteFlannel", "deleteflannel", false, 0},
-  {"macvlan", "full-macvlan", "withAddress", "deletemacvlan", false, 1},
   {"bridgeWithDanmIpam", "full-bridge", "withAddressSimple", "deletebridge", false, 1},
   {"bridgeWithExternalIpam", "full-bridge", "withForeignAddressSimple", "deletebridge-wo-ipam", false, 0},
 }
@@ -299,6 +458,82 @@ func TestIsDeviceNeeded(t *testing.T) {
   }
 }
 
+func TestGetNativeBackend(t *testing.T) {
+  for _, tc := range nativeBackendTcs {
+    t.Run(tc.netName, func(t *testing.T) {
+      _, isNative := cnidel.GetNativeBackend(utils.GetTestNet(tc.netName, testNets))
+      if isNative != tc.isNativeExpected {
+        t.Errorf("Received native backend result:%t does not match with expected:%t", isNative, tc.isNativeExpected)
+      }
+    })
+  }
+}
+
+func TestLoadBackends(t *testing.T) {
+  err := setupBackends()
+  if err != nil {
+    t.Fatalf("backend definitions could not be set-up because:%v", err)
+  }
+  for _, tc := range backendLookupTcs {
+    t.Run(tc.neType, func(t *testing.T) {
+      backend, isFound := cnidel.LookupBackend(tc.neType)
+      if isFound != tc.isFoundExpected {
+        t.Fatalf("backend was found:%t, but it was expected to be:%t", isFound, tc.isFoundExpected)
+      }
+      if isFound && backend.DeviceNeeded != tc.isDeviceNeededExpected {
+        t.Errorf("backend needs a device:%t, but it was expected to be:%t", backend.DeviceNeeded, tc.isDeviceNeededExpected)
+      }
+    })
+  }
+  err = cnidel.LoadBackends(filepath.Join(cniTestBackendDir, "missing"))
+  if err != nil {
+    t.Errorf("missing backend directory shall not be an error, but it was:%v", err)
+  }
+  if _, isFound := cnidel.LookupBackend("templated"); isFound {
+    t.Errorf("pluggable backends shall be removed when their definitions are removed")
+  }
+}
+
+func TestReserveHostDevice(t *testing.T) {
+  os.RemoveAll(cniTestStoreDir)
+  testNet := utils.GetTestNet("host-device", testNets)
+  device, err := cnidel.ReserveHostDevice(&cniConf, testNet, "cid1")
+  if err != nil || device != "lo" {
+    t.Fatalf("host device:%s was reserved instead of the only existing one, error:%v", device, err)
+  }
+  _, err = cnidel.ReserveHostDevice(&cniConf, testNet, "cid2")
+  if err == nil || cnierrors.CodeOf(err) != cnierrors.ErrPoolExhausted {
+    t.Errorf("an already reserved host device was handed out again, error:%v", err)
+  }
+  otherEp := danmtypes.DanmEp{Spec: danmtypes.DanmEpSpec{CID: "cid2", Iface: danmtypes.DanmEpIface{DeviceID: device}}}
+  cnidel.ReleaseHostDevice(&cniConf, testNet, &otherEp)
+  _, err = cnidel.ReserveHostDevice(&cniConf, testNet, "cid2")
+  if err == nil {
+    t.Errorf("host device was released by a container it was not reserved for")
+  }
+  ownerEp := danmtypes.DanmEp{Spec: danmtypes.DanmEpSpec{CID: "cid1", Iface: danmtypes.DanmEpIface{DeviceID: device}}}
+  err = cnidel.ReleaseHostDevice(&cniConf, testNet, &ownerEp)
+  if err != nil {
+    t.Errorf("host device could not be released because:%v", err)
+  }
+  device, err = cnidel.ReserveHostDevice(&cniConf, testNet, "cid2")
+  if err != nil || device != "lo" {
+    t.Errorf("released host device could not be reserved again, received device:%s, error:%v", device, err)
+  }
+  os.RemoveAll(cniTestStoreDir)
+}
+
+func TestValidateVfOptions(t *testing.T) {
+  for _, tc := range vfOptionsTcs {
+    t.Run(tc.tcName, func(t *testing.T) {
+      err := cnidel.ValidateVfOptions(tc.vf)
+      if (err != nil && !tc.isErrorExpected) || (err == nil && tc.isErrorExpected) {
+        t.Errorf("Received error:%v does not match with expectation:%t", err, tc.isErrorExpected)
+      }
+    })
+  }
+}
+
 func TestGetEnv(t *testing.T) {
   testEnvKey := "HOTEL"
   testEnvVal := "trivago"
@@ -328,7 +563,7 @@ func TestDelegateInterfaceSetup(t *testing.T) {
       testNet := utils.GetTestNet(tc.netName, testNets)
       testEp := getTestEp(tc.epName)
       testEp.Spec.NetworkName = testNet.ObjectMeta.Name
-      cniRes, err := cnidel.DelegateInterfaceSetup(&cniConf,tc.isIpAlreadyAllocatedByDanmIpam,testNet,testEp)
+      cniRes, err := cnidel.DelegateInterfaceSetup(context.Background(), &cniConf,tc.isIpAlreadyAllocatedByDanmIpam,testNet,testEp, nil)
       if (err != nil && !tc.isErrorExpected) || (err == nil && tc.isErrorExpected) {
         var detailedErrorMessage string
         if err != nil {
@@ -360,6 +595,29 @@ func TestDelegateInterfaceSetup(t *testing.T) {
   }
 }
 
+func TestDelegateInterfaceSetupCancelled(t *testing.T) {
+  err := setupDelTest("ADD")
+  if err != nil {
+    t.Errorf("Test suite could not be set-up because:%s", err.Error())
+  }
+  err = setupDelTestTc("flannel")
+  if err != nil {
+    t.Errorf("TC could not be set-up because:%s", err.Error())
+  }
+  ctx, cancel := context.WithCancel(context.Background())
+  cancel()
+  testNet := utils.GetTestNet("flannel-test", testNets)
+  testEp := getTestEp("noIps")
+  _, err = cnidel.DelegateInterfaceSetup(ctx, &cniConf, false, testNet, testEp, nil)
+  if err == nil {
+    t.Errorf("CNI plugin was executed even though the context of the operation was already cancelled")
+  }
+  err = teardownDelTest()
+  if err != nil {
+    t.Errorf("Test suite setup could not be reversed because:%s", err.Error())
+  }
+}
+
 func TestDelegateInterfaceDelete(t *testing.T) {
   err := setupDelTest("DEL")
   if err != nil {
@@ -374,7 +632,7 @@ func TestDelegateInterfaceDelete(t *testing.T) {
         t.Errorf("TC could not be set-up because:%s", err.Error())
       }
       if testNet.Spec.NetworkType == "flannel" && testEp.Spec.Iface.Address != "" {
-        var dataDir = filepath.Join(defaultDataDir, flannelBridge)
+        var dataDir = filepath.Join(cniTestHostLocalDir, flannelBridge)
         err = os.MkdirAll(dataDir, os.ModePerm)
         if err != nil {
           t.Errorf("Delete TC Flannel prereq could not be set-up because:%s", err.Error())
@@ -383,8 +641,12 @@ func TestDelegateInterfaceDelete(t *testing.T) {
         if err != nil {
           t.Errorf("Delete TC Flannel prereq could not be set-up because:%s", err.Error())
         }
+        _,err = os.Create(filepath.Join(dataDir, "lock"))
+        if err != nil {
+          t.Errorf("Delete TC Flannel prereq could not be set-up because:%s", err.Error())
+        }
       }
-      err := cnidel.DelegateInterfaceDelete(&cniConf,testNet,testEp)
+      err := cnidel.DelegateInterfaceDelete(context.Background(), &cniConf,testNet,testEp)
       if (err != nil && !tc.isErrorExpected) || (err == nil && tc.isErrorExpected) {
         var detailedErrorMessage string
         if err != nil {
@@ -393,7 +655,7 @@ func TestDelegateInterfaceDelete(t *testing.T) {
         t.Errorf("Received error does not match with expectation: %t for TC: %s, detailed error message: %s", tc.isErrorExpected, tc.tcName, detailedErrorMessage)
       }
       if testNet.Spec.NetworkType == "flannel" && testEp.Spec.Iface.Address != "" {
-        var ipFile = filepath.Join(defaultDataDir, flannelBridge, testEp.Spec.Iface.Address)
+        var ipFile = filepath.Join(cniTestHostLocalDir, flannelBridge, testEp.Spec.Iface.Address)
         _,err = os.Lstat(ipFile)
         if err == nil {
           t.Errorf("IP file:" + ipFile + " was not cleaned-up by Flannel IP exhaustion protection code!")
@@ -403,13 +665,320 @@ func TestDelegateInterfaceDelete(t *testing.T) {
   }
 }
 
-func setupDelTest(opType string) error {
-  os.RemoveAll(cniTestConfigDir)
-  err := os.MkdirAll(cniTestConfigDir, os.ModePerm)
+var delChainTcs = []struct {
+  tcName string
+  netName string
+  isErrorExpected bool
+  expectedAddTrace []string
+  expectedDelTrace []string
+}{
+  {"successfulChain", "chain", false, []string{"ADD:bridge", "ADD:tuning", "ADD:portmap"}, []string{"DEL:portmap", "DEL:tuning", "DEL:bridge"}},
+  {"brokenChainRollsBack", "chain-broken", true, []string{"ADD:bridge", "ADD:tuning", "DEL:tuning", "DEL:bridge"}, nil},
+}
+
+func TestDelegateChain(t *testing.T) {
+  err := setupDelTest("ADD")
+  if err != nil {
+    t.Errorf("Test suite could not be set-up because:%s", err.Error())
+  }
+  err = setupDelTestTc("chain")
+  if err != nil {
+    t.Errorf("TC could not be set-up because:%s", err.Error())
+  }
+  for _, tc := range delChainTcs {
+    t.Run(tc.tcName, func(t *testing.T) {
+      testNet := utils.GetTestNet(tc.netName, testNets)
+      testEp := getTestEp("simpleIpv4")
+      os.Remove(filepath.Join(cniTestConfigDir, cniTestTraceFile))
+      cniRes, err := cnidel.DelegateInterfaceSetup(context.Background(), &cniConf, true, testNet, testEp, nil)
+      if (err != nil && !tc.isErrorExpected) || (err == nil && tc.isErrorExpected) {
+        t.Errorf("Received error:%v does not match with expectation:%t", err, tc.isErrorExpected)
+      }
+      checkTrace(t, tc.expectedAddTrace)
+      if tc.isErrorExpected {
+        return
+      }
+      if cniRes == nil || len(cniRes.IPs) != 1 || cniRes.IPs[0].Address.String() != "192.168.1.65/26" {
+        t.Errorf("Result of the first plugin was not passed through the whole chain, received result:%v", cniRes)
+      }
+      os.Remove(filepath.Join(cniTestConfigDir, cniTestTraceFile))
+      err = cnidel.DelegateInterfaceDelete(context.Background(), &cniConf, testNet, testEp)
+      if err != nil {
+        t.Errorf("DEL of the plugin chain failed with error:%s", err.Error())
+      }
+      checkTrace(t, tc.expectedDelTrace)
+    })
+  }
+  err = teardownDelTest()
+  if err != nil {
+    t.Errorf("Test suite setup could not be reversed because:%s", err.Error())
+  }
+}
+
+func TestChainedPlugins(t *testing.T) {
+  err := setupDelTest("ADD")
+  if err != nil {
+    t.Errorf("Test suite could not be set-up because:%s", err.Error())
+  }
+  err = setupDelTestTc("chained-plugins")
+  if err != nil {
+    t.Errorf("TC could not be set-up because:%s", err.Error())
+  }
+  testNet := utils.GetTestNet("chained-ipvlan", testNets)
+  testEp := getTestEp("simpleIpv4")
+  testEp.Spec.CID = "chainedcid"
+  ipvlanResult := &current.Result{CNIVersion: "0.3.1"}
+  ipvlanResult.Interfaces = append(ipvlanResult.Interfaces, &current.Interface{Name: "eth0"})
+  ip, _ := types.ParseCIDR("192.168.1.65/26")
+  ipvlanResult.IPs = append(ipvlanResult.IPs, &current.IPConfig{Version: "4", Address: *ip})
+  os.Remove(filepath.Join(cniTestConfigDir, cniTestTraceFile))
+  cniRes, err := cnidel.ExecChainedPlugins(context.Background(), &cniConf, testNet, testEp, ipvlanResult, chainRuntimeConfig)
+  if err != nil {
+    t.Errorf("Chained plugins failed with error:%s", err.Error())
+  }
+  checkTrace(t, []string{"ADD:tuning", "ADD:portmap"})
+  checkRuntimeConfig(t, testEp.Spec.CID, testEp.Spec.Iface.Name + "-chained")
+  if cniRes == nil || len(cniRes.IPs) != 1 || cniRes.IPs[0].Address.String() != "192.168.1.65/26" {
+    t.Errorf("Result of the interface creation was not passed through the chained plugins, received result:%v", cniRes)
+  }
+  os.Remove(filepath.Join(cniTestConfigDir, cniTestTraceFile))
+  err = cnidel.DeleteChainedPlugins(context.Background(), &cniConf, testNet, testEp)
+  if err != nil {
+    t.Errorf("DEL of the chained plugins failed with error:%s", err.Error())
+  }
+  checkTrace(t, []string{"DEL:portmap", "DEL:tuning"})
+  err = teardownDelTest()
+  if err != nil {
+    t.Errorf("Test suite setup could not be reversed because:%s", err.Error())
+  }
+}
+
+var chainRuntimeConfig = map[string]interface{} {
+  "portMappings": []interface{}{map[string]interface{}{"hostPort": float64(8080), "containerPort": float64(80), "protocol": "tcp"}},
+  "bandwidth": map[string]interface{}{"ingressRate": float64(1000)},
+}
+
+//Only the plugin declaring the portMappings capability shall receive it, and nothing else from the runtimeConfig
+func checkRuntimeConfig(t *testing.T, cid, key string) {
+  rec, err := epstore.NewStore(cniTestStoreDir).FindDelegate(cid, key)
+  if err != nil || rec == nil {
+    t.Errorf("config of the plugins was not cached, error:%v", err)
+    return
+  }
+  for _, plugin := range rec.Plugins {
+    var pluginConf map[string]interface{}
+    json.Unmarshal(plugin.Config, &pluginConf)
+    runtimeConfig, hasRuntimeConfig := pluginConf["runtimeConfig"].(map[string]interface{})
+    if plugin.Type != "portmap" {
+      if hasRuntimeConfig {
+        t.Errorf("plugin:%s without capabilities received runtimeConfig:%v", plugin.Type, runtimeConfig)
+      }
+      continue
+    }
+    if !reflect.DeepEqual(runtimeConfig, map[string]interface{}{"portMappings": chainRuntimeConfig["portMappings"]}) {
+      t.Errorf("plugin:%s received runtimeConfig:%v instead of its portMappings", plugin.Type, runtimeConfig)
+    }
+  }
+}
+
+func TestDelegateConfigCache(t *testing.T) {
+  err := setupDelTest("ADD")
+  if err != nil {
+    t.Errorf("Test suite could not be set-up because:%s", err.Error())
+  }
+  err = setupDelTestTc("chain")
+  if err != nil {
+    t.Errorf("TC could not be set-up because:%s", err.Error())
+  }
+  testNet := utils.GetTestNet("chain", testNets)
+  testEp := getTestEp("simpleIpv4")
+  testEp.Spec.CID = "cachedcid"
+  store := epstore.NewStore(cniTestStoreDir)
+  _, err = cnidel.DelegateInterfaceSetup(context.Background(), &cniConf, true, testNet, testEp, chainRuntimeConfig)
+  if err != nil {
+    t.Errorf("ADD of the plugin chain failed with error:%s", err.Error())
+  }
+  checkRuntimeConfig(t, testEp.Spec.CID, testEp.Spec.Iface.Name)
+  rec, err := store.FindDelegate(testEp.Spec.CID, testEp.Spec.Iface.Name)
+  if err != nil || rec == nil || len(rec.Plugins) != 3 || rec.Result == nil {
+    t.Errorf("Rendered config of the plugin chain was not cached properly during ADD, cached record:%v, error:%v", rec, err)
+  }
+  os.Remove(filepath.Join(cniTestConfigDir, cniTestTraceFile))
+  err = cnidel.CheckDelegatedInterfaces(context.Background(), &cniConf, testEp.Spec.CID)
+  if err != nil {
+    t.Errorf("CHECK of the plugin chain failed with error:%s", err.Error())
+  }
+  checkTrace(t, nil)
+  //DEL shall replay the config of the ADD, even though the config list has changed since
+  err = ioutil.WriteFile(filepath.Join(cniTestConfigDir, "chain.conflist"), []byte(`{"cniVersion":"0.3.1","name": "chain","plugins":[{"type": "bridge","bridge": "mynet0"}]}`), 0666)
+  if err != nil {
+    t.Errorf("CNI config list could not be changed because:%s", err.Error())
+  }
+  os.Remove(filepath.Join(cniTestConfigDir, cniTestTraceFile))
+  err = cnidel.DelegateInterfaceDelete(context.Background(), &cniConf, testNet, testEp)
+  if err != nil {
+    t.Errorf("DEL of the plugin chain failed with error:%s", err.Error())
+  }
+  checkTrace(t, []string{"DEL:portmap", "DEL:tuning", "DEL:bridge"})
+  rec, err = store.FindDelegate(testEp.Spec.CID, testEp.Spec.Iface.Name)
+  if err != nil || rec != nil {
+    t.Errorf("Cached config of the plugin chain was not removed during DEL, cached record:%v, error:%v", rec, err)
+  }
+  err = teardownDelTest()
+  if err != nil {
+    t.Errorf("Test suite setup could not be reversed because:%s", err.Error())
+  }
+}
+
+type hostLocalFile struct {
+  network string
+  ip string
+  content string
+  age time.Duration
+}
+
+var freeDelegatedIpsTcs = []struct {
+  tcName string
+  file hostLocalFile
+  isRemoved bool
+}{
+  {"matchingCidAndIface", hostLocalFile{"calico", "10.1.1.5", "freecid\r\neth0", 0}, true},
+  {"matchingCidOtherIface", hostLocalFile{"calico", "10.1.1.6", "freecid\r\neth1", 0}, false},
+  {"otherCid", hostLocalFile{"calico", "10.1.1.7", "othercid\r\neth0", 0}, false},
+  {"legacyMatchingIp", hostLocalFile{"cbr0", "10.244.1.5", "freecid", 0}, true},
+  {"legacyOtherCidSameIp", hostLocalFile{"other", "10.244.1.5", "othercid", 0}, false},
+  {"legacyMatchingIpv6", hostLocalFile{"cbr0", "2a00:8a00::5", "freecid", 0}, true},
+}
+
+func TestFreeDelegatedIps(t *testing.T) {
+  os.RemoveAll(cniTestHostLocalDir)
+  defer os.RemoveAll(cniTestHostLocalDir)
+  for _, tc := range freeDelegatedIpsTcs {
+    err := createHostLocalFile(tc.file)
+    if err != nil {
+      t.Errorf("host-local reservation could not be created because:%s", err.Error())
+    }
+  }
+  testEp := &danmtypes.DanmEp{Spec: danmtypes.DanmEpSpec{CID: "freecid", Iface: danmtypes.DanmEpIface{Name: "eth0", Address: "10.244.1.5/24", AddressIPv6: "2a00:8a00::5/64"}}}
+  err := cnidel.FreeDelegatedIps(&cniConf, testEp)
+  if err != nil {
+    t.Errorf("FreeDelegatedIps failed with error:%s", err.Error())
+  }
+  for _, tc := range freeDelegatedIpsTcs {
+    t.Run(tc.tcName, func(t *testing.T) {
+      checkHostLocalFile(t, tc.file, tc.isRemoved)
+    })
+  }
+}
+
+var staleReservationTcs = []struct {
+  tcName string
+  file hostLocalFile
+  isRemoved bool
+}{
+  {"staleCid", hostLocalFile{"calico", "10.1.1.5", "deadcid\r\neth0", time.Hour}, true},
+  {"staleLegacyCid", hostLocalFile{"cbr0", "10.244.1.5", "deadcid", time.Hour}, true},
+  {"liveCid", hostLocalFile{"calico", "10.1.1.6", "livecid\r\neth0", time.Hour}, false},
+  {"staleCidInGracePeriod", hostLocalFile{"calico", "10.1.1.7", "newcid\r\neth0", time.Minute}, false},
+  {"emptyReservation", hostLocalFile{"calico", "10.1.1.8", "", time.Hour}, false},
+  {"lastReservedIpFile", hostLocalFile{"calico", "last_reserved_ip.0", "10.1.1.8", time.Hour}, false},
+}
+
+func TestCleanupStaleHostLocalReservations(t *testing.T) {
+  os.RemoveAll(cniTestHostLocalDir)
+  defer os.RemoveAll(cniTestHostLocalDir)
+  for _, tc := range staleReservationTcs {
+    err := createHostLocalFile(tc.file)
+    if err != nil {
+      t.Errorf("host-local reservation could not be created because:%s", err.Error())
+    }
+  }
+  removed, err := cnidel.CleanupStaleHostLocalReservations(&cniConf, map[string]bool{"livecid": true})
+  if err != nil {
+    t.Errorf("CleanupStaleHostLocalReservations failed with error:%s", err.Error())
+  }
+  if len(removed) != 2 {
+    t.Errorf("Number of removed reservations:%d does not match with the expected:2, removed reservations:%v", len(removed), removed)
+  }
+  for _, tc := range staleReservationTcs {
+    t.Run(tc.tcName, func(t *testing.T) {
+      checkHostLocalFile(t, tc.file, tc.isRemoved)
+    })
+  }
+}
+
+func TestCleanupStaleHostLocalReservationsWithoutDataDir(t *testing.T) {
+  os.RemoveAll(cniTestHostLocalDir)
+  removed, err := cnidel.CleanupStaleHostLocalReservations(&cniConf, map[string]bool{})
+  if err != nil || len(removed) != 0 {
+    t.Errorf("Missing host-local data directory shall not be an error, removed reservations:%v, error:%v", removed, err)
+  }
+}
+
+func TestCleanupSkipsDirectoriesWithoutHostLocalLock(t *testing.T) {
+  os.RemoveAll(cniTestHostLocalDir)
+  defer os.RemoveAll(cniTestHostLocalDir)
+  file := hostLocalFile{"notowned", "10.1.1.5", "deadcid", time.Hour}
+  err := createHostLocalFile(file)
+  if err != nil {
+    t.Fatalf("host-local reservation could not be created because:%s", err.Error())
+  }
+  lockPath := filepath.Join(cniTestHostLocalDir, file.network, "lock")
+  os.Remove(lockPath)
+  _, err = cnidel.CleanupStaleHostLocalReservations(&cniConf, map[string]bool{})
+  if err != nil {
+    t.Errorf("CleanupStaleHostLocalReservations failed with error:%s", err.Error())
+  }
+  checkHostLocalFile(t, file, false)
+  _, err = os.Lstat(lockPath)
+  if err == nil {
+    t.Errorf("lock file was created in directory:%s not owned by host-local", filepath.Dir(lockPath))
+  }
+}
+
+//host-local creates its lock file in every network directory it manages
+func createHostLocalFile(file hostLocalFile) error {
+  netDir := filepath.Join(cniTestHostLocalDir, file.network)
+  err := os.MkdirAll(netDir, os.ModePerm)
+  if err != nil {
+    return err
+  }
+  err = ioutil.WriteFile(filepath.Join(netDir, "lock"), nil, 0644)
   if err != nil {
     return err
   }
-  err = os.Setenv("CNI_PATH", cniTesterDir)
+  ipFile := filepath.Join(netDir, file.ip)
+  err = ioutil.WriteFile(ipFile, []byte(file.content), 0644)
+  if err != nil {
+    return err
+  }
+  modTime := time.Now().Add(-file.age)
+  return os.Chtimes(ipFile, modTime, modTime)
+}
+
+func checkHostLocalFile(t *testing.T, file hostLocalFile, isRemoved bool) {
+  ipFile := filepath.Join(cniTestHostLocalDir, file.network, file.ip)
+  _, err := os.Lstat(ipFile)
+  if isRemoved && err == nil {
+    t.Errorf("host-local reservation:%s was expected to be removed, but it still exists", ipFile)
+  }
+  if !isRemoved && err != nil {
+    t.Errorf("host-local reservation:%s was not expected to be removed, but:%s", ipFile, err.Error())
+  }
+}
+
+func checkTrace(t *testing.T, expectedTrace []string) {
+  rawTrace, _ := ioutil.ReadFile(filepath.Join(cniTestConfigDir, cniTestTraceFile))
+  trace := strings.Fields(string(rawTrace))
+  if strings.Join(trace, ",") != strings.Join(expectedTrace, ",") {
+    t.Errorf("Plugins of the chain were invoked in order:%v instead of the expected:%v", trace, expectedTrace)
+  }
+}
+
+func setupDelTest(opType string) error {
+  os.RemoveAll(cniTestConfigDir)
+  os.RemoveAll(cniTestStoreDir)
+  err := os.MkdirAll(cniTestConfigDir, os.ModePerm)
   if err != nil {
     return err
   }
@@ -417,21 +986,19 @@ func setupDelTest(opType string) error {
   if err != nil {
     return err
   }
-  err = os.Setenv("CNI_CONTAINERID", "12346")
+  cniConf.CniEnv = datastructs.CniEnv{ContainerId: "12346", Netns: "argsdfhtz", Path: cniTesterDir}
+  cniTester := filepath.Join(cniTesterDir, "cnitest")
+  err = buildCniTester(cniTester)
   if err != nil {
     return err
   }
-  err = os.Setenv("CNI_NETNS", "argsdfhtz")
+  input, err := ioutil.ReadFile(cniTester)
   if err != nil {
     return err
   }
-  testPlugins := [4]string{"flannel","macvlan","sriov","bridge"}
+  testPlugins := [9]string{"flannel","sriov","bridge","tuning","portmap","host-device","ovs","templated","templated-device"}
   for _, plugin := range testPlugins {
     os.RemoveAll(filepath.Join(cniTesterDir, plugin))
-    input, err := ioutil.ReadFile(filepath.Join(os.Getenv("GOPATH"),"bin","cnitest"))
-    if err != nil {
-      return err
-    }
     err = ioutil.WriteFile(filepath.Join(cniTesterDir, plugin), input, 777)
     if err != nil {
       return err
@@ -443,6 +1010,10 @@ func setupDelTest(opType string) error {
       return err
     }
   }
+  err = setupBackends()
+  if err != nil {
+    return err
+  }
   err = utils.SetupAllocationPools(testNets)
   if err != nil {
     return err
@@ -456,6 +1027,33 @@ func setupDelTest(opType string) error {
   return nil
 }
 
+func setupBackends() error {
+  os.RemoveAll(cniTestBackendDir)
+  err := os.MkdirAll(cniTestBackendDir, os.ModePerm)
+  if err != nil {
+    return err
+  }
+  for _, def := range testBackendDefinitions {
+    err = ioutil.WriteFile(filepath.Join(cniTestBackendDir, def.ConfName), def.Conftent, 0666)
+    if err != nil {
+      return err
+    }
+  }
+  return cnidel.LoadBackends(cniTestBackendDir)
+}
+
+//The tester binary is always built from the sources, so it cannot get out of sync with cmd/cnitest
+func buildCniTester(path string) error {
+  build := exec.Command("go", "build", "-o", path, "github.com/nokia/danm/cmd/cnitest")
+  //Test cases change the working directory, so the build is run from the one the suite was started in
+  build.Dir = testSourceDir
+  output, err := build.CombinedOutput()
+  if err != nil {
+    return errors.New("cnitest could not be built because:" + err.Error() + ", output:" + string(output))
+  }
+  return nil
+}
+
 func setupDelTestTc(expectedCniConfig string) error {
   var expectedConf CniConf
   for _, conf := range expectedCniConfigs {
@@ -0,0 +1,62 @@
+package cnierrors_test
+
+import (
+  "errors"
+  "testing"
+  "github.com/containernetworking/cni/pkg/types"
+  "github.com/nokia/danm/pkg/cnierrors"
+)
+
+var wrapTcs = []struct {
+  tcName string
+  err error
+  expectedCode uint
+  expectedMsg string
+}{
+  {"untypedError", errors.New("cause"), types.ErrInternal, "prefix:cause:suffix"},
+  {"typedError", cnierrors.New(cnierrors.ErrPoolExhausted, "cause"), cnierrors.ErrPoolExhausted, "prefix:cause:suffix"},
+}
+
+func TestWrapAndAppend(t *testing.T) {
+  for _, tc := range wrapTcs {
+    t.Run(tc.tcName, func(t *testing.T) {
+      err := cnierrors.Append(cnierrors.Wrap(tc.err, "prefix:"), ":suffix")
+      if cnierrors.CodeOf(err) != tc.expectedCode {
+        t.Errorf("Code of the wrapped error:%d does not match with the expected:%d", cnierrors.CodeOf(err), tc.expectedCode)
+      }
+      msg := err.Error()
+      if cniErr, isCniError := err.(*types.Error); isCniError {
+        msg = cniErr.Msg
+      }
+      if msg != tc.expectedMsg {
+        t.Errorf("Message of the wrapped error:%s does not match with the expected:%s", msg, tc.expectedMsg)
+      }
+    })
+  }
+}
+
+func TestReason(t *testing.T) {
+  if cnierrors.Reason(cnierrors.ErrTenantNotAllowed) != "TenantNotAllowed" {
+    t.Errorf("Reason of a known error code:%s does not match with the expected TenantNotAllowed", cnierrors.Reason(cnierrors.ErrTenantNotAllowed))
+  }
+  if cnierrors.Reason(types.ErrInternal) != "NetworkAttachmentFailed" {
+    t.Errorf("Reason of an unknown error code:%s does not match with the expected generic reason", cnierrors.Reason(types.ErrInternal))
+  }
+}
+
+func TestNewWithDetails(t *testing.T) {
+  err := cnierrors.NewWithDetails(cnierrors.ErrDelegateFailed, "CNI delegation failed", "dnet", errors.New("plugin crashed"))
+  if cnierrors.MessageOf(err) != "CNI delegation failed" {
+    t.Errorf("Message of the error:%s does not match with the expected:CNI delegation failed", cnierrors.MessageOf(err))
+  }
+  if cnierrors.DetailsOf(err) != "network:dnet, cause:plugin crashed" {
+    t.Errorf("Details of the error:%s do not contain the failed network and the cause", cnierrors.DetailsOf(err))
+  }
+  wrappedErr := cnierrors.Wrap(err, "prefix:")
+  if cnierrors.DetailsOf(wrappedErr) != cnierrors.DetailsOf(err) {
+    t.Errorf("Details of the wrapped error:%s were not kept", cnierrors.DetailsOf(wrappedErr))
+  }
+  if cnierrors.DetailsOf(errors.New("untyped")) != "" || cnierrors.MessageOf(errors.New("untyped")) != "untyped" {
+    t.Errorf("Untyped errors are not expected to have details")
+  }
+}
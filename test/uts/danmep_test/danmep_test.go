@@ -0,0 +1,88 @@
+package danmep_test
+
+import (
+  "context"
+  "net"
+  "testing"
+  danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
+  danmclientset "github.com/nokia/danm/crd/client/clientset/versioned"
+  "github.com/nokia/danm/crd/client/clientset/versioned/fake"
+  "github.com/nokia/danm/pkg/bitarray"
+  "github.com/nokia/danm/pkg/danmep"
+  "github.com/nokia/danm/pkg/datastructs"
+  "github.com/nokia/danm/pkg/ipam"
+  "github.com/nokia/danm/pkg/journal"
+  "github.com/nokia/danm/test/utils"
+  corev1 "k8s.io/api/core/v1"
+  meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+  testNamespace = "default"
+)
+
+var rollbackTcs = []struct {
+  tcName string
+  netName string
+  cidr string
+  requestedIp string
+}{
+  {"staticIp", "rollback-static", "10.20.0.0/24", "10.20.0.10"},
+  {"dynamicIp", "rollback-dynamic", "10.30.0.0/24", "dynamic"},
+}
+
+func TestIpReservationRollback(t *testing.T) {
+  for _, tc := range rollbackTcs {
+    t.Run(tc.tcName, func(t *testing.T) {
+      dnet := danmtypes.DanmNet {
+        ObjectMeta: meta_v1.ObjectMeta{Name: tc.netName, Namespace: testNamespace},
+        TypeMeta: meta_v1.TypeMeta{Kind: "DanmNet"},
+        Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: tc.netName, Options: danmtypes.DanmNetOption{Device: "ens1f0", Cidr: tc.cidr}},
+      }
+      utils.InitAllocPool(&dnet)
+      client := fake.NewSimpleClientset(&dnet)
+      steps := journal.NewJournal()
+      iface := datastructs.Interface{Network: tc.netName, Ip: tc.requestedIp, DefaultIfaceName: "eth", SequenceId: 1}
+      ep, _, err := danmep.CreateDanmEp(context.TODO(), steps, client, "", true, &dnet, iface, newTestArgs())
+      if err != nil {
+        t.Fatalf("DanmEp could not be created because:%v", err)
+      }
+      if !isIpReserved(t, client, tc.netName, ep.Spec.Iface.Address) {
+        t.Fatalf("IP:%s was not reserved in the API server", ep.Spec.Iface.Address)
+      }
+      err = steps.Rollback(context.TODO())
+      if err != nil {
+        t.Fatalf("rollback failed with error:%v", err)
+      }
+      if isIpReserved(t, client, tc.netName, ep.Spec.Iface.Address) {
+        t.Errorf("IP:%s is still reserved in the API server after rollback", ep.Spec.Iface.Address)
+      }
+      eps, _ := client.DanmV1().DanmEps(testNamespace).List(context.TODO(), meta_v1.ListOptions{})
+      if len(eps.Items) != 0 {
+        t.Errorf("DanmEp was not deleted during rollback")
+      }
+    })
+  }
+}
+
+func newTestArgs() *datastructs.CniArgs {
+  return &datastructs.CniArgs {
+    Namespace: testNamespace,
+    PodName: "test-pod",
+    ContainerId: "test-cid",
+    Pod: &corev1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: "test-pod", Namespace: testNamespace, UID: "test-uid"}},
+  }
+}
+
+func isIpReserved(t *testing.T, client danmclientset.Interface, netName, cidr string) bool {
+  dnet, err := client.DanmV1().DanmNets(testNamespace).Get(context.TODO(), netName, meta_v1.GetOptions{})
+  if err != nil {
+    t.Fatalf("network:%s could not be read from the API server because:%v", netName, err)
+  }
+  ip, _, err := net.ParseCIDR(cidr)
+  if err != nil {
+    t.Fatalf("reserved address:%s is invalid", cidr)
+  }
+  _, subnet, _ := net.ParseCIDR(dnet.Spec.Options.Cidr)
+  return bitarray.NewBitArrayFromBase64(dnet.Spec.Options.Alloc).Get(ipam.GetIndexOfIp(ip, subnet))
+}
@@ -0,0 +1,86 @@
+package danmep_test
+
+import (
+  "context"
+  "testing"
+  danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
+  "github.com/nokia/danm/crd/client/clientset/versioned/fake"
+  "github.com/nokia/danm/pkg/danmep"
+  "github.com/nokia/danm/pkg/datastructs"
+  "github.com/nokia/danm/pkg/journal"
+  meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+  "k8s.io/apimachinery/pkg/runtime"
+)
+
+var (
+  proutes = map[string]string{"10.10.0.0/16": "10.0.0.1"}
+)
+
+type rtableTest struct {
+  tcName string
+  netName string
+  vrf string
+  rtable int
+  proutes map[string]string
+  expectedRTable int
+  isErrorExpected bool
+}
+
+//The interfaces are created one after the other into the same Pod, so every case sees the tables of the earlier ones
+var rtableTcs = []rtableTest {
+  {"noPolicyRoutes", "plain", "", 0, nil, 0, false},
+  {"firstAllocatedTable", "auto1", "", 0, proutes, 1, false},
+  {"secondAllocatedTable", "auto2", "", 0, proutes, 2, false},
+  {"explicitTableOfNoOther", "explicit5", "", 5, proutes, 5, false},
+  {"explicitTableAlreadyAllocated", "explicit2", "", 2, proutes, 0, true},
+  {"explicitTableAlreadyExplicit", "explicit5", "", 5, proutes, 0, true},
+  {"vrfTable", "vrf1a", "vrf1", 3, nil, 3, false},
+  {"vrfTableSharedInVrf", "vrf1b", "vrf1", 3, proutes, 3, false},
+  {"vrfTableOfOtherVrf", "vrf2", "vrf2", 3, nil, 0, true},
+  {"vrfTableOfInterface", "vrf3", "vrf3", 5, nil, 0, true},
+  {"explicitTableOfVrf", "explicit3", "", 3, proutes, 0, true},
+  {"allocationSkipsUsedTables", "auto3", "", 0, proutes, 4, false},
+}
+
+var rangeTcs = []rtableTest {
+  {"firstTableOfRange", "range1", "", 0, proutes, 100, false},
+  {"explicitTableInRange", "range2", "", 101, proutes, 101, false},
+  {"rangeExhausted", "range3", "", 0, proutes, 0, true},
+}
+
+func TestRoutingTableAllocation(t *testing.T) {
+  runRTableTcs(t, rtableTcs)
+}
+
+func TestRoutingTableAllocationFromRange(t *testing.T) {
+  tconf := &danmtypes.TenantConfig{ObjectMeta: meta_v1.ObjectMeta{Name: "tconf"}, RoutingTables: "100-101"}
+  runRTableTcs(t, rangeTcs, tconf)
+}
+
+func runRTableTcs(t *testing.T, tcs []rtableTest, objects ...runtime.Object) {
+  client := fake.NewSimpleClientset(objects...)
+  for seqId, tc := range tcs {
+    t.Run(tc.tcName, func(t *testing.T) {
+      dnet := danmtypes.DanmNet {
+        ObjectMeta: meta_v1.ObjectMeta{Name: tc.netName, Namespace: testNamespace},
+        TypeMeta: meta_v1.TypeMeta{Kind: "DanmNet"},
+        Spec: danmtypes.DanmNetSpec{NetworkType: "ipvlan", NetworkID: tc.netName, Options: danmtypes.DanmNetOption{Device: "ens1f0", Vrf: tc.vrf, RTables: tc.rtable}},
+      }
+      client.DanmV1().DanmNets(testNamespace).Create(context.TODO(), &dnet, meta_v1.CreateOptions{})
+      iface := datastructs.Interface{Network: tc.netName, Proutes: tc.proutes, DefaultIfaceName: "eth", SequenceId: seqId}
+      ep, _, err := danmep.CreateDanmEp(context.TODO(), journal.NewJournal(), client, "", false, &dnet, iface, newTestArgs())
+      if (err != nil && !tc.isErrorExpected) || (err == nil && tc.isErrorExpected) {
+        t.Fatalf("received error:%v does not match with expectation", err)
+      }
+      if err != nil {
+        return
+      }
+      if ep.Spec.Iface.RTable != tc.expectedRTable {
+        t.Errorf("routing table of the interface:%d does not match with the expected:%d", ep.Spec.Iface.RTable, tc.expectedRTable)
+      }
+      if ep.Spec.Iface.Vrf != tc.vrf {
+        t.Errorf("VRF of the interface:%s does not match with the VRF of its network:%s", ep.Spec.Iface.Vrf, tc.vrf)
+      }
+    })
+  }
+}
@@ -0,0 +1,88 @@
+package danmep_test
+
+import (
+  "net"
+  "syscall"
+  "testing"
+  "time"
+  danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
+  "github.com/nokia/danm/pkg/danmep"
+  "github.com/vishvananda/netlink"
+  meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var formatRouteTcs = []struct {
+  tcName string
+  route netlink.Route
+  expectedRoute string
+}{
+  {"defaultRoute", netlink.Route{Gw: net.ParseIP("10.0.0.1"), Table: syscall.RT_TABLE_MAIN}, "default via 10.0.0.1"},
+  {"onLinkRoute", netlink.Route{Dst: parseNet("10.0.0.0/24"), Table: syscall.RT_TABLE_MAIN}, "10.0.0.0/24"},
+  {"policyRoute", netlink.Route{Dst: parseNet("10.1.0.0/16"), Gw: net.ParseIP("10.0.0.1"), Table: 100}, "10.1.0.0/16 via 10.0.0.1 table 100"},
+  {"routeWithMetric", netlink.Route{Dst: parseNet("fd00::/64"), Gw: net.ParseIP("fd00::1"), Table: syscall.RT_TABLE_MAIN, Priority: 200}, "fd00::/64 via fd00::1 metric 200"},
+  {"ecmpRoute", netlink.Route{Dst: parseNet("10.2.0.0/16"), Table: syscall.RT_TABLE_MAIN, MultiPath: []*netlink.NexthopInfo{{Gw: net.ParseIP("10.0.0.1")}, {Gw: net.ParseIP("10.0.0.2"), Hops: 2}}}, "10.2.0.0/16 nexthop via 10.0.0.1 weight 1 nexthop via 10.0.0.2 weight 3"},
+}
+
+var routeOfLinkTcs = []struct {
+  tcName string
+  route netlink.Route
+  isRouteOfLink bool
+}{
+  {"directRoute", netlink.Route{LinkIndex: 5}, true},
+  {"routeOfOtherLink", netlink.Route{LinkIndex: 6}, false},
+  {"nexthopOfLink", netlink.Route{MultiPath: []*netlink.NexthopInfo{{LinkIndex: 6}, {LinkIndex: 5}}}, true},
+  {"nexthopsOfOtherLinks", netlink.Route{MultiPath: []*netlink.NexthopInfo{{LinkIndex: 6}, {LinkIndex: 7}}}, false},
+}
+
+var (
+  lastWrite = meta_v1.NewTime(time.Now())
+  writtenStatus = danmtypes.DanmEpStatus{OperState: "up", Carrier: true, MTU: 1500, Addresses: []string{"10.0.0.5/24"}, Statistics: &danmtypes.DanmEpStats{RxBytes: 100}, LastUpdated: lastWrite}
+)
+
+var statusChangeTcs = []struct {
+  tcName string
+  newStatus danmtypes.DanmEpStatus
+  isChanged bool
+}{
+  {"unchanged", danmtypes.DanmEpStatus{OperState: "up", Carrier: true, MTU: 1500, Addresses: []string{"10.0.0.5/24"}, Routes: []string{}, Statistics: &danmtypes.DanmEpStats{RxBytes: 100}, LastUpdated: meta_v1.NewTime(lastWrite.Add(time.Hour))}, false},
+  {"operStateChanged", danmtypes.DanmEpStatus{OperState: "down", Carrier: true, MTU: 1500, Addresses: []string{"10.0.0.5/24"}, Statistics: &danmtypes.DanmEpStats{RxBytes: 100}, LastUpdated: meta_v1.NewTime(lastWrite.Add(time.Second))}, true},
+  {"routeAdded", danmtypes.DanmEpStatus{OperState: "up", Carrier: true, MTU: 1500, Addresses: []string{"10.0.0.5/24"}, Routes: []string{"default via 10.0.0.1"}, Statistics: &danmtypes.DanmEpStats{RxBytes: 100}, LastUpdated: meta_v1.NewTime(lastWrite.Add(time.Second))}, true},
+  {"onlyCountersChangedRecently", danmtypes.DanmEpStatus{OperState: "up", Carrier: true, MTU: 1500, Addresses: []string{"10.0.0.5/24"}, Statistics: &danmtypes.DanmEpStats{RxBytes: 200}, LastUpdated: meta_v1.NewTime(lastWrite.Add(time.Second))}, false},
+  {"onlyCountersChangedLongAgo", danmtypes.DanmEpStatus{OperState: "up", Carrier: true, MTU: 1500, Addresses: []string{"10.0.0.5/24"}, Statistics: &danmtypes.DanmEpStats{RxBytes: 200}, LastUpdated: meta_v1.NewTime(lastWrite.Add(time.Hour))}, true},
+}
+
+func TestFormatRoute(t *testing.T) {
+  for _, tc := range formatRouteTcs {
+    t.Run(tc.tcName, func(t *testing.T) {
+      routeStr := danmep.FormatRoute(tc.route)
+      if routeStr != tc.expectedRoute {
+        t.Errorf("route was formatted as:%s instead of the expected:%s", routeStr, tc.expectedRoute)
+      }
+    })
+  }
+}
+
+func TestIsRouteOfLink(t *testing.T) {
+  for _, tc := range routeOfLinkTcs {
+    t.Run(tc.tcName, func(t *testing.T) {
+      if danmep.IsRouteOfLink(tc.route, 5) != tc.isRouteOfLink {
+        t.Errorf("route:%v was not expected to be a route of the link:%t", tc.route, !tc.isRouteOfLink)
+      }
+    })
+  }
+}
+
+func TestIsStatusChanged(t *testing.T) {
+  for _, tc := range statusChangeTcs {
+    t.Run(tc.tcName, func(t *testing.T) {
+      if danmep.IsStatusChanged(&writtenStatus, &tc.newStatus, 5*time.Minute) != tc.isChanged {
+        t.Errorf("status change was expected to be:%t", tc.isChanged)
+      }
+    })
+  }
+}
+
+func parseNet(cidr string) *net.IPNet {
+  _, ipnet, _ := net.ParseCIDR(cidr)
+  return ipnet
+}
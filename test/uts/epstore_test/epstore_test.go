@@ -0,0 +1,237 @@
+package epstore_test
+
+import (
+  "io/ioutil"
+  "os"
+  "sort"
+  "strings"
+  "testing"
+  "time"
+  "github.com/containernetworking/cni/pkg/types/current"
+  meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+  danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
+  "github.com/nokia/danm/pkg/epstore"
+)
+
+var testRecords = []epstore.Record {
+  {Ep: createEp("ep1", "cid1", "eth0"), Network: createNet("net1"), Result: &current.Result{CNIVersion: "0.3.1"}},
+  {Ep: createEp("ep2", "cid1", "eth1"), Network: createNet("net2")},
+  {Ep: createEp("ep3", "cid2", "eth0"), Network: createNet("net1")},
+}
+
+func createEp(name, cid, ifName string) danmtypes.DanmEp {
+  return danmtypes.DanmEp {
+    ObjectMeta: meta_v1.ObjectMeta{Name: name},
+    Spec: danmtypes.DanmEpSpec{CID: cid, Iface: danmtypes.DanmEpIface{Name: ifName}},
+  }
+}
+
+func createNet(name string) danmtypes.DanmNet {
+  return danmtypes.DanmNet{ObjectMeta: meta_v1.ObjectMeta{Name: name}, Spec: danmtypes.DanmNetSpec{NetworkID: name}}
+}
+
+func setupStore(t *testing.T) (*epstore.Store, func()) {
+  testDir, err := ioutil.TempDir("", "epstore")
+  if err != nil {
+    t.Fatalf("temporary directory could not be created because:%v", err)
+  }
+  store := epstore.NewStore(testDir)
+  for index := range testRecords {
+    err = store.Save(&testRecords[index])
+    if err != nil {
+      t.Fatalf("record could not be saved because:%v", err)
+    }
+  }
+  return store, func() {os.RemoveAll(testDir)}
+}
+
+func TestSave(t *testing.T) {
+  store, cleanup := setupStore(t)
+  defer cleanup()
+  err := store.Save(&epstore.Record{Ep: createEp("ep4", "", "eth0")})
+  if err == nil {
+    t.Errorf("record without container ID was saved")
+  }
+}
+
+func TestFindByCid(t *testing.T) {
+  store, cleanup := setupStore(t)
+  defer cleanup()
+  recs, err := store.FindByCid("cid1")
+  if err != nil {
+    t.Fatalf("records could not be read because:%v", err)
+  }
+  if len(recs) != 2 {
+    t.Fatalf("number of stored records:%d does not match with the expected:2", len(recs))
+  }
+  for _, rec := range recs {
+    if rec.Ep.Spec.CID != "cid1" {
+      t.Errorf("record of DanmEp:%s belongs to another CID:%s", rec.Ep.ObjectMeta.Name, rec.Ep.Spec.CID)
+    }
+    if rec.Ep.ObjectMeta.Name == "ep1" && (rec.Result == nil || rec.Result.CNIVersion != "0.3.1" || rec.Network.Spec.NetworkID != "net1") {
+      t.Errorf("record of DanmEp:ep1 was not stored correctly:%v", rec)
+    }
+  }
+  recs, err = store.FindByCid("nonexistent")
+  if err != nil || len(recs) != 0 {
+    t.Errorf("records were found for a non-existent CID:%v, error:%v", recs, err)
+  }
+}
+
+func TestRemove(t *testing.T) {
+  store, cleanup := setupStore(t)
+  defer cleanup()
+  err := store.Remove("cid1", "eth0")
+  if err != nil {
+    t.Fatalf("record could not be removed because:%v", err)
+  }
+  recs, _ := store.FindByCid("cid1")
+  if len(recs) != 1 || recs[0].Ep.Spec.Iface.Name != "eth1" {
+    t.Errorf("remaining records:%v do not match with expectation", recs)
+  }
+  err = store.Remove("cid1", "eth0")
+  if err != nil {
+    t.Errorf("removal of a non-existent record failed with error:%v", err)
+  }
+}
+
+func TestPendingReleases(t *testing.T) {
+  store, cleanup := setupStore(t)
+  defer cleanup()
+  store.QueueRelease(&testRecords[0])
+  store.QueueRelease(&testRecords[2])
+  recs, err := store.PendingReleases()
+  if err != nil || len(recs) != 2 {
+    t.Fatalf("queued releases:%v do not match with expectation, error:%v", recs, err)
+  }
+  store.RemovePendingRelease("ep1")
+  recs, _ = store.PendingReleases()
+  if len(recs) != 1 || recs[0].Ep.ObjectMeta.Name != "ep3" {
+    t.Errorf("remaining queued releases:%v do not match with expectation", recs)
+  }
+  recs, _ = store.FindByCid("cid2")
+  if len(recs) != 1 {
+    t.Errorf("queued releases must not affect the records of existing interfaces")
+  }
+}
+
+func TestDelegates(t *testing.T) {
+  store, cleanup := setupStore(t)
+  defer cleanup()
+  rec := epstore.DelegateRecord{IfName: "eth0", Plugins: []epstore.DelegatePlugin{{Type: "bridge", Config: []byte(`{"cniVersion":"0.4.0","name":"net1","type":"bridge"}`)}}, Result: &current.Result{CNIVersion: "0.4.0"}}
+  err := store.SaveDelegate("cid1", "eth0", &rec)
+  if err != nil {
+    t.Fatalf("delegate config could not be saved because:%v", err)
+  }
+  err = store.SaveDelegate("", "eth0", &rec)
+  if err == nil {
+    t.Errorf("delegate config without container ID was saved")
+  }
+  storedRec, err := store.FindDelegate("cid1", "eth0")
+  if err != nil || storedRec == nil || storedRec.IfName != "eth0" || len(storedRec.Plugins) != 1 || string(storedRec.Plugins[0].Config) != string(rec.Plugins[0].Config) || storedRec.Result == nil {
+    t.Errorf("stored delegate config:%v does not match with expectation, error:%v", storedRec, err)
+  }
+  recs, err := store.FindDelegatesByCid("cid1")
+  if err != nil || len(recs) != 1 {
+    t.Errorf("stored delegate configs:%v do not match with expectation, error:%v", recs, err)
+  }
+  interfaceRecs, _ := store.FindByCid("cid1")
+  if len(interfaceRecs) != 2 {
+    t.Errorf("delegate configs interfere with the records of the interfaces:%v", interfaceRecs)
+  }
+  err = store.RemoveDelegate("cid1", "eth0")
+  if err != nil {
+    t.Fatalf("delegate config could not be removed because:%v", err)
+  }
+  storedRec, err = store.FindDelegate("cid1", "eth0")
+  if err != nil || storedRec != nil {
+    t.Errorf("removed delegate config was still found:%v, error:%v", storedRec, err)
+  }
+}
+
+func TestDeviceReservations(t *testing.T) {
+  store, cleanup := setupStore(t)
+  defer cleanup()
+  unlock, err := store.LockDevices()
+  if err != nil {
+    t.Fatalf("device lock could not be acquired because:%v", err)
+  }
+  defer unlock()
+  err = store.ReserveDevice("ens1f0", "cid1")
+  if err != nil {
+    t.Fatalf("host device could not be reserved because:%v", err)
+  }
+  holder, err := store.FindDeviceReservation("ens1f0")
+  if err != nil || holder != "cid1" {
+    t.Errorf("host device is reserved for:%s instead of the expected cid1, error:%v", holder, err)
+  }
+  err = store.ReleaseDevice("ens1f0", "cid2")
+  if err != nil {
+    t.Errorf("release of a host device reserved for another container failed with error:%v", err)
+  }
+  holder, _ = store.FindDeviceReservation("ens1f0")
+  if holder != "cid1" {
+    t.Errorf("host device was released by a container it was not reserved for")
+  }
+  err = store.ReleaseDevice("ens1f0", "cid1")
+  if err != nil {
+    t.Fatalf("host device could not be released because:%v", err)
+  }
+  holder, _ = store.FindDeviceReservation("ens1f0")
+  if holder != "" {
+    t.Errorf("released host device is still reserved for:%s", holder)
+  }
+}
+
+func TestLockReplay(t *testing.T) {
+  store, cleanup := setupStore(t)
+  defer cleanup()
+  unlock, isLocked, err := store.LockReplay()
+  if err != nil || !isLocked {
+    t.Fatalf("replay lock could not be acquired, error:%v", err)
+  }
+  _, isLockedAgain, err := store.LockReplay()
+  if err != nil || isLockedAgain {
+    t.Errorf("replay lock was acquired twice, error:%v", err)
+  }
+  unlock()
+  unlock, isLocked, err = store.LockReplay()
+  if err != nil || !isLocked {
+    t.Fatalf("replay lock could not be re-acquired after release, error:%v", err)
+  }
+  unlock()
+}
+
+func TestReleaseStaleDevices(t *testing.T) {
+  store, cleanup := setupStore(t)
+  defer cleanup()
+  for device, cid := range map[string]string{"ens1f0": "cid1", "ens1f1": "deleted-cid", "ens1f2": "other-deleted-cid"} {
+    err := store.ReserveDevice(device, cid)
+    if err != nil {
+      t.Fatalf("host device could not be reserved because:%v", err)
+    }
+  }
+  liveCids := map[string]bool{"cid1": true}
+  released, err := store.ReleaseStaleDevices(liveCids, time.Hour)
+  if err != nil || len(released) != 0 {
+    t.Errorf("reservations younger than the grace period were released:%v, error:%v", released, err)
+  }
+  released, err = store.ReleaseStaleDevices(liveCids, -time.Hour)
+  if err != nil {
+    t.Fatalf("stale host device reservations could not be released because:%v", err)
+  }
+  sort.Strings(released)
+  if strings.Join(released, ",") != "ens1f1,ens1f2" {
+    t.Errorf("released host devices:%v do not match with the expected:[ens1f1 ens1f2]", released)
+  }
+  holder, _ := store.FindDeviceReservation("ens1f0")
+  if holder != "cid1" {
+    t.Errorf("host device of a live container was released")
+  }
+  for _, device := range []string{"ens1f1", "ens1f2"} {
+    holder, _ = store.FindDeviceReservation(device)
+    if holder != "" {
+      t.Errorf("host device:%s is still reserved for deleted container:%s", device, holder)
+    }
+  }
+}
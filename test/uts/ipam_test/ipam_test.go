@@ -1,6 +1,7 @@
 package ipam_test
 
 import (
+  "context"
   "os"
   "strconv"
   "testing"
@@ -115,7 +116,7 @@ func TestReserve(t *testing.T) {
       ips = utils.AppendIpToExpectedAllocsList(ips, tc.expectedIp6, true, testNets[tc.netIndex].Spec.NetworkID)
       testArtifacts := utils.TestArtifacts{TestNets: testNets, ReservedIps: ips}
       netClientStub := stubs.NewClientSetStub(testArtifacts)
-      ip4, ip6, err := ipam.Reserve(netClientStub, testNets[tc.netIndex], tc.requestedIp4, tc.requestedIp6)
+      ip4, ip6, err := ipam.Reserve(context.TODO(), netClientStub, testNets[tc.netIndex], tc.requestedIp4, tc.requestedIp6)
       if (err != nil && !tc.isErrorExpected) || (err == nil && tc.isErrorExpected) {
         t.Errorf("Received error:%v does not match with expectation", err)
         return
@@ -148,7 +149,7 @@ func TestFree(t *testing.T) {
       ips = utils.AppendIpToExpectedAllocsList(ips, tc.allocatedIp, false, testNets[tc.netIndex].Spec.NetworkID)
       testArtifacts := utils.TestArtifacts{TestNets: testNets, ReservedIps: ips}
       netClientStub := stubs.NewClientSetStub(testArtifacts)
-      err := ipam.Free(netClientStub, testNets[tc.netIndex], tc.allocatedIp)
+      err := ipam.Free(context.TODO(), netClientStub, testNets[tc.netIndex], tc.allocatedIp)
       if (err != nil && !tc.isErrorExpected) || (err == nil && tc.isErrorExpected) {
         t.Errorf("Received error:%v does not match with expectation", err)
         return
@@ -176,11 +177,33 @@ func TestGarbageCollectIps(t *testing.T) {
       ips = utils.AppendIpToExpectedAllocsList(ips, tc.allocatedIp6, false, testNets[tc.netIndex].Spec.NetworkID)
       testArtifacts := utils.TestArtifacts{TestNets: testNets, ReservedIps: ips}
       netClientStub := stubs.NewClientSetStub(testArtifacts)
-      ipam.GarbageCollectIps(netClientStub, &testNets[tc.netIndex], tc.allocatedIp4, tc.allocatedIp6)
+      ipam.GarbageCollectIps(context.TODO(), netClientStub, &testNets[tc.netIndex], tc.allocatedIp4, tc.allocatedIp6)
     })
   }
 }
 
+func TestCancelledReservation(t *testing.T) {
+  err := utils.SetupAllocationPools(testNets)
+  if err != nil {
+    t.Errorf("Allocation pool for testnets could not be set-up because:%v", err)
+  }
+  testArtifacts := utils.TestArtifacts{TestNets: testNets}
+  netClientStub := stubs.NewClientSetStub(testArtifacts)
+  ctx, cancel := context.WithCancel(context.Background())
+  cancel()
+  _, _, err = ipam.Reserve(ctx, netClientStub, testNets[1], "dynamic", "")
+  if err == nil {
+    t.Errorf("IP reservation was expected to fail with a cancelled context")
+  }
+  err = ipam.Free(ctx, netClientStub, testNets[2], "192.168.1.2/30")
+  if err == nil {
+    t.Errorf("freeing an IP was expected to fail with a cancelled context")
+  }
+  if netClientStub.DanmClient.NetClient != nil && netClientStub.DanmClient.NetClient.TimesUpdateWasCalled != 0 {
+    t.Errorf("Network manifest should not have been updated with a cancelled context")
+  }
+}
+
 func TestMain(m *testing.M) {
   code := m.Run()
   os.Exit(code)
@@ -0,0 +1,74 @@
+package journal_test
+
+import (
+  "context"
+  "errors"
+  "strings"
+  "testing"
+  "github.com/nokia/danm/pkg/journal"
+)
+
+type testStep struct {
+  name string
+  undoErr error
+}
+
+var rollbackTcs = []struct {
+  tcName string
+  steps []testStep
+  expectedUndoOrder []string
+  isErrorExpected bool
+  expectedErrorParts []string
+}{
+  {"noSteps", []testStep{}, []string{}, false, nil},
+  {"successfulRollback", []testStep{{"ip", nil}, {"ep", nil}, {"delegate", nil}}, []string{"delegate", "ep", "ip"}, false, nil},
+  {"failingMiddleStep", []testStep{{"ip", nil}, {"ep", errors.New("API is down")}, {"delegate", nil}}, []string{"delegate", "ep", "ip"}, true, []string{"ep", "API is down"}},
+  {"multipleFailingSteps", []testStep{{"ip", errors.New("conflict")}, {"ep", nil}, {"delegate", errors.New("plugin crashed")}}, []string{"delegate", "ep", "ip"}, true, []string{"ip", "conflict", "delegate", "plugin crashed"}},
+}
+
+func TestRollback(t *testing.T) {
+  for _, tc := range rollbackTcs {
+    t.Run(tc.tcName, func(t *testing.T) {
+      steps := journal.NewJournal()
+      var undoOrder []string
+      for _, step := range tc.steps {
+        name, undoErr := step.name, step.undoErr
+        steps.Record(name, func(ctx context.Context) error {
+          undoOrder = append(undoOrder, name)
+          return undoErr
+        })
+      }
+      if len(steps.Steps()) != len(tc.steps) {
+        t.Errorf("Number of recorded steps:%d does not match with the expected:%d", len(steps.Steps()), len(tc.steps))
+      }
+      err := steps.Rollback(context.Background())
+      if (err != nil && !tc.isErrorExpected) || (err == nil && tc.isErrorExpected) {
+        t.Fatalf("Received error:%v does not match with expectation", err)
+      }
+      for _, errorPart := range tc.expectedErrorParts {
+        if !strings.Contains(err.Error(), errorPart) {
+          t.Errorf("Rollback error:%s does not contain the expected part:%s", err.Error(), errorPart)
+        }
+      }
+      if strings.Join(undoOrder, ",") != strings.Join(tc.expectedUndoOrder, ",") {
+        t.Errorf("Steps were undone in order:%v instead of the expected:%v", undoOrder, tc.expectedUndoOrder)
+      }
+      if len(steps.Steps()) != 0 {
+        t.Errorf("Journal still contains steps:%v after rollback", steps.Steps())
+      }
+      undoOrder = nil
+      steps.Rollback(context.Background())
+      if len(undoOrder) != 0 {
+        t.Errorf("Steps:%v were undone twice", undoOrder)
+      }
+    })
+  }
+}
+
+func TestNilJournal(t *testing.T) {
+  var steps *journal.Journal
+  steps.Record("ip", func(ctx context.Context) error {return errors.New("must not be called")})
+  if steps.Rollback(context.Background()) != nil || len(steps.Steps()) != 0 {
+    t.Errorf("A nil Journal shall silently ignore every operation")
+  }
+}
@@ -0,0 +1,82 @@
+package netcontrol_test
+
+import (
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "testing"
+  meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+  danmtypes "github.com/nokia/danm/crd/apis/danm/v1"
+  "github.com/nokia/danm/pkg/netcontrol"
+)
+
+const (
+  fakeOvsVsctl = "#!/bin/sh\necho \"$@\" >> $(dirname $0)/calls\n"
+)
+
+var ovsTcs = []struct {
+  tcName string
+  dnet danmtypes.DanmNet
+  expectedCalls string
+}{
+  {"ovsBridgeAdded", createNet("ovs", "br-data", 500), "--may-exist add-br br-data\n"},
+  {"ovsWithoutBridge", createNet("ovs", "", 0), ""},
+  {"otherNetworkType", createNet("ipvlan", "", 0), ""},
+}
+
+func createNet(neType, device string, vlan int) danmtypes.DanmNet {
+  return danmtypes.DanmNet {
+    ObjectMeta: meta_v1.ObjectMeta{Name: "test-net"},
+    Spec: danmtypes.DanmNetSpec{NetworkType: neType, NetworkID: "testnet", Options: danmtypes.DanmNetOption{Device: device, Vlan: vlan}},
+  }
+}
+
+func setupFakeOvsVsctl(t *testing.T) (string, func()) {
+  testDir, err := ioutil.TempDir("", "netcontrol")
+  if err != nil {
+    t.Fatalf("temporary directory could not be created because:%v", err)
+  }
+  ovsVsctl := filepath.Join(testDir, "ovs-vsctl")
+  err = ioutil.WriteFile(ovsVsctl, []byte(fakeOvsVsctl), 0755)
+  if err != nil {
+    t.Fatalf("fake ovs-vsctl could not be created because:%v", err)
+  }
+  os.Setenv("OVS_VSCTL_PATH", ovsVsctl)
+  return filepath.Join(testDir, "calls"), func() {
+    os.Unsetenv("OVS_VSCTL_PATH")
+    os.RemoveAll(testDir)
+  }
+}
+
+func readCalls(callsFile string) string {
+  calls, err := ioutil.ReadFile(callsFile)
+  if err != nil {
+    return ""
+  }
+  return string(calls)
+}
+
+func TestAddOvsNetwork(t *testing.T) {
+  for _, tc := range ovsTcs {
+    t.Run(tc.tcName, func(t *testing.T) {
+      callsFile, cleanup := setupFakeOvsVsctl(t)
+      defer cleanup()
+      netcontrol.AddDanmNet(&tc.dnet)
+      calls := readCalls(callsFile)
+      if calls != tc.expectedCalls {
+        t.Errorf("ovs-vsctl was called with:%q, but expected:%q", calls, tc.expectedCalls)
+      }
+    })
+  }
+}
+
+func TestDeleteOvsNetworkKeepsBridge(t *testing.T) {
+  callsFile, cleanup := setupFakeOvsVsctl(t)
+  defer cleanup()
+  dnet := createNet("ovs", "br-data", 500)
+  netcontrol.DeleteDanmNet(&dnet)
+  calls := readCalls(callsFile)
+  if calls != "" {
+    t.Errorf("ovs-vsctl shall not be called when an ovs network is deleted, but it was called with:%q", calls)
+  }
+}
@@ -1,10 +1,14 @@
 package syncher_test
 
 import (
+  "context"
   "errors"
+  "strings"
   "testing"
   "time"
+  "github.com/containernetworking/cni/pkg/types"
   "github.com/containernetworking/cni/pkg/types/current"
+  "github.com/nokia/danm/pkg/cnierrors"
   "github.com/nokia/danm/pkg/syncher"
 )
 
@@ -114,6 +118,111 @@ func TestGetAggregatedResultTimeout(t *testing.T) {
   }
 }
 
+func TestGetAggregatedResultWithContextTimeout(t *testing.T) {
+  syncher := setupTest(len(totalSuccessTestConsts)+1, totalSuccessTestConsts)
+  ctx, cancel := context.WithTimeout(context.Background(), 500 * time.Millisecond)
+  defer cancel()
+  startTime := time.Now()
+  err := syncher.GetAggregatedResultWithContext(ctx)
+  timeDifference := time.Now().Sub(startTime)
+  if err != context.DeadlineExceeded {
+    t.Errorf("Received error:%v does not match with the expected deadline exceeded error", err)
+  }
+  if timeDifference < 500 * time.Millisecond || timeDifference >= time.Duration(timeout - 1) * time.Second {
+    t.Errorf("Configured deadline was not respected, aggregation returned after:%v", timeDifference)
+  }
+}
+
+func TestGetAggregatedResultWithContextCancel(t *testing.T) {
+  syncher := setupTest(len(totalSuccessTestConsts)+1, totalSuccessTestConsts)
+  ctx, cancel := context.WithCancel(context.Background())
+  cancel()
+  err := syncher.GetAggregatedResultWithContext(ctx)
+  if err != context.Canceled {
+    t.Errorf("Received error:%v does not match with the expected cancellation error", err)
+  }
+}
+
+func TestWaitForAllResults(t *testing.T) {
+  syncher := setupTest(len(failingTestConsts)+1, failingTestConsts)
+  if syncher.WaitForAllResults(100 * time.Millisecond) {
+    t.Errorf("Waiting for results was successful even though one of them never arrived")
+  }
+  go addResultToSyncher(syncher,result{"ipvlan", errors.New("timed-out"), nil, ""})
+  if !syncher.WaitForAllResults(time.Duration(timeout) * time.Second) {
+    t.Errorf("Waiting for results failed even though all of them arrived")
+  }
+}
+
+var errorCodeTcs = []struct {
+  tcName string
+  results []result
+  expectedCode uint
+}{
+  {"untypedErrors", failingTestConsts, types.ErrInternal},
+  {"sameTypedErrors", []result{{"dnet1", cnierrors.New(cnierrors.ErrPoolExhausted, "no IP"), nil, ""}, {"dnet2", cnierrors.New(cnierrors.ErrPoolExhausted, "no IP either"), nil, ""}}, cnierrors.ErrPoolExhausted},
+  {"typedErrorWithSuccess", []result{{"dnet1", cnierrors.New(cnierrors.ErrTenantNotAllowed, "forbidden"), nil, ""}, {"dnet2", nil, nil, "eth1"}}, cnierrors.ErrTenantNotAllowed},
+  {"differentErrors", []result{{"dnet1", cnierrors.New(cnierrors.ErrNetworkNotFound, "missing"), nil, ""}, {"dnet2", errors.New("untyped"), nil, ""}}, cnierrors.ErrMultipleFailures},
+  {"errorWithDetails", []result{{"dnet1", cnierrors.NewWithDetails(cnierrors.ErrDelegateFailed, "delegation failed", "dnet1", errors.New("plugin crashed")), nil, ""}}, cnierrors.ErrDelegateFailed},
+}
+
+func TestGetAggregatedResultErrorCode(t *testing.T) {
+  for _, tc := range errorCodeTcs {
+    t.Run(tc.tcName, func(t *testing.T) {
+      syncher := setupTest(len(tc.results), tc.results)
+      err := syncher.GetAggregatedResult()
+      cniErr, isCniError := err.(*types.Error)
+      if !isCniError {
+        t.Fatalf("Aggregated error:%v is not a typed CNI error", err)
+      }
+      if cniErr.Code != tc.expectedCode {
+        t.Errorf("Code of the aggregated error:%d does not match with the expected:%d", cniErr.Code, tc.expectedCode)
+      }
+      for _, res := range tc.results {
+        if res.opRes == nil {
+          continue
+        }
+        if !strings.Contains(cniErr.Msg, res.cniName) {
+          t.Errorf("Aggregated error message:%s does not name failed network:%s", cniErr.Msg, res.cniName)
+        }
+        if !strings.Contains(cniErr.Details, "network:" + res.cniName) || !strings.Contains(cniErr.Details, cnierrors.DetailsOf(res.opRes)) {
+          t.Errorf("Aggregated error details:%s do not contain the failed network:%s, and its cause", cniErr.Details, res.cniName)
+        }
+      }
+    })
+  }
+}
+
+func TestAddExpectedResults(t *testing.T) {
+  syncher := setupTest(len(totalSuccessTestConsts)-1, totalSuccessTestConsts[:1])
+  syncher.AddExpectedResults(1)
+  if syncher.WaitForAllResults(100 * time.Millisecond) {
+    t.Errorf("Waiting for results was successful even though the additionally expected result never arrived")
+  }
+  go addResultToSyncher(syncher, totalSuccessTestConsts[1])
+  if !syncher.WaitForAllResults(time.Duration(timeout) * time.Second) {
+    t.Errorf("Waiting for results failed even though the additionally expected result arrived")
+  }
+}
+
+func TestGetFailedResults(t *testing.T) {
+  syncher := setupTest(len(failingTestConsts)+1, failingTestConsts)
+  go syncher.PushResult("macvlan", errors.New("pushed while the results are read"), nil, "")
+  failedResults := syncher.GetFailedResults()
+  if len(failedResults) < 2 {
+    t.Fatalf("Number of failed results:%d is less than the number of failures pushed before reading them", len(failedResults))
+  }
+  for _, failedResult := range failedResults {
+    if failedResult.OpResult == nil {
+      t.Errorf("Successful result of network:%s was returned as a failure", failedResult.CniName)
+    }
+  }
+  syncher.WaitForAllResults(time.Second)
+  if len(syncher.GetFailedResults()) != 3 {
+    t.Errorf("Number of failed results:%d does not match with the expected 3", len(syncher.GetFailedResults()))
+  }
+}
+
 func TestMergeCniResults(t *testing.T) {
   syncher := setupTest(len(totalSuccessTestConsts), totalSuccessTestConsts)
   cniResult := syncher.MergeCniResults()